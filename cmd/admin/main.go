@@ -0,0 +1,257 @@
+package main
+
+import (
+	"configuration-management/internal/config"
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+const usage = `Usage: admin [-config file] <command> [arguments]
+
+Commands:
+  users list                        list every user
+  projects list USER                list the projects of a user
+  projects transfer PROJECT USER    give a project to another user
+  sessions revoke USER              sign a user out of every browser
+  access-key rotate PROJECT         replace the access key of a project
+  secrets verify                    check every header value decrypts with SECRET_KEY
+
+Users are named by id or by provider:subject, such as local:alice. Projects
+are named by id. The commands work on the database directly, without the
+application running.
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("invalid database configuration:\n%v", err)
+	}
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := run(context.Background(), db, cfg.Encrypter, args[0]+" "+args[1], args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run builds the encrypter only for the commands that need SECRET_KEY.
+func run(ctx context.Context, db *database.DatabaseHandler, newEncrypter func() (*utils.Encrypter, error), command string, args []string) error {
+	switch command {
+	case "users list":
+		if err := expectArgs(command, args); err != nil {
+			return err
+		}
+		return listUsers(ctx, db)
+	case "projects list":
+		if err := expectArgs(command, args, "USER"); err != nil {
+			return err
+		}
+		return listProjects(ctx, db, args[0])
+	case "projects transfer":
+		if err := expectArgs(command, args, "PROJECT", "USER"); err != nil {
+			return err
+		}
+		return transferProject(ctx, db, args[0], args[1])
+	case "sessions revoke":
+		if err := expectArgs(command, args, "USER"); err != nil {
+			return err
+		}
+		return revokeSessions(ctx, db, args[0])
+	case "access-key rotate":
+		if err := expectArgs(command, args, "PROJECT"); err != nil {
+			return err
+		}
+		return rotateAccessKey(ctx, db, newEncrypter, args[0])
+	case "secrets verify":
+		if err := expectArgs(command, args); err != nil {
+			return err
+		}
+		return verifySecrets(ctx, db, newEncrypter)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func expectArgs(command string, args []string, names ...string) error {
+	if len(args) != len(names) {
+		return errors.New(strings.TrimSpace(fmt.Sprintf("usage: admin %s %s", command, strings.Join(names, " "))))
+	}
+	return nil
+}
+
+func findUser(ctx context.Context, users database.UserStore, reference string) (*models.User, error) {
+	user, err := database.FindUser(ctx, users, reference)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("user %s not found", reference)
+	}
+	return user, err
+}
+
+func findProject(ctx context.Context, projects database.ProjectStore, reference string) (*models.Project, error) {
+	id, err := uuid.Parse(reference)
+	if err != nil {
+		return nil, fmt.Errorf("a project is named by id, got %q", reference)
+	}
+	project, err := projects.GetProject(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("project %s not found", reference)
+	}
+	return project, err
+}
+
+func listUsers(ctx context.Context, db *database.DatabaseHandler) error {
+	users, err := db.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	counts, err := db.CountProjectsByUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER\tNAME\tEMAIL\tPROJECTS")
+	for _, user := range users {
+		fmt.Fprintf(w, "%s\t%s:%s\t%s\t%s\t%d\n", user.ID, user.Provider, user.Subject, user.Name, user.Email, counts[user.ID])
+	}
+	return w.Flush()
+}
+
+func listProjects(ctx context.Context, db *database.DatabaseHandler, reference string) error {
+	user, err := findUser(ctx, db, reference)
+	if err != nil {
+		return err
+	}
+	projects, err := db.ListProjects(ctx, user.ID, database.ProjectListOptions{WithConfigs: true})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCONFIGS")
+	for _, project := range projects {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", project.ID, project.Name, len(project.Configs))
+	}
+	return w.Flush()
+}
+
+func transferProject(ctx context.Context, db *database.DatabaseHandler, projectReference string, userReference string) error {
+	project, err := findProject(ctx, db, projectReference)
+	if err != nil {
+		return err
+	}
+	user, err := findUser(ctx, db, userReference)
+	if err != nil {
+		return err
+	}
+	if project.UserID == user.ID {
+		fmt.Printf("Project %s already belongs to %s:%s\n", project.Name, user.Provider, user.Subject)
+		return nil
+	}
+
+	if err := db.UpdateProjectOwner(ctx, project.ID, user.ID); err != nil {
+		return err
+	}
+	fmt.Printf("Project %s now belongs to %s:%s\n", project.Name, user.Provider, user.Subject)
+	return nil
+}
+
+func revokeSessions(ctx context.Context, db *database.DatabaseHandler, reference string) error {
+	user, err := findUser(ctx, db, reference)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Printf("Revoked the sessions of %s:%s\n", user.Provider, user.Subject)
+	return nil
+}
+
+// rotateAccessKey notifies the webhooks of the project as the rotate button
+// does, and waits for the deliveries before exiting.
+func rotateAccessKey(ctx context.Context, db *database.DatabaseHandler, newEncrypter func() (*utils.Encrypter, error), reference string) error {
+	project, err := findProject(ctx, db, reference)
+	if err != nil {
+		return err
+	}
+	// The webhook secrets are encrypted.
+	encrypter, err := newEncrypter()
+	if err != nil {
+		return err
+	}
+
+	if err := db.UpdateProjectAccessKey(ctx, project.ID, utils.GenerateToken(32)); err != nil {
+		return err
+	}
+	dispatcher := webhooks.NewDispatcher(db, encrypter)
+	dispatcher.Publish(ctx, project.ID, models.EventAccessKeyRotated, webhooks.NewProjectPayload(*project))
+	dispatcher.Wait()
+
+	fmt.Printf("Rotated the access key of project %s, existing proxy URLs stopped working\n", project.Name)
+	return nil
+}
+
+// verifySecrets lists the header values that do not decrypt, such as after
+// SECRET_KEY was changed without re-encrypting them.
+func verifySecrets(ctx context.Context, db *database.DatabaseHandler, newEncrypter func() (*utils.Encrypter, error)) error {
+	encrypter, err := newEncrypter()
+	if err != nil {
+		return err
+	}
+	headers, err := db.ListAllHeaderReplacements(ctx)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, header := range headers {
+		// Decrypt reads values that are not hex encoded as empty.
+		_, err := hex.DecodeString(header.HeaderValue)
+		if err == nil {
+			_, err = encrypter.Decrypt(ctx, header.HeaderValue)
+		}
+		if err != nil {
+			fmt.Printf("header %s %s of config %s: %v\n", header.ID, header.HeaderName, header.ConfigID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d header values do not decrypt with SECRET_KEY", failed, len(headers))
+	}
+	fmt.Printf("All %d header values decrypt with SECRET_KEY\n", len(headers))
+	return nil
+}
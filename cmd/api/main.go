@@ -2,18 +2,35 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"flag"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"configuration-management/internal/alerts"
+	"configuration-management/internal/auth"
+	"configuration-management/internal/config"
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/migrations"
+	"configuration-management/internal/expiry"
+	"configuration-management/internal/health"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/metrics"
 	"configuration-management/internal/server"
-	"github.com/joho/godotenv"
+	"configuration-management/internal/tracing"
+	"configuration-management/internal/webhooks"
+
+	_ "github.com/joho/godotenv/autoload"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+// gracefulShutdown stops the workers once the server is shut down, then waits
+// for the webhook deliveries still in progress, retries included.
+func gracefulShutdown(apiServer *http.Server, dispatcher *webhooks.Dispatcher, stopWorkers context.CancelFunc, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -21,42 +38,120 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	// Listen for the interrupt signal.
 	<-ctx.Done()
 
-	log.Println("shutting down gracefully, press Ctrl+C again to force")
+	slog.Info("shutting down gracefully, press Ctrl+C again to force")
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := apiServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown with error: %v", err)
+		slog.Error("server forced to shutdown", "error", err)
+	}
+
+	stopWorkers()
+	deliveriesCtx, cancelDeliveries := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelDeliveries()
+	if err := dispatcher.WaitContext(deliveriesCtx); err != nil {
+		slog.Error("webhook deliveries dropped", "error", err)
 	}
 
-	log.Println("Server exiting")
+	slog.Info("server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
 	done <- true
 }
 
+func migrateDatabase(db *database.DatabaseHandler) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(); err != nil {
+		return err
+	}
+	version, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	slog.Info("database migrated", "version", version)
+	return nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
 func main() {
-	envErr := godotenv.Load()
-	if envErr != nil {
-		log.Fatalf("Error loading .env file: %v\n", envErr)
+	migrate := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log)
+	if err != nil {
+		log.Fatal(err)
 	}
+	slog.SetDefault(logger)
 
-	server := server.NewServer()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		fatal("failed to open database", err)
+	}
+
+	if *migrate {
+		if err := migrateDatabase(db); err != nil {
+			fatal("failed to migrate database", err)
+		}
+	}
+
+	encrypter, err := cfg.Encrypter()
+	if err != nil {
+		fatal("invalid secret key", err)
+	}
+	dispatcher := webhooks.NewDispatcher(db, encrypter)
+
+	server, err := server.NewServer(cfg, db, logger, metrics.New(db, db.DB), dispatcher, health.MigrationsCheck(db))
+	if err != nil {
+		fatal("failed to create server", err)
+	}
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	mailer := mail.NewMailer(cfg.SMTP)
+	notifiers := alerts.DefaultNotifiers(mailer)
+	go alerts.NewWorker(db, db, cfg.AlertsInterval, notifiers, logger).Run(workerCtx)
+	go expiry.NewWorker(db, db, db, dispatcher, cfg.ConfigExpiryInterval, mailer, logger).Run(workerCtx)
+	go auth.NewSessionCleaner(db, cfg.Sessions, cfg.SessionsCleanupInterval, logger).Run(workerCtx)
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(server, dispatcher, stopWorker, done)
 
-	err := server.ListenAndServe()
+	slog.Info("listening", "addr", server.Addr)
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
-		panic(fmt.Sprintf("http server error: %s", err))
+		fatal("http server error", err)
 	}
 
 	// Wait for the graceful shutdown to complete
 	<-done
-	log.Println("Graceful shutdown complete.")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("graceful shutdown complete")
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	urlEnv         = "LIMITER_URL"
+	tokenEnv       = "LIMITER_TOKEN"
+	credentialsEnv = "LIMITER_CREDENTIALS"
+)
+
+// credentials are saved by login, LIMITER_URL and LIMITER_TOKEN override
+// them.
+type credentials struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+func credentialsPath() (string, error) {
+	if path := os.Getenv(credentialsEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the configuration directory: %v", err)
+	}
+	return filepath.Join(dir, "limiterctl", "credentials.json"), nil
+}
+
+func loadCredentials() (*credentials, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var creds credentials
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read credentials: %v", err)
+	default:
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return nil, fmt.Errorf("invalid credentials file %s: %v", path, err)
+		}
+	}
+
+	if url := os.Getenv(urlEnv); url != "" {
+		creds.URL = url
+	}
+	if token := os.Getenv(tokenEnv); token != "" {
+		creds.Token = token
+	}
+	if creds.URL == "" || creds.Token == "" {
+		return nil, fmt.Errorf("not logged in, run limiterctl login or set %s and %s", urlEnv, tokenEnv)
+	}
+	return &creds, nil
+}
+
+// saveCredentials writes the file readable by the user only, as it holds
+// the token.
+func saveCredentials(creds credentials) (string, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to save credentials: %v", err)
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("failed to save credentials: %v", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"configuration-management/internal/api"
+	"configuration-management/internal/connection"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const usage = `Usage: limiterctl <command> [flags] [arguments]
+
+Commands:
+  login --url URL --token TOKEN              save the credentials of an API token
+  projects list
+  projects create NAME [--description TEXT]
+  projects delete PROJECT
+  configs list PROJECT
+  configs create PROJECT NAME --limit 100/hour
+  configs update PROJECT CONFIG --limit 100/hour
+  configs delete PROJECT CONFIG
+  headers set PROJECT CONFIG HEADER VALUE    a VALUE of - is read from stdin
+  headers unset PROJECT CONFIG HEADER
+  connection show PROJECT CONFIG [--format url|env|dotenv|curl|python|go|node]
+
+Projects and configs are named by id or by name. Every command accepts
+-o table (the default) or -o json. API tokens are created on the API tokens
+page, the credentials saved by login are overridden by LIMITER_URL and
+LIMITER_TOKEN.
+`
+
+// errUsage reports invalid arguments, the usage is printed.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	err := run(context.Background(), os.Args[1], os.Args[2:])
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "limiterctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// command holds the flags shared by every command.
+type command struct {
+	flags  *flag.FlagSet
+	output string
+}
+
+func newCommand(name string) *command {
+	cmd := &command{flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	cmd.flags.SetOutput(io.Discard)
+	cmd.flags.StringVar(&cmd.output, "o", outputTable, "output format, table or json")
+	return cmd
+}
+
+// parse accepts the flags before, between and after the positional
+// arguments, and checks their number.
+func (cmd *command) parse(args []string, count int) ([]string, printer, error) {
+	var positional []string
+	for {
+		if err := cmd.flags.Parse(args); err != nil {
+			return nil, printer{}, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = cmd.flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != count {
+		return nil, printer{}, errUsage
+	}
+	if cmd.output != outputTable && cmd.output != outputJSON {
+		return nil, printer{}, fmt.Errorf("%w: -o must be table or json", errUsage)
+	}
+	return positional, printer{os.Stdout, cmd.output}, nil
+}
+
+func run(ctx context.Context, name string, args []string) error {
+	if name == "login" {
+		return login(ctx, args)
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	creds, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	client := api.NewClient(creds.URL, creds.Token)
+
+	switch name + " " + args[0] {
+	case "projects list":
+		return listProjects(ctx, client, args[1:])
+	case "projects create":
+		return createProject(ctx, client, args[1:])
+	case "projects delete":
+		return deleteProject(ctx, client, args[1:])
+	case "configs list":
+		return listConfigs(ctx, client, args[1:])
+	case "configs create":
+		return createConfig(ctx, client, args[1:])
+	case "configs update":
+		return updateConfig(ctx, client, args[1:])
+	case "configs delete":
+		return deleteConfig(ctx, client, args[1:])
+	case "headers set":
+		return setHeader(ctx, client, args[1:])
+	case "headers unset":
+		return unsetHeader(ctx, client, args[1:])
+	case "connection show":
+		return showConnection(ctx, client, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// login checks the token before saving it.
+func login(ctx context.Context, args []string) error {
+	cmd := newCommand("login")
+	url := cmd.flags.String("url", "", "address of the management application")
+	token := cmd.flags.String("token", "", "API token")
+	if _, _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+	if *url == "" || *token == "" {
+		return fmt.Errorf("%w: --url and --token are required", errUsage)
+	}
+
+	if _, err := api.NewClient(*url, *token).ListProjects(ctx); err != nil {
+		return fmt.Errorf("failed to log in: %v", err)
+	}
+	path, err := saveCredentials(credentials{*url, *token})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Logged in to %s, the credentials are saved in %s\n", *url, path)
+	return nil
+}
+
+func listProjects(ctx context.Context, client *api.Client, args []string) error {
+	_, out, err := newCommand("projects list").parse(args, 0)
+	if err != nil {
+		return err
+	}
+
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	return out.projects(projects)
+}
+
+func createProject(ctx context.Context, client *api.Client, args []string) error {
+	cmd := newCommand("projects create")
+	description := cmd.flags.String("description", "", "description of the project")
+	positional, out, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	project, err := client.CreateProject(ctx, api.CreateProjectRequest{Name: positional[0], Description: *description})
+	if err != nil {
+		return err
+	}
+	return out.projects([]api.Project{*project})
+}
+
+func deleteProject(ctx context.Context, client *api.Client, args []string) error {
+	positional, out, err := newCommand("projects delete").parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	project, err := client.FindProject(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteProject(ctx, project.ID); err != nil {
+		return err
+	}
+	return out.done("Deleted project %s", project.Name)
+}
+
+func listConfigs(ctx context.Context, client *api.Client, args []string) error {
+	positional, out, err := newCommand("configs list").parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	project, err := client.FindProject(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	configs := project.Configs
+	if configs == nil {
+		configs = []api.Config{}
+	}
+	return out.configs(configs)
+}
+
+// findConfig returns the project and its config, each named by id or by
+// name.
+func findConfig(ctx context.Context, client *api.Client, projectIDOrName string, configIDOrName string) (*api.Project, *api.Config, error) {
+	project, err := client.FindProject(ctx, projectIDOrName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, _ := uuid.Parse(configIDOrName)
+	for _, config := range project.Configs {
+		if config.ID == id || config.Name == configIDOrName {
+			return project, &config, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("config %q not found in project %s", configIDOrName, project.Name)
+}
+
+func createConfig(ctx context.Context, client *api.Client, args []string) error {
+	cmd := newCommand("configs create")
+	limit := cmd.flags.String("limit", "", "number of requests per period, such as 100/hour")
+	positional, out, err := cmd.parse(args, 2)
+	if err != nil {
+		return err
+	}
+	if *limit == "" {
+		return fmt.Errorf("%w: --limit is required", errUsage)
+	}
+
+	project, err := client.FindProject(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	config, err := client.CreateConfig(ctx, project.ID, api.ConfigRequest{Name: positional[1], Limit: *limit})
+	if err != nil {
+		return err
+	}
+	return out.configs([]api.Config{*config})
+}
+
+func updateConfig(ctx context.Context, client *api.Client, args []string) error {
+	cmd := newCommand("configs update")
+	limit := cmd.flags.String("limit", "", "number of requests per period, such as 100/hour")
+	positional, out, err := cmd.parse(args, 2)
+	if err != nil {
+		return err
+	}
+	if *limit == "" {
+		return fmt.Errorf("%w: --limit is required", errUsage)
+	}
+
+	project, config, err := findConfig(ctx, client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	updated, err := client.UpdateConfig(ctx, project.ID, config.ID, api.ConfigRequest{Limit: *limit})
+	if err != nil {
+		return err
+	}
+	return out.configs([]api.Config{*updated})
+}
+
+func deleteConfig(ctx context.Context, client *api.Client, args []string) error {
+	positional, out, err := newCommand("configs delete").parse(args, 2)
+	if err != nil {
+		return err
+	}
+
+	project, config, err := findConfig(ctx, client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteConfig(ctx, project.ID, config.ID); err != nil {
+		return err
+	}
+	return out.done("Deleted config %s of project %s", config.Name, project.Name)
+}
+
+func setHeader(ctx context.Context, client *api.Client, args []string) error {
+	positional, out, err := newCommand("headers set").parse(args, 4)
+	if err != nil {
+		return err
+	}
+	value := positional[3]
+	if value == "-" {
+		// Reading the value from stdin keeps it out of the shell history.
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	project, config, err := findConfig(ctx, client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	updated, err := client.SetHeader(ctx, project.ID, config.ID, positional[2], value)
+	if err != nil {
+		return err
+	}
+	return out.configs([]api.Config{*updated})
+}
+
+func unsetHeader(ctx context.Context, client *api.Client, args []string) error {
+	positional, out, err := newCommand("headers unset").parse(args, 3)
+	if err != nil {
+		return err
+	}
+
+	project, config, err := findConfig(ctx, client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	updated, err := client.UnsetHeader(ctx, project.ID, config.ID, positional[2])
+	if err != nil {
+		return err
+	}
+	return out.configs([]api.Config{*updated})
+}
+
+func showConnection(ctx context.Context, client *api.Client, args []string) error {
+	cmd := newCommand("connection show")
+	format := cmd.flags.String("format", connection.FormatURL, "url, env, dotenv, curl, python, go or node")
+	positional, out, err := cmd.parse(args, 2)
+	if err != nil {
+		return err
+	}
+
+	project, config, err := findConfig(ctx, client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	conn, err := client.GetConnection(ctx, project.ID, config.ID)
+	if err != nil {
+		return err
+	}
+	return out.connection(*conn, *format)
+}
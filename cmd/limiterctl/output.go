@@ -0,0 +1,82 @@
+package main
+
+import (
+	"configuration-management/internal/api"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p printer) json(value any) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// table writes the rows under the header with aligned columns.
+func (p printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (p printer) projects(projects []api.Project) error {
+	if p.format == outputJSON {
+		return p.json(projects)
+	}
+	var rows [][]string
+	for _, project := range projects {
+		rows = append(rows, []string{project.ID.String(), project.Name, project.Description})
+	}
+	return p.table([]string{"ID", "NAME", "DESCRIPTION"}, rows)
+}
+
+func (p printer) configs(configs []api.Config) error {
+	if p.format == outputJSON {
+		return p.json(configs)
+	}
+	var rows [][]string
+	for _, config := range configs {
+		rows = append(rows, []string{config.ID.String(), config.Name, config.Limit, strings.Join(config.Headers, ", ")})
+	}
+	return p.table([]string{"ID", "NAME", "LIMIT", "HEADERS"}, rows)
+}
+
+// connection prints the snippet of the format, table mode or not, as it is
+// meant to be copied or redirected to a file.
+func (p printer) connection(connection api.Connection, format string) error {
+	if p.format == outputJSON {
+		return p.json(connection)
+	}
+	snippet, ok := connection.Snippets[format]
+	if !ok {
+		return fmt.Errorf("unknown connection format %q", format)
+	}
+	_, err := fmt.Fprintln(p.w, strings.TrimSuffix(snippet, "\n"))
+	return err
+}
+
+// done confirms a change that returns nothing, only in table mode so that
+// JSON output stays parsable.
+func (p printer) done(format string, args ...any) error {
+	if p.format == outputJSON {
+		return nil
+	}
+	_, err := fmt.Fprintf(p.w, format+"\n", args...)
+	return err
+}
@@ -1,44 +1,176 @@
 package main
 
 import (
-	"database/sql"
+	"configuration-management/internal/config"
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/migrations"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/joho/godotenv/autoload"
 )
 
+const usage = `Usage: migrate [-dry-run] [-config file] <command>
+
+Commands:
+  up          apply every pending migration
+  down [N]    roll back the last N migrations, 1 by default
+  goto V      migrate up or down to version V
+  version     print the current version
+  force V     set the version without running migrations, -1 resets it
+  status      list applied and pending migrations
+
+Flags:
+`
+
 func main() {
-	database := os.Getenv("DB_DATABASE")
-	password := os.Getenv("DB_PASSWORD")
-	username := os.Getenv("DB_USERNAME")
-	port := os.Getenv("DB_PORT")
-	host := os.Getenv("DB_HOST")
-	schema := os.Getenv("DB_SCHEMA")
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
-	db, err := sql.Open("pgx", connStr)
+	dryRun := flag.Bool("dry-run", false, "print the migrations up, down and goto would run without running them")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("invalid database configuration:\n%v", err)
+	}
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://cmd/migrate/migrations",
-		"postgres", driver)
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
 
+	migrator, err := migrations.New(db)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
+	}
+	defer migrator.Close()
+
+	if err := run(migrator, args[0], args[1:], *dryRun); err != nil {
+		log.Fatal(err)
 	}
-	if err := m.Up(); err != nil {
-		panic(err)
+}
+
+func run(migrator *migrations.Migrator, command string, args []string, dryRun bool) error {
+	switch command {
+	case "up":
+		if dryRun {
+			return printPlan(migrator.PlanUp())
+		}
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+			n = parsed
+		}
+		if dryRun {
+			return printPlan(migrator.PlanDown(n))
+		}
+		if err := migrator.Down(n); err != nil {
+			return err
+		}
+	case "goto":
+		if len(args) == 0 {
+			return fmt.Errorf("goto requires a version")
+		}
+		version, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		if dryRun {
+			return printPlan(migrator.PlanGoto(uint(version)))
+		}
+		if err := migrator.Goto(uint(version)); err != nil {
+			return err
+		}
+	case "force":
+		if len(args) == 0 {
+			return fmt.Errorf("force requires a version")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+	case "version":
+	case "status":
+		return printStatus(migrator)
+	default:
+		return fmt.Errorf("unknown command %q", command)
 	}
 
-	fmt.Println("Migrations run successfully!")
-	defer db.Close()
+	return printVersion(migrator)
+}
+
+func printVersion(migrator *migrations.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%d (dirty)\n", version)
+	} else {
+		fmt.Println(version)
+	}
+	return nil
+}
+
+func printPlan(steps []migrations.Step, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("No migrations to run")
+		return nil
+	}
+	for _, step := range steps {
+		direction := "down"
+		if step.Up {
+			direction = "up"
+		}
+		fmt.Printf("%-4s %06d %s\n", direction, step.Version, step.Name)
+	}
+	return nil
+}
+
+func printStatus(migrator *migrations.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Version: %d", status.Version)
+	if status.Dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Println()
+	for _, migration := range status.Applied {
+		fmt.Printf("applied %06d %s\n", migration.Version, migration.Name)
+	}
+	for _, migration := range status.Pending {
+		fmt.Printf("pending %06d %s\n", migration.Version, migration.Name)
+	}
+	return nil
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"configuration-management/internal/config"
+	"configuration-management/internal/database"
+	"configuration-management/internal/manifest"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+const usage = `Usage: reconcile -owner user [-dry-run] [-prune] [-config file] <directory>
+       reconcile [-config file] encrypt < value > file
+
+Makes the projects of the owner match the .yaml, .yml and .json documents of
+the directory, in a single transaction. Running it again without changes to
+the documents changes nothing. Header values can be read from environment
+variables or from files encrypted with SECRET_KEY, as written by encrypt:
+
+  headers:
+    - name: Authorization
+      value_from:
+        env: STRIPE_AUTHORIZATION
+    - name: X-Api-Key
+      value_from:
+        file: secrets/stripe-api-key.enc
+
+Flags:
+`
+
+type options struct {
+	owner  string
+	dryRun bool
+	prune  bool
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.owner, "owner", "", "user owning the projects, as a user id or provider:subject such as local:alice")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "print the changes without applying them")
+	flag.BoolVar(&opts.prune, "prune", false, "delete the configs of the managed projects missing from the documents")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	encrypter, err := cfg.Encrypter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	args := flag.Args()
+	if len(args) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if args[0] == "encrypt" {
+		if err := encrypt(encrypter, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if opts.owner == "" {
+		log.Fatal("-owner is required")
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("invalid database configuration:\n%v", err)
+	}
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := reconcile(context.Background(), db, encrypter, args[0], opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// encrypt writes the first line of r encrypted with SECRET_KEY, to be saved
+// in a file referenced by value_from.
+func encrypt(encrypter *utils.Encrypter, r io.Reader, w io.Writer) error {
+	value, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return fmt.Errorf("the value to encrypt is read from stdin")
+	}
+
+	encrypted, err := encrypter.Encrypt(context.Background(), value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, encrypted)
+	return err
+}
+
+// reconcile plans every document before applying any of them, so that an
+// invalid document or a missing secret changes nothing.
+func reconcile(ctx context.Context, db *database.DatabaseHandler, encrypter *utils.Encrypter, dir string, opts options) error {
+	owner, err := findOwner(ctx, db, opts.owner)
+	if err != nil {
+		return err
+	}
+	sources, err := manifest.ReadDir(dir, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no documents found in %s", dir)
+	}
+
+	plans := make([]*manifest.Plan, 0, len(sources))
+	total := map[string]int{}
+	for _, source := range sources {
+		project, err := manifest.FindProject(ctx, db, owner.ID, source.Document.Project.Name)
+		if err != nil {
+			return fmt.Errorf("failed to find project %q: %v", source.Document.Project.Name, err)
+		}
+		plan, err := manifest.NewPlan(ctx, encrypter, project, source.Document, manifest.Options{Prune: opts.prune})
+		if err != nil {
+			return fmt.Errorf("%s: %v", source.Path, err)
+		}
+		plans = append(plans, plan)
+
+		for _, change := range plan.Changes {
+			fmt.Printf("%-6s %-7s %s", change.Action, change.Resource, change.Name)
+			if change.Detail != "" {
+				fmt.Printf(" (%s)", change.Detail)
+			}
+			fmt.Println()
+		}
+		for action, count := range plan.Count() {
+			total[action] += count
+		}
+	}
+
+	summary := fmt.Sprintf("%d to create, %d to update, %d to delete",
+		total[manifest.ActionCreate], total[manifest.ActionUpdate], total[manifest.ActionDelete])
+	changed := total[manifest.ActionCreate]+total[manifest.ActionUpdate]+total[manifest.ActionDelete] > 0
+	if !changed {
+		fmt.Println("No changes, the projects match the documents")
+		return nil
+	}
+	if opts.dryRun {
+		fmt.Println("Dry run: " + summary)
+		return nil
+	}
+
+	var results []*manifest.Result
+	err = db.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		for _, plan := range plans {
+			result, err := plan.Apply(ctx, tx, owner.ID)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Applied: " + summary)
+
+	dispatcher := webhooks.NewDispatcher(db, encrypter)
+	for _, result := range results {
+		result.Publish(ctx, dispatcher)
+	}
+	dispatcher.Wait()
+	return nil
+}
+
+// findOwner reports the users that do not exist plainly.
+func findOwner(ctx context.Context, users database.UserStore, owner string) (*models.User, error) {
+	user, err := database.FindUser(ctx, users, owner)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("user %s not found", owner)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("-owner: %v", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,88 @@
+package alerts
+
+import (
+	"configuration-management/internal/models"
+	"fmt"
+	"time"
+)
+
+// LimitWindow returns how far back proxied requests count towards a
+// config's limit. Zero means the limit never resets.
+func LimitWindow(per string) time.Duration {
+	switch per {
+	case "second":
+		return time.Second
+	case "minute":
+		return time.Minute
+	case "hour":
+		return time.Hour
+	case "day":
+		return 24 * time.Hour
+	case "week":
+		return 7 * 24 * time.Hour
+	case "month":
+		return 30 * 24 * time.Hour
+	case "year":
+		return 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// UsageSince returns the start of the usage window the alert is evaluated against.
+func UsageSince(alert models.Alert, config models.Config, now time.Time) time.Time {
+	if alert.Kind == models.AlertKindRejections {
+		return now.Add(-time.Duration(alert.WindowMinutes) * time.Minute)
+	}
+
+	window := LimitWindow(config.LimitPer)
+	if window == 0 {
+		return time.Time{}
+	}
+	return now.Add(-window)
+}
+
+// Evaluate computes the alert value from the usage counts and reports
+// whether the threshold has been reached.
+func Evaluate(alert models.Alert, config models.Config, requests int, rejected int) (int, bool) {
+	switch alert.Kind {
+	case models.AlertKindQuota:
+		if config.LimitNumberOfRequests <= 0 {
+			return 0, false
+		}
+		value := requests * 100 / config.LimitNumberOfRequests
+		return value, value >= alert.Threshold
+	case models.AlertKindRejections:
+		return rejected, rejected >= alert.Threshold
+	default:
+		return 0, false
+	}
+}
+
+func Describe(alert models.Alert, config models.Config) string {
+	switch alert.Kind {
+	case models.AlertKindQuota:
+		return fmt.Sprintf("%d%% of %d requests / %s used", alert.Threshold, config.LimitNumberOfRequests, config.LimitPer)
+	case models.AlertKindRejections:
+		return fmt.Sprintf("%d rejected requests in %d minutes", alert.Threshold, alert.WindowMinutes)
+	default:
+		return alert.Kind
+	}
+}
+
+func message(alert models.Alert, config models.Config, value int) string {
+	if alert.State == models.AlertStateOK {
+		return fmt.Sprintf("Alert for config %q resolved: %s threshold no longer reached", config.Name, Describe(alert, config))
+	}
+
+	switch alert.Kind {
+	case models.AlertKindQuota:
+		return fmt.Sprintf("Config %q used %d%% of its %d requests / %s limit (threshold %d%%)",
+			config.Name, value, config.LimitNumberOfRequests, config.LimitPer, alert.Threshold)
+	case models.AlertKindRejections:
+		return fmt.Sprintf("Config %q had %d rejected requests in the last %d minutes (threshold %d)",
+			config.Name, value, alert.WindowMinutes, alert.Threshold)
+	default:
+		return fmt.Sprintf("Alert for config %q is firing", config.Name)
+	}
+}
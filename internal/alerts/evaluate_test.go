@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"configuration-management/internal/models"
+	"testing"
+	"time"
+)
+
+func TestLimitWindow(t *testing.T) {
+	tests := []struct {
+		per  string
+		want time.Duration
+	}{
+		{"second", time.Second},
+		{"minute", time.Minute},
+		{"hour", time.Hour},
+		{"day", 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"year", 365 * 24 * time.Hour},
+		{"", 0},
+		{"fortnight", 0},
+	}
+	for _, test := range tests {
+		if got := LimitWindow(test.per); got != test.want {
+			t.Errorf("LimitWindow(%q) = %v, want %v", test.per, got, test.want)
+		}
+	}
+}
+
+func TestUsageSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		alert  models.Alert
+		config models.Config
+		want   time.Time
+	}{
+		{
+			name:   "quota alerts count the limit window",
+			alert:  models.Alert{Kind: models.AlertKindQuota},
+			config: models.Config{LimitPer: "hour"},
+			want:   now.Add(-time.Hour),
+		},
+		{
+			name:   "quota alerts of a limit that never resets count everything",
+			alert:  models.Alert{Kind: models.AlertKindQuota},
+			config: models.Config{LimitPer: "forever"},
+			want:   time.Time{},
+		},
+		{
+			name:   "rejection alerts count their own window",
+			alert:  models.Alert{Kind: models.AlertKindRejections, WindowMinutes: 15},
+			config: models.Config{LimitPer: "day"},
+			want:   now.Add(-15 * time.Minute),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UsageSince(test.alert, test.config, now); !got.Equal(test.want) {
+				t.Errorf("UsageSince = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	quota := models.Alert{Kind: models.AlertKindQuota, Threshold: 80}
+	rejections := models.Alert{Kind: models.AlertKindRejections, Threshold: 5, WindowMinutes: 10}
+	config := models.Config{LimitNumberOfRequests: 200, LimitPer: "hour"}
+
+	tests := []struct {
+		name       string
+		alert      models.Alert
+		config     models.Config
+		requests   int
+		rejected   int
+		wantValue  int
+		wantFiring bool
+	}{
+		{"quota below the threshold", quota, config, 158, 0, 79, false},
+		{"quota at the threshold", quota, config, 160, 0, 80, true},
+		{"quota above the limit", quota, config, 250, 50, 125, true},
+		{"quota without a limit", quota, models.Config{}, 10, 0, 0, false},
+		{"rejections below the threshold", rejections, config, 100, 4, 4, false},
+		{"rejections at the threshold", rejections, config, 100, 5, 5, true},
+		{"unknown kind", models.Alert{Kind: "latency", Threshold: 1}, config, 100, 100, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, firing := Evaluate(test.alert, test.config, test.requests, test.rejected)
+			if value != test.wantValue || firing != test.wantFiring {
+				t.Errorf("Evaluate = %d, %v, want %d, %v", value, firing, test.wantValue, test.wantFiring)
+			}
+		})
+	}
+}
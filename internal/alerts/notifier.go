@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"bytes"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	Alert   models.Alert
+	Config  models.Config
+	Value   int
+	Message string
+	SentAt  time.Time
+}
+
+// Notifier delivers alert notifications through a single channel. Notifiers
+// are registered with the worker under the alert channel name they handle.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+type webhookPayload struct {
+	AlertID    uuid.UUID `json:"alert_id"`
+	ConfigID   uuid.UUID `json:"config_id"`
+	ConfigName string    `json:"config_name"`
+	Kind       string    `json:"kind"`
+	State      string    `json:"state"`
+	Threshold  int       `json:"threshold"`
+	Value      int       `json:"value"`
+	Message    string    `json:"message"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{webhooks.NewClient(10 * time.Second)}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		AlertID:    notification.Alert.ID,
+		ConfigID:   notification.Config.ID,
+		ConfigName: notification.Config.Name,
+		Kind:       notification.Alert.Kind,
+		State:      notification.Alert.State,
+		Threshold:  notification.Alert.Threshold,
+		Value:      notification.Value,
+		Message:    notification.Message,
+		SentAt:     notification.SentAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.Alert.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type EmailNotifier struct {
+	mailer *mail.Mailer
+}
+
+func NewEmailNotifier(mailer *mail.Mailer) *EmailNotifier {
+	return &EmailNotifier{mailer}
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	subject := fmt.Sprintf("[API Key Limiter] %s alert for %s", notification.Alert.State, notification.Config.Name)
+	if err := e.mailer.Send(notification.Alert.Target, subject, notification.Message); err != nil {
+		return fmt.Errorf("failed to send alert email: %v", err)
+	}
+
+	return nil
+}
+
+// DefaultNotifiers returns the available notifiers, email alerts need a
+// mailer.
+func DefaultNotifiers(mailer *mail.Mailer) map[string]Notifier {
+	notifiers := map[string]Notifier{
+		models.AlertChannelWebhook: NewWebhookNotifier(),
+	}
+	if mailer != nil {
+		notifiers[models.AlertChannelEmail] = NewEmailNotifier(mailer)
+	}
+
+	return notifiers
+}
@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Worker periodically evaluates every alert against the recorded config
+// usage and notifies the alert channel whenever an alert changes state.
+type Worker struct {
+	alerts    database.AlertStore
+	configs   database.ConfigStore
+	notifiers map[string]Notifier
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+func NewWorker(alerts database.AlertStore, configs database.ConfigStore,
+	interval time.Duration, notifiers map[string]Notifier, logger *slog.Logger) *Worker {
+	return &Worker{alerts, configs, notifiers, interval, logger.With("job", "alerts")}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.EvaluateAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) EvaluateAll(ctx context.Context) {
+	alerts, err := w.alerts.ListAllAlerts(ctx)
+	if err != nil {
+		w.logger.Error("failed to list alerts", "error", err)
+		return
+	}
+
+	configs := make(map[uuid.UUID]*models.Config)
+	for _, alert := range alerts {
+		config, ok := configs[alert.ConfigID]
+		if !ok {
+			config, err = w.configs.GetConfig(ctx, alert.ConfigID)
+			if err != nil {
+				w.logger.Error("failed to get config for alert", "alert_id", alert.ID, "error", err)
+				continue
+			}
+			configs[alert.ConfigID] = config
+		}
+
+		if err := w.evaluate(ctx, alert, *config, time.Now().UTC()); err != nil {
+			w.logger.Error("failed to evaluate alert", "alert_id", alert.ID, "error", err)
+		}
+	}
+}
+
+func (w *Worker) evaluate(ctx context.Context, alert models.Alert, config models.Config, now time.Time) error {
+	requests, rejected, err := w.alerts.CountConfigUsage(ctx, config.ID, UsageSince(alert, config, now))
+	if err != nil {
+		return err
+	}
+
+	value, firing := Evaluate(alert, config, requests, rejected)
+	state := models.AlertStateOK
+	if firing {
+		state = models.AlertStateFiring
+	}
+
+	var triggeredAt *time.Time
+	changed := state != alert.State
+	if changed && firing {
+		triggeredAt = &now
+	}
+
+	if changed {
+		if err := w.notify(ctx, alert, state, config, value, now); err != nil {
+			// Keep the previous state so that the next tick sends it again.
+			if updateErr := w.alerts.UpdateAlertState(ctx, alert.ID, alert.State, value, now, nil); updateErr != nil {
+				w.logger.Error("failed to update alert", "alert_id", alert.ID, "error", updateErr)
+			}
+			return err
+		}
+	}
+
+	return w.alerts.UpdateAlertState(ctx, alert.ID, state, value, now, triggeredAt)
+}
+
+func (w *Worker) notify(ctx context.Context, alert models.Alert, state string, config models.Config, value int, now time.Time) error {
+	alert.State = state
+	notifier, ok := w.notifiers[alert.Channel]
+	if !ok {
+		w.logger.Warn("no notifier configured for alert channel", "channel", alert.Channel)
+		return nil
+	}
+
+	return notifier.Notify(ctx, Notification{
+		Alert:   alert,
+		Config:  config,
+		Value:   value,
+		Message: message(alert, config, value),
+		SentAt:  now,
+	})
+}
@@ -0,0 +1,119 @@
+package alerts
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	sent []Notification
+	err  error
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, notification)
+	return nil
+}
+
+func TestWorkerNotifiesStateChanges(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice"})
+	project, _ := store.CreateProject(ctx, "payments", "", "key", owner.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 10, "hour")
+	alert, _ := store.CreateAlert(ctx, config.ID, models.AlertKindQuota, 50, 0, models.AlertChannelWebhook, "https://example.com/hook")
+
+	now := time.Now().UTC()
+	// The request older than the limit window does not count.
+	store.RecordUsage(config.ID, false, now.Add(-2*time.Hour))
+	for range 5 {
+		store.RecordUsage(config.ID, false, now.Add(-30*time.Minute))
+	}
+
+	notifier := &recordingNotifier{}
+	worker := NewWorker(store, store, time.Minute, map[string]Notifier{models.AlertChannelWebhook: notifier}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	worker.EvaluateAll(ctx)
+	worker.EvaluateAll(ctx)
+
+	got, _ := store.GetAlert(ctx, alert.ID)
+	if got.State != models.AlertStateFiring || got.LastValue != 50 || got.LastTriggeredAt == nil {
+		t.Fatalf("expected the alert to fire at 50%%, got %+v", got)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Alert.State != models.AlertStateFiring || notifier.sent[0].Value != 50 {
+		t.Fatalf("expected a single firing notification, got %+v", notifier.sent)
+	}
+
+	// An hour later the requests fall out of the window.
+	if err := worker.evaluate(ctx, *got, *config, now.Add(time.Hour)); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	got, _ = store.GetAlert(ctx, alert.ID)
+	if got.State != models.AlertStateOK || got.LastValue != 0 {
+		t.Fatalf("expected the alert to resolve, got %+v", got)
+	}
+	if len(notifier.sent) != 2 || notifier.sent[1].Alert.State != models.AlertStateOK {
+		t.Fatalf("expected a resolved notification, got %+v", notifier.sent)
+	}
+}
+
+func TestWorkerWithoutNotifier(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice"})
+	project, _ := store.CreateProject(ctx, "payments", "", "key", owner.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 10, "hour")
+	alert, _ := store.CreateAlert(ctx, config.ID, models.AlertKindRejections, 1, 5, models.AlertChannelEmail, "alice@example.com")
+	store.RecordUsage(config.ID, true, time.Now().UTC())
+
+	var logs strings.Builder
+	worker := NewWorker(store, store, time.Minute, map[string]Notifier{}, slog.New(slog.NewTextHandler(&logs, nil)))
+	worker.EvaluateAll(ctx)
+	if !strings.Contains(logs.String(), "no notifier configured") || !strings.Contains(logs.String(), "job=alerts") {
+		t.Fatalf("expected the missing notifier to be logged for the job, got %q", logs.String())
+	}
+
+	if got, _ := store.GetAlert(ctx, alert.ID); got.State != models.AlertStateFiring || got.LastValue != 1 {
+		t.Fatalf("expected the state to be recorded without a notifier, got %+v", got)
+	}
+}
+
+func TestWorkerRetriesFailedNotifications(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice"})
+	project, _ := store.CreateProject(ctx, "payments", "", "key", owner.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 10, "hour")
+	alert, _ := store.CreateAlert(ctx, config.ID, models.AlertKindQuota, 50, 0, models.AlertChannelWebhook, "https://example.com/hook")
+	for range 5 {
+		store.RecordUsage(config.ID, false, time.Now().UTC())
+	}
+
+	notifier := &recordingNotifier{err: errors.New("connection refused")}
+	worker := NewWorker(store, store, time.Minute, map[string]Notifier{models.AlertChannelWebhook: notifier}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	worker.EvaluateAll(ctx)
+
+	got, _ := store.GetAlert(ctx, alert.ID)
+	if got.State != models.AlertStateOK || got.LastValue != 50 || got.LastTriggeredAt != nil {
+		t.Fatalf("expected the alert to stay ok while the notification fails, got %+v", got)
+	}
+
+	notifier.err = nil
+	worker.EvaluateAll(ctx)
+	got, _ = store.GetAlert(ctx, alert.ID)
+	if got.State != models.AlertStateFiring || got.LastTriggeredAt == nil {
+		t.Fatalf("expected the alert to fire once notified, got %+v", got)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Alert.State != models.AlertStateFiring {
+		t.Fatalf("expected the notification to be sent on the next tick, got %+v", notifier.sent)
+	}
+}
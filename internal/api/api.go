@@ -0,0 +1,123 @@
+// Package api describes the JSON HTTP API served under /api/v1 to
+// command-line clients, which authenticate with an API token, and provides
+// a client for it.
+package api
+
+import (
+	"configuration-management/internal/connection"
+	"configuration-management/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prefix is the path of the API on the server.
+const Prefix = "/api/v1"
+
+type Project struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	// Configs are only listed when getting a single project.
+	Configs  []Config     `json:"configs,omitempty"`
+	Disabled *Disablement `json:"disabled,omitempty"`
+}
+
+type Config struct {
+	ID        uuid.UUID `json:"id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	// Limit is written as requests/period, such as 100/hour.
+	Limit string `json:"limit"`
+	// Headers lists the names of the replaced headers, never their values.
+	Headers []string `json:"headers"`
+	// Status is one of the models.ConfigStatus values, such as active or
+	// expired.
+	Status     string     `json:"status"`
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// Schedule is written as "Mon-Fri 09:00-17:00 UTC", empty when the
+	// config is served at any time.
+	Schedule string       `json:"schedule,omitempty"`
+	Disabled *Disablement `json:"disabled,omitempty"`
+}
+
+// Disablement is set on disabled configs and projects, By is the id of the
+// user who disabled it, omitted when the application disabled a config on
+// expiry.
+type Disablement struct {
+	At     time.Time  `json:"at"`
+	Reason string     `json:"reason"`
+	By     *uuid.UUID `json:"by,omitempty"`
+}
+
+type Connection struct {
+	ConnectionString string `json:"connection_string"`
+	// Snippets holds the connection in every format of the connection
+	// package, by format name such as curl or dotenv.
+	Snippets map[string]string `json:"snippets"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+type CreateProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ConfigRequest creates a config with a name and a limit, or updates the
+// limit of a config.
+type ConfigRequest struct {
+	Name  string `json:"name,omitempty"`
+	Limit string `json:"limit"`
+}
+
+type HeaderRequest struct {
+	Value string `json:"value"`
+}
+
+func NewProject(project models.Project) Project {
+	converted := Project{ID: project.ID, Name: project.Name, Description: project.Description, Disabled: newDisablement(project.Disabled)}
+	for _, config := range project.Configs {
+		converted.Configs = append(converted.Configs, NewConfig(config))
+	}
+	return converted
+}
+
+func newDisablement(disabled *models.Disablement) *Disablement {
+	if disabled == nil {
+		return nil
+	}
+	return &Disablement{disabled.At, disabled.Reason, disabled.By}
+}
+
+func NewConnection(conn connection.Connection) Connection {
+	converted := Connection{ConnectionString: conn.URL(), Snippets: map[string]string{}}
+	for _, snippet := range conn.Snippets() {
+		converted.Snippets[snippet.Name] = snippet.Text
+	}
+	return converted
+}
+
+func NewConfig(config models.Config) Config {
+	converted := Config{
+		ID:         config.ID,
+		ProjectID:  config.ProjectID,
+		Name:       config.Name,
+		Limit:      models.FormatLimit(config.LimitNumberOfRequests, config.LimitPer),
+		Headers:    []string{},
+		Status:     config.Status(time.Now()),
+		ActiveFrom: config.ActiveFrom,
+		ExpiresAt:  config.ExpiresAt,
+		Disabled:   newDisablement(config.Disabled),
+	}
+	if len(config.Schedule) > 0 {
+		converted.Schedule = models.FormatSchedule(config.Schedule)
+	}
+	for _, header := range config.HeaderReplacements {
+		converted.Headers = append(converted.Headers, header.HeaderName)
+	}
+	return converted
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Client calls the API of the server at BaseURL as the user of the token.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string, token string) *Client {
+	return &Client{strings.TrimRight(baseURL, "/"), token, &http.Client{Timeout: 30 * time.Second}}
+}
+
+// StatusError is returned for the responses that are not successful, with
+// the message of the server.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+Prefix+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr Error
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return &StatusError{resp.StatusCode, apiErr.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to read the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+func projectPath(projectID uuid.UUID) string {
+	return "/projects/" + projectID.String()
+}
+
+func configPath(projectID uuid.UUID, configID uuid.UUID) string {
+	return projectPath(projectID) + "/configs/" + configID.String()
+}
+
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	err := c.do(ctx, http.MethodGet, "/projects", nil, &projects)
+	return projects, err
+}
+
+// GetProject returns the project with its configs.
+func (c *Client) GetProject(ctx context.Context, projectID uuid.UUID) (*Project, error) {
+	var project Project
+	if err := c.do(ctx, http.MethodGet, projectPath(projectID), nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// FindProject returns the project with its configs, by id or by name.
+func (c *Client) FindProject(ctx context.Context, idOrName string) (*Project, error) {
+	if id, err := uuid.Parse(idOrName); err == nil {
+		return c.GetProject(ctx, id)
+	}
+
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if project.Name == idOrName {
+			return c.GetProject(ctx, project.ID)
+		}
+	}
+	return nil, fmt.Errorf("project %q not found", idOrName)
+}
+
+func (c *Client) CreateProject(ctx context.Context, request CreateProjectRequest) (*Project, error) {
+	var project Project
+	if err := c.do(ctx, http.MethodPost, "/projects", request, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (c *Client) DeleteProject(ctx context.Context, projectID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, projectPath(projectID), nil, nil)
+}
+
+func (c *Client) CreateConfig(ctx context.Context, projectID uuid.UUID, request ConfigRequest) (*Config, error) {
+	var config Config
+	if err := c.do(ctx, http.MethodPost, projectPath(projectID)+"/configs", request, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Client) UpdateConfig(ctx context.Context, projectID uuid.UUID, configID uuid.UUID, request ConfigRequest) (*Config, error) {
+	var config Config
+	if err := c.do(ctx, http.MethodPatch, configPath(projectID, configID), request, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Client) DeleteConfig(ctx context.Context, projectID uuid.UUID, configID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, configPath(projectID, configID), nil, nil)
+}
+
+// SetHeader creates the header replacement, or replaces its value.
+func (c *Client) SetHeader(ctx context.Context, projectID uuid.UUID, configID uuid.UUID, name string, value string) (*Config, error) {
+	var config Config
+	path := configPath(projectID, configID) + "/headers/" + url.PathEscape(name)
+	if err := c.do(ctx, http.MethodPut, path, HeaderRequest{value}, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Client) UnsetHeader(ctx context.Context, projectID uuid.UUID, configID uuid.UUID, name string) (*Config, error) {
+	var config Config
+	path := configPath(projectID, configID) + "/headers/" + url.PathEscape(name)
+	if err := c.do(ctx, http.MethodDelete, path, nil, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Client) GetConnection(ctx context.Context, projectID uuid.UUID, configID uuid.UUID) (*Connection, error) {
+	var connection Connection
+	if err := c.do(ctx, http.MethodGet, configPath(projectID, configID)+"/connection", nil, &connection); err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const githubUserURL = "https://api.github.com/user"
+
+type GitHubConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// GitHubProvider signs users in with their GitHub account. GitHub does not
+// implement OpenID Connect, the identity is read from the user API instead.
+type GitHubProvider struct {
+	conf    *oauth2.Config
+	userURL string
+}
+
+func NewGitHubProvider(clientID string, clientSecret string) *GitHubProvider {
+	conf := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{},
+		Endpoint:     github.Endpoint,
+	}
+	return &GitHubProvider{conf, githubUserURL}
+}
+
+func (p *GitHubProvider) ID() string {
+	return "github"
+}
+
+func (p *GitHubProvider) DisplayName() string {
+	return "GitHub"
+}
+
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, flow LoginFlow) (string, error) {
+	return p.conf.AuthCodeURL(flow.State, oauth2.S256ChallengeOption(flow.Verifier)), nil
+}
+
+func (p *GitHubProvider) Identify(ctx context.Context, code string, flow LoginFlow) (*Identity, error) {
+	token, err := exchange(ctx, p.ID(), p.conf, code, flow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+
+	resp, err := p.conf.Client(ctx, token).Get(p.userURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch github user: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch github user: %s", resp.Status)
+	}
+
+	var githubUser models.GithubUser
+	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
+		return nil, fmt.Errorf("failed to decode github user: %v", err)
+	}
+
+	name := githubUser.Name
+	if name == "" {
+		name = githubUser.Login
+	}
+	return &Identity{
+		Subject:   strconv.Itoa(githubUser.Id),
+		Name:      name,
+		Email:     githubUser.Email,
+		AvatarURL: githubUser.AvatarUrl,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	SignupOpen   = "open"
+	SignupInvite = "invite"
+
+	defaultInviteTTL        = 72 * time.Hour
+	defaultPasswordResetTTL = time.Hour
+	// totpIssuer is shown next to the account in authenticator apps.
+	totpIssuer = "API Key Limiter"
+	// totpPeriod is the lifetime in seconds of a code, the default of
+	// authenticator apps.
+	totpPeriod = 30
+)
+
+// LocalAuth configures the username and password accounts, which are
+// disabled by default.
+type LocalAuth struct {
+	Enabled bool `yaml:"enabled"`
+	// Signup is SignupOpen when anyone may create an account, otherwise an
+	// invite is required. The first account can always be created.
+	Signup           string        `yaml:"signup"`
+	InviteTTL        time.Duration `yaml:"invite_ttl"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+}
+
+func DefaultLocalAuth() LocalAuth {
+	return LocalAuth{
+		Signup:           SignupInvite,
+		InviteTTL:        defaultInviteTTL,
+		PasswordResetTTL: defaultPasswordResetTTL,
+	}
+}
+
+func (l LocalAuth) Validate() error {
+	var errs []error
+	if l.Signup != SignupOpen && l.Signup != SignupInvite {
+		errs = append(errs, fmt.Errorf("LOCAL_SIGNUP must be %q or %q, got %q", SignupOpen, SignupInvite, l.Signup))
+	}
+	if l.InviteTTL <= 0 {
+		errs = append(errs, fmt.Errorf("INVITE_TTL must be positive, got %s", l.InviteTTL))
+	}
+	if l.PasswordResetTTL <= 0 {
+		errs = append(errs, fmt.Errorf("PASSWORD_RESET_TTL must be positive, got %s", l.PasswordResetTTL))
+	}
+
+	return errors.Join(errs...)
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+// unknownUserHash is compared against when the username does not exist, so
+// that a login takes as long for unknown users as for wrong passwords.
+var unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
+// CheckPassword reports whether the password matches the hash. An empty
+// hash is checked against a dummy hash and never matches.
+func CheckPassword(hash string, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(unknownUserHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewAuthToken returns a token to send to the user and the hash to store.
+func NewAuthToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashAuthToken(token), nil
+}
+
+func HashAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTOTPKey generates the second factor secret of an account.
+func NewTOTPKey(username string) (*otp.Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: username})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp key: %v", err)
+	}
+	return key, nil
+}
+
+// ValidateTOTP accepts the code of the current period and of the adjacent
+// ones, to allow for clock drift. It returns the time step of the code, a
+// code must not be accepted twice.
+func ValidateTOTP(code string, secret string, at time.Time) (int64, bool) {
+	current := at.Unix() / totpPeriod
+	for _, step := range []int64{current, current - 1, current + 1} {
+		valid, err := totp.ValidateCustom(code, secret, time.Unix(step*totpPeriod, 0).UTC(), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && valid {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	if !CheckPassword(hash, "correct horse battery staple") {
+		t.Fatalf("expected the password to match")
+	}
+	if CheckPassword(hash, "wrong password") {
+		t.Fatalf("expected a wrong password not to match")
+	}
+	if CheckPassword("", "unknown user") {
+		t.Fatalf("expected an empty hash never to match")
+	}
+}
+
+func TestAuthTokens(t *testing.T) {
+	token, hash, err := NewAuthToken()
+	if err != nil {
+		t.Fatalf("NewAuthToken: %v", err)
+	}
+	if token == "" || hash == token || HashAuthToken(token) != hash {
+		t.Fatalf("expected the hash of %q, got %q", token, hash)
+	}
+
+	other, _, _ := NewAuthToken()
+	if other == token {
+		t.Fatalf("expected tokens to be random")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key, err := NewTOTPKey("alice")
+	if err != nil {
+		t.Fatalf("NewTOTPKey: %v", err)
+	}
+	if key.AccountName() != "alice" || key.Issuer() != totpIssuer {
+		t.Fatalf("unexpected key %s", key.URL())
+	}
+
+	now := time.Unix(1700000000, 0)
+	code, err := totp.GenerateCode(key.Secret(), now)
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	if step, valid := ValidateTOTP(code, key.Secret(), now); !valid || step != now.Unix()/totpPeriod {
+		t.Fatalf("expected the current code to be valid for step %d, got %d %t", now.Unix()/totpPeriod, step, valid)
+	}
+	// the code of the previous period is still accepted for clock drift
+	if step, valid := ValidateTOTP(code, key.Secret(), now.Add(totpPeriod*time.Second)); !valid || step != now.Unix()/totpPeriod {
+		t.Fatalf("expected the previous code to be valid for step %d, got %d %t", now.Unix()/totpPeriod, step, valid)
+	}
+
+	stale, _ := totp.GenerateCode(key.Secret(), now.Add(-time.Hour))
+	if _, valid := ValidateTOTP(stale, key.Secret(), now); stale != code && valid {
+		t.Fatalf("expected a code from an hour ago to be rejected")
+	}
+}
+
+func TestLocalAuthValidate(t *testing.T) {
+	if err := DefaultLocalAuth().Validate(); err != nil {
+		t.Fatalf("expected the defaults to be valid: %v", err)
+	}
+
+	local := DefaultLocalAuth()
+	local.Signup = "anyone"
+	local.InviteTTL = 0
+	if err := local.Validate(); err == nil {
+		t.Fatalf("expected an unknown signup mode to be rejected")
+	}
+}
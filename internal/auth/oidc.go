@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type OIDCConfig struct {
+	ID           string `yaml:"id"`
+	Name         string `yaml:"name"`
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+	// Scopes are requested in addition to openid, profile and email.
+	Scopes []string `yaml:"scopes"`
+}
+
+// OIDCProvider signs users in with any OpenID Connect identity provider.
+// The provider endpoints are discovered from the issuer on first use, so
+// that an unreachable provider does not prevent the application from
+// starting.
+type OIDCProvider struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(config OIDCConfig) (*OIDCProvider, error) {
+	if config.ID == "" || config.Issuer == "" || config.ClientID == "" {
+		return nil, fmt.Errorf("oidc provider %q requires an issuer and a client id", config.ID)
+	}
+	if config.Name == "" {
+		config.Name = config.ID
+	}
+
+	return &OIDCProvider{config: config}, nil
+}
+
+func (p *OIDCProvider) ID() string {
+	return p.config.ID
+}
+
+func (p *OIDCProvider) DisplayName() string {
+	return p.config.Name
+}
+
+// discover fetches the provider metadata, failures are retried on the next
+// login attempt.
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// The metadata and signing keys are cached for the lifetime of the
+	// provider, they must not be bound to the request that discovered them.
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), p.config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc provider %s: %v", p.config.ID, err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID, "profile", "email"}
+	for _, scope := range p.config.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+
+	return p.oauth2, p.verifier, nil
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, flow LoginFlow) (string, error) {
+	conf, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return conf.AuthCodeURL(flow.State, oauth2.S256ChallengeOption(flow.Verifier), oidc.Nonce(flow.Nonce)), nil
+}
+
+func (p *OIDCProvider) Identify(ctx context.Context, code string, flow LoginFlow) (*Identity, error) {
+	conf, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchange(ctx, p.ID(), conf, code, flow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response does not contain an id token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %v", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, fmt.Errorf("id token nonce does not match the login flow")
+	}
+
+	var claims struct {
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+		Picture           string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to read id token claims: %v", err)
+	}
+
+	identity := &Identity{
+		Subject:   idToken.Subject,
+		Name:      claims.Name,
+		Email:     claims.Email,
+		AvatarURL: claims.Picture,
+	}
+	for _, fallback := range []string{claims.PreferredUsername, claims.Email, idToken.Subject} {
+		if identity.Name == "" {
+			identity.Name = fallback
+		}
+	}
+	return identity, nil
+}
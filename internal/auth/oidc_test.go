@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIdentityProvider is a minimal OpenID Connect provider issuing ID
+// tokens for the code challenge and nonce of the last authorization request.
+type fakeIdentityProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	audience  string
+}
+
+func newFakeIdentityProvider(t *testing.T) *fakeIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &fakeIdentityProvider{key: key, audience: "client"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "key", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken(t),
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *fakeIdentityProvider) authorize(t *testing.T, authCodeURL string) {
+	parsed, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected a PKCE challenge in %s", authCodeURL)
+	}
+	idp.challenge = parsed.Query().Get("code_challenge")
+	idp.nonce = parsed.Query().Get("nonce")
+}
+
+func (idp *fakeIdentityProvider) idToken(t *testing.T) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":                idp.URL,
+		"sub":                "user-1",
+		"aud":                idp.audience,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              idp.nonce,
+		"preferred_username": "jane",
+		"email":              "jane@example.com",
+	})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestOIDCProvider(t *testing.T, idp *fakeIdentityProvider) *OIDCProvider {
+	provider, err := NewOIDCProvider(OIDCConfig{
+		ID:          "corp",
+		Issuer:      idp.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost:8080/auth/corp/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestOIDCProviderIdentify(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdentityProvider(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	flow, _ := NewLoginFlow()
+	authCodeURL, err := provider.AuthCodeURL(ctx, flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authCodeURL, idp.URL+"/authorize") {
+		t.Fatalf("expected the discovered authorization endpoint, got %s", authCodeURL)
+	}
+	idp.authorize(t, authCodeURL)
+
+	identity, err := provider.Identify(ctx, "code", flow)
+	if err != nil {
+		t.Fatalf("Identify: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Name != "jane" || identity.Email != "jane@example.com" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
+
+func TestOIDCProviderRejectsInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		tamper func(idp *fakeIdentityProvider, flow *LoginFlow)
+	}{
+		{"nonce mismatch", func(idp *fakeIdentityProvider, flow *LoginFlow) { idp.nonce = "other" }},
+		{"wrong audience", func(idp *fakeIdentityProvider, flow *LoginFlow) { idp.audience = "other client" }},
+		{"wrong verifier", func(idp *fakeIdentityProvider, flow *LoginFlow) { flow.Verifier = "other" }},
+	}
+	for _, tt := range tests {
+		idp := newFakeIdentityProvider(t)
+		provider := newTestOIDCProvider(t, idp)
+
+		flow, _ := NewLoginFlow()
+		authCodeURL, err := provider.AuthCodeURL(ctx, flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idp.authorize(t, authCodeURL)
+		tt.tamper(idp, &flow)
+
+		if _, err := provider.Identify(ctx, "code", flow); err == nil {
+			t.Errorf("%s: expected the login to be rejected", tt.name)
+		}
+	}
+}
+
+func TestLoginFlowEncoding(t *testing.T) {
+	flow, err := NewLoginFlow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeLoginFlow(flow.Encode())
+	if err != nil || decoded != flow {
+		t.Fatalf("expected %+v, got %+v, %v", flow, decoded, err)
+	}
+	if _, err := DecodeLoginFlow("state-only"); err == nil {
+		t.Fatalf("expected a malformed flow to be rejected")
+	}
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"configuration-management/internal/models"
+	"configuration-management/internal/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/oauth2"
+)
+
+// Identity is a user as authenticated by an identity provider.
+type Identity struct {
+	Subject   string
+	Name      string
+	Email     string
+	AvatarURL string
+}
+
+// LoginFlow holds the values bound to a single login attempt. They are
+// kept in a cookie between the redirect to the provider and the callback.
+type LoginFlow struct {
+	State    string
+	Verifier string
+	Nonce    string
+}
+
+// Provider authenticates users through the OAuth 2.0 authorization code flow.
+type Provider interface {
+	// ID names the provider in URLs and identifies the users it
+	// authenticated, so it must not change once users signed in.
+	ID() string
+	DisplayName() string
+	AuthCodeURL(ctx context.Context, flow LoginFlow) (string, error)
+	// Identify exchanges the authorization code returned to the callback
+	// for the identity of the user.
+	Identify(ctx context.Context, code string, flow LoginFlow) (*Identity, error)
+}
+
+func NewLoginFlow() (LoginFlow, error) {
+	state, err := randomString(16)
+	if err != nil {
+		return LoginFlow{}, err
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		return LoginFlow{}, err
+	}
+
+	return LoginFlow{State: state, Verifier: oauth2.GenerateVerifier(), Nonce: nonce}, nil
+}
+
+// Encode returns the flow as a cookie value.
+func (f LoginFlow) Encode() string {
+	return strings.Join([]string{f.State, f.Verifier, f.Nonce}, ".")
+}
+
+func DecodeLoginFlow(value string) (LoginFlow, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return LoginFlow{}, fmt.Errorf("malformed login flow")
+	}
+
+	return LoginFlow{State: parts[0], Verifier: parts[1], Nonce: parts[2]}, nil
+}
+
+// NewProviders returns GitHub when a client id is configured, followed by
+// the OpenID Connect providers.
+func NewProviders(github GitHubConfig, oidc []OIDCConfig) ([]Provider, error) {
+	var providers []Provider
+	if github.ClientID != "" {
+		providers = append(providers, NewGitHubProvider(github.ClientID, github.ClientSecret))
+	}
+
+	for _, config := range oidc {
+		if config.ID == models.ProviderLocal {
+			return nil, fmt.Errorf("the %q provider id is reserved for local accounts", config.ID)
+		}
+
+		provider, err := NewOIDCProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// exchange trades the authorization code of flow for a token, traced as a
+// span of the provider.
+func exchange(ctx context.Context, provider string, conf *oauth2.Config, code string, flow LoginFlow) (*oauth2.Token, error) {
+	ctx, span := tracing.Start(ctx, "oauth2.Exchange", attribute.String("auth.provider", provider))
+	token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	tracing.End(span, err)
+	return token, err
+}
@@ -0,0 +1,128 @@
+// Package auth holds the rules for authenticating users and keeping their
+// sessions alive.
+package auth
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSessionMaxAge      = 7 * 24 * time.Hour
+	defaultSessionIdleTimeout = 24 * time.Hour
+	// sessionTouchInterval limits how often the last seen time of a
+	// session is written, so that not every request updates the database.
+	sessionTouchInterval = time.Minute
+)
+
+// SessionPolicy decides how long sessions stay valid. A session ends when
+// it reaches MaxAge or has not been used for IdleTimeout.
+type SessionPolicy struct {
+	MaxAge      time.Duration `yaml:"max_age"`
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+func DefaultSessionPolicy() SessionPolicy {
+	return SessionPolicy{
+		MaxAge:      defaultSessionMaxAge,
+		IdleTimeout: defaultSessionIdleTimeout,
+	}
+}
+
+func (p SessionPolicy) ExpiresAt(now time.Time) time.Time {
+	return now.Add(p.MaxAge)
+}
+
+func (p SessionPolicy) Active(session models.Session, now time.Time) bool {
+	return now.Before(session.ExpiresAt) && now.Sub(session.LastSeenAt) < p.IdleTimeout
+}
+
+// NeedsTouch reports whether the last seen time of the session is stale
+// enough to be written again.
+func (p SessionPolicy) NeedsTouch(session models.Session, now time.Time) bool {
+	return now.Sub(session.LastSeenAt) >= sessionTouchInterval
+}
+
+// SessionCleaner periodically deletes the expired and idle sessions.
+type SessionCleaner struct {
+	sessions database.SessionStore
+	policy   SessionPolicy
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewSessionCleaner(sessions database.SessionStore, policy SessionPolicy, interval time.Duration, logger *slog.Logger) *SessionCleaner {
+	return &SessionCleaner{sessions, policy, interval, logger.With("job", "session_cleanup")}
+}
+
+func (c *SessionCleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.Cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *SessionCleaner) Cleanup(ctx context.Context) {
+	now := time.Now().UTC()
+	deleted, err := c.sessions.DeleteExpiredSessions(ctx, now, now.Add(-c.policy.IdleTimeout))
+	if err != nil {
+		c.logger.Error("failed to delete expired sessions", "error", err)
+		return
+	}
+	if deleted > 0 {
+		c.logger.Info("deleted expired sessions", "count", deleted)
+	}
+}
+
+// DescribeDevice returns a short browser and operating system description
+// of a user agent, e.g. "Firefox on Linux".
+func DescribeDevice(userAgent string) string {
+	browser := "Unknown browser"
+	for _, candidate := range []struct{ token, name string }{
+		// Order matters, Chromium based browsers also send the Chrome and
+		// Safari tokens.
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	system := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
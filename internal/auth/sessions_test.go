@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"configuration-management/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSessionPolicyActive(t *testing.T) {
+	policy := SessionPolicy{MaxAge: 24 * time.Hour, IdleTimeout: time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		session models.Session
+		active  bool
+	}{
+		{"recently used", models.Session{ExpiresAt: now.Add(time.Hour), LastSeenAt: now.Add(-time.Minute)}, true},
+		{"expired", models.Session{ExpiresAt: now.Add(-time.Second), LastSeenAt: now}, false},
+		{"idle", models.Session{ExpiresAt: now.Add(time.Hour), LastSeenAt: now.Add(-2 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if active := policy.Active(tt.session, now); active != tt.active {
+			t.Errorf("%s: expected active to be %v", tt.name, tt.active)
+		}
+	}
+}
+
+func TestDescribeDevice(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0":                                                        "Firefox on Linux",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15":         "Safari on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0": "Edge on Windows",
+		"curl/8.5.0": "curl",
+		"":           "Unknown browser",
+	}
+	for userAgent, expected := range tests {
+		if device := DescribeDevice(userAgent); device != expected {
+			t.Errorf("DescribeDevice(%q) = %q, expected %q", userAgent, device, expected)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+// Package config loads the application configuration once at startup. Every
+// setting has a default, which an optional YAML file, the environment and
+// finally the command line flags override in that order.
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/models"
+	"configuration-management/internal/tracing"
+	"configuration-management/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minSessionSecretLength follows the recommended size of the key signing
+// the session cookie.
+const minSessionSecretLength = 32
+
+type Config struct {
+	Port int `yaml:"port"`
+	// BaseURL is the public address of the application, used to build the
+	// links sent to identity providers and by email.
+	BaseURL string `yaml:"base_url"`
+	// ProxyHost is the address of the proxy shown in the connection
+	// details of a configuration.
+	ProxyHost string `yaml:"proxy_host"`
+	// SecretKey is the hex encoded key encrypting the stored secrets, it
+	// must match the key of the proxy.
+	SecretKey     string `yaml:"secret_key"`
+	SessionSecret string `yaml:"session_secret"`
+	// CORSAllowedOrigins defaults to the origin of BaseURL.
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse
+	// proxies whose X-Forwarded-For header names the client address. The
+	// address of the connection is used when empty.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// MetricsToken is the bearer token Prometheus sends to scrape /metrics,
+	// which is not served when empty.
+	MetricsToken string `yaml:"metrics_token"`
+
+	Log                     logging.Config     `yaml:"log"`
+	Tracing                 tracing.Config     `yaml:"tracing"`
+	Database                database.Config    `yaml:"database"`
+	Sessions                auth.SessionPolicy `yaml:"sessions"`
+	SessionsCleanupInterval time.Duration      `yaml:"sessions_cleanup_interval"`
+	AlertsInterval          time.Duration      `yaml:"alerts_interval"`
+	ConfigExpiryInterval    time.Duration      `yaml:"config_expiry_interval"`
+	SMTP                    mail.Config        `yaml:"smtp"`
+	GitHub                  auth.GitHubConfig  `yaml:"github"`
+	OIDC                    []auth.OIDCConfig  `yaml:"oidc"`
+	Local                   auth.LocalAuth     `yaml:"local_auth"`
+}
+
+func Default() *Config {
+	return &Config{
+		Port:    8080,
+		Log:     logging.DefaultConfig(),
+		Tracing: tracing.DefaultConfig(),
+		Database: database.Config{
+			Driver:       database.DriverPostgres,
+			QueryTimeout: database.DefaultQueryTimeout,
+		},
+		Sessions:                auth.DefaultSessionPolicy(),
+		SessionsCleanupInterval: time.Hour,
+		AlertsInterval:          time.Minute,
+		ConfigExpiryInterval:    time.Minute,
+		SMTP:                    mail.Config{Port: "587"},
+		Local:                   auth.DefaultLocalAuth(),
+	}
+}
+
+// Load registers the -config, -port, -base-url and -db-driver flags on fs
+// and parses args, so that the caller can register its own flags first and
+// read the remaining arguments afterwards. The file named by -config or
+// CONFIG_FILE is optional. The returned configuration is not validated.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", "", "YAML configuration file, overrides CONFIG_FILE")
+	port := fs.Int("port", 0, "port to listen on, overrides PORT")
+	baseURL := fs.String("base-url", "", "public URL of the application, overrides BASE_URL")
+	dbDriver := fs.String("db-driver", "", "postgres or sqlite, overrides DB_DRIVER")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			config.Port = *port
+		case "base-url":
+			config.BaseURL = *baseURL
+		case "db-driver":
+			config.Database.Driver = *dbDriver
+		}
+	})
+
+	config.applyDefaults()
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// env reads the variables that are set, collecting the values that cannot
+// be parsed.
+type env struct {
+	errs []error
+}
+
+func (e *env) string(name string, value *string) {
+	if v := os.Getenv(name); v != "" {
+		*value = v
+	}
+}
+
+func (e *env) int(name string, value *int) {
+	if v := os.Getenv(name); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be an integer, got %q", name, v))
+			return
+		}
+		*value = parsed
+	}
+}
+
+func (e *env) bool(name string, value *bool) {
+	if v := os.Getenv(name); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be true or false, got %q", name, v))
+			return
+		}
+		*value = parsed
+	}
+}
+
+func (e *env) float(name string, value *float64) {
+	if v := os.Getenv(name); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a number, got %q", name, v))
+			return
+		}
+		*value = parsed
+	}
+}
+
+func (e *env) duration(name string, value *time.Duration) {
+	if v := os.Getenv(name); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a duration such as 30s or 1h, got %q", name, v))
+			return
+		}
+		*value = parsed
+	}
+}
+
+// list splits a comma or space separated variable.
+func (e *env) list(name string, value *[]string) {
+	if v := os.Getenv(name); v != "" {
+		*value = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+}
+
+func (c *Config) loadEnv() error {
+	var e env
+	e.int("PORT", &c.Port)
+	e.string("BASE_URL", &c.BaseURL)
+	e.string("PROXY_HOST", &c.ProxyHost)
+	e.string("SECRET_KEY", &c.SecretKey)
+	e.string("SESSION_SECRET", &c.SessionSecret)
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORSAllowedOrigins)
+	e.list("TRUSTED_PROXIES", &c.TrustedProxies)
+	e.string("METRICS_TOKEN", &c.MetricsToken)
+	e.string("LOG_FORMAT", &c.Log.Format)
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	e.string("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.string("DB_DRIVER", &c.Database.Driver)
+	e.string("DB_HOST", &c.Database.Host)
+	e.string("DB_PORT", &c.Database.Port)
+	e.string("DB_DATABASE", &c.Database.Database)
+	e.string("DB_USERNAME", &c.Database.Username)
+	e.string("DB_PASSWORD", &c.Database.Password)
+	e.string("DB_SCHEMA", &c.Database.Schema)
+	e.string("DB_PATH", &c.Database.Path)
+	e.duration("DB_QUERY_TIMEOUT", &c.Database.QueryTimeout)
+
+	e.duration("SESSION_MAX_AGE", &c.Sessions.MaxAge)
+	e.duration("SESSION_IDLE_TIMEOUT", &c.Sessions.IdleTimeout)
+	e.duration("SESSIONS_CLEANUP_INTERVAL", &c.SessionsCleanupInterval)
+	e.duration("ALERTS_INTERVAL", &c.AlertsInterval)
+	e.duration("CONFIG_EXPIRY_INTERVAL", &c.ConfigExpiryInterval)
+
+	e.string("SMTP_HOST", &c.SMTP.Host)
+	e.string("SMTP_PORT", &c.SMTP.Port)
+	e.string("SMTP_USERNAME", &c.SMTP.Username)
+	e.string("SMTP_PASSWORD", &c.SMTP.Password)
+	e.string("SMTP_FROM", &c.SMTP.From)
+
+	e.string("GITHUB_CLIENT_ID", &c.GitHub.ClientID)
+	e.string("GITHUB_CLIENT_SECRET", &c.GitHub.ClientSecret)
+
+	// The providers listed in OIDC_PROVIDERS replace the ones of the file.
+	var oidcProviders []string
+	e.list("OIDC_PROVIDERS", &oidcProviders)
+	if oidcProviders != nil {
+		c.OIDC = nil
+	}
+	for _, id := range oidcProviders {
+		prefix := "OIDC_" + strings.ToUpper(id) + "_"
+		provider := auth.OIDCConfig{ID: id}
+		e.string(prefix+"NAME", &provider.Name)
+		e.string(prefix+"ISSUER", &provider.Issuer)
+		e.string(prefix+"CLIENT_ID", &provider.ClientID)
+		e.string(prefix+"CLIENT_SECRET", &provider.ClientSecret)
+		e.string(prefix+"REDIRECT_URL", &provider.RedirectURL)
+		e.list(prefix+"SCOPES", &provider.Scopes)
+		c.OIDC = append(c.OIDC, provider)
+	}
+
+	e.bool("LOCAL_AUTH", &c.Local.Enabled)
+	e.string("LOCAL_SIGNUP", &c.Local.Signup)
+	e.duration("INVITE_TTL", &c.Local.InviteTTL)
+	e.duration("PASSWORD_RESET_TTL", &c.Local.PasswordResetTTL)
+
+	return errors.Join(e.errs...)
+}
+
+// applyDefaults fills in the settings derived from other ones.
+func (c *Config) applyDefaults() {
+	if c.BaseURL == "" {
+		c.BaseURL = "http://localhost:" + strconv.Itoa(c.Port)
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+
+	origins := c.CORSAllowedOrigins
+	c.CORSAllowedOrigins = nil
+	for _, origin := range origins {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			c.CORSAllowedOrigins = append(c.CORSAllowedOrigins, origin)
+		}
+	}
+	if len(c.CORSAllowedOrigins) == 0 {
+		c.CORSAllowedOrigins = []string{c.BaseURL}
+	}
+
+	for i, provider := range c.OIDC {
+		if provider.RedirectURL == "" {
+			c.OIDC[i].RedirectURL = c.BaseURL + "/auth/" + provider.ID + "/callback"
+		}
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	if err := validateURL(c.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("BASE_URL %v", err))
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if err := validateURL(origin); err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS %v", err))
+		}
+	}
+	if _, err := c.TrustedProxyNetworks(); err != nil {
+		errs = append(errs, err)
+	}
+	if key, err := hex.DecodeString(c.SecretKey); err != nil || len(key) != 32 {
+		errs = append(errs, fmt.Errorf("SECRET_KEY must be 32 hex encoded bytes (64 characters), generate one with `make generate-secret-key`"))
+	}
+	if len(c.SessionSecret) < minSessionSecretLength {
+		errs = append(errs, fmt.Errorf("SESSION_SECRET must be at least %d characters long", minSessionSecretLength))
+	}
+
+	if err := c.Log.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Sessions.MaxAge <= 0 {
+		errs = append(errs, fmt.Errorf("SESSION_MAX_AGE must be positive, got %s", c.Sessions.MaxAge))
+	}
+	if c.Sessions.IdleTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SESSION_IDLE_TIMEOUT must be positive, got %s", c.Sessions.IdleTimeout))
+	}
+	if c.SessionsCleanupInterval <= 0 {
+		errs = append(errs, fmt.Errorf("SESSIONS_CLEANUP_INTERVAL must be positive, got %s", c.SessionsCleanupInterval))
+	}
+	if c.AlertsInterval <= 0 {
+		errs = append(errs, fmt.Errorf("ALERTS_INTERVAL must be positive, got %s", c.AlertsInterval))
+	}
+	if c.ConfigExpiryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("CONFIG_EXPIRY_INTERVAL must be positive, got %s", c.ConfigExpiryInterval))
+	}
+	if c.SMTP.Host != "" && c.SMTP.From == "" {
+		errs = append(errs, fmt.Errorf("SMTP_FROM is required when SMTP_HOST is set"))
+	}
+
+	if c.GitHub.ClientID != "" && c.GitHub.ClientSecret == "" {
+		errs = append(errs, fmt.Errorf("GITHUB_CLIENT_SECRET is required when GITHUB_CLIENT_ID is set"))
+	}
+	ids := map[string]bool{"github": c.GitHub.ClientID != ""}
+	for _, provider := range c.OIDC {
+		switch {
+		case provider.ID == "":
+			errs = append(errs, fmt.Errorf("every OpenID Connect provider needs an id"))
+			continue
+		case provider.ID == models.ProviderLocal:
+			errs = append(errs, fmt.Errorf("the %q provider id is reserved for local accounts", provider.ID))
+		case ids[provider.ID]:
+			errs = append(errs, fmt.Errorf("the %q provider is configured twice", provider.ID))
+		}
+		ids[provider.ID] = true
+
+		prefix := "OIDC_" + strings.ToUpper(provider.ID) + "_"
+		if provider.Issuer == "" {
+			errs = append(errs, fmt.Errorf("%sISSUER is required", prefix))
+		}
+		if provider.ClientID == "" {
+			errs = append(errs, fmt.Errorf("%sCLIENT_ID is required", prefix))
+		}
+	}
+	if err := c.Local.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.GitHub.ClientID == "" && len(c.OIDC) == 0 && !c.Local.Enabled {
+		errs = append(errs, fmt.Errorf("no login method is configured, set GITHUB_CLIENT_ID, OIDC_PROVIDERS or LOCAL_AUTH"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("must be an http or https URL, got %q", value)
+	}
+	return nil
+}
+
+// SecureCookies reports whether the application is served over https.
+func (c *Config) SecureCookies() bool {
+	return strings.HasPrefix(c.BaseURL, "https://")
+}
+
+// TrustedProxyNetworks parses TrustedProxies, a single address is a network
+// of its own.
+func (c *Config) TrustedProxyNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, proxy := range c.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES must list addresses or CIDR ranges, got %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (c *Config) Encrypter() (*utils.Encrypter, error) {
+	key, err := hex.DecodeString(c.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SECRET_KEY: %v", err)
+	}
+	return utils.NewEncrypter(key)
+}
+
+func (c *Config) Providers() ([]auth.Provider, error) {
+	return auth.NewProviders(c.GitHub, c.OIDC)
+}
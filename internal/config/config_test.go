@@ -0,0 +1,164 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+)
+
+func validConfig() *Config {
+	config := Default()
+	config.SecretKey = strings.Repeat("ab", 32)
+	config.SessionSecret = strings.Repeat("s", minSessionSecretLength)
+	config.Database = database.Config{Driver: database.DriverSQLite, Path: "data.db"}
+	config.GitHub = auth.GitHubConfig{ClientID: "id", ClientSecret: "secret"}
+	config.applyDefaults()
+	return config
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+port: 9000
+base_url: https://file.example.com/
+alerts_interval: 5m
+database:
+  driver: sqlite
+  path: file.db
+oidc:
+  - id: corp
+    issuer: https://sso.example.com
+    client_id: limiter
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_PATH", "env.db")
+	t.Setenv("PORT", "9001")
+
+	config, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-port", "9002"})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if config.Port != 9002 {
+		t.Errorf("expected the flag to override the env, got port %d", config.Port)
+	}
+	if config.Database.Path != "env.db" || config.Database.Driver != database.DriverSQLite {
+		t.Errorf("expected the env to override the file, got %+v", config.Database)
+	}
+	if config.AlertsInterval != 5*time.Minute || config.SessionsCleanupInterval != time.Hour {
+		t.Errorf("expected the file to override the defaults, got %s and %s", config.AlertsInterval, config.SessionsCleanupInterval)
+	}
+	if config.BaseURL != "https://file.example.com" {
+		t.Errorf("expected the trailing slash to be trimmed, got %s", config.BaseURL)
+	}
+	if len(config.CORSAllowedOrigins) != 1 || config.CORSAllowedOrigins[0] != config.BaseURL {
+		t.Errorf("expected BASE_URL to be allowed by default, got %v", config.CORSAllowedOrigins)
+	}
+	if len(config.OIDC) != 1 || config.OIDC[0].RedirectURL != "https://file.example.com/auth/corp/callback" {
+		t.Errorf("expected the redirect URL to default to the callback, got %+v", config.OIDC)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com/")
+	t.Setenv("OIDC_PROVIDERS", "corp")
+	t.Setenv("OIDC_CORP_ISSUER", "https://sso.example.com")
+	t.Setenv("OIDC_CORP_SCOPES", "groups,offline_access")
+	t.Setenv("LOCAL_AUTH", "true")
+	t.Setenv("LOCAL_SIGNUP", "open")
+
+	config, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	origins := config.CORSAllowedOrigins
+	if len(origins) != 2 || origins[0] != "https://a.example.com" || origins[1] != "https://b.example.com" {
+		t.Errorf("unexpected origins %v", origins)
+	}
+	if len(config.OIDC) != 1 || len(config.OIDC[0].Scopes) != 2 || config.OIDC[0].Issuer != "https://sso.example.com" {
+		t.Errorf("unexpected providers %+v", config.OIDC)
+	}
+	if !config.Local.Enabled || config.Local.Signup != auth.SignupOpen || config.Local.InviteTTL != 72*time.Hour {
+		t.Errorf("unexpected local authentication %+v", config.Local)
+	}
+}
+
+func TestTrustedProxyNetworks(t *testing.T) {
+	config := validConfig()
+	config.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::1"}
+	networks, err := config.TrustedProxyNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 3 || networks[0].String() != "10.0.0.0/8" || networks[1].String() != "192.0.2.1/32" ||
+		networks[2].String() != "2001:db8::1/128" {
+		t.Fatalf("unexpected networks %v", networks)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("ALERTS_INTERVAL", "5")
+	t.Setenv("LOCAL_AUTH", "yes please")
+	t.Setenv("TRACING_SAMPLE_RATIO", "all")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil {
+		t.Fatalf("expected invalid values to be rejected")
+	}
+	for _, name := range []string{"PORT", "ALERTS_INTERVAL", "LOCAL_AUTH", "TRACING_SAMPLE_RATIO"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to name %s, got %v", name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("expected the configuration to be valid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		error  string
+	}{
+		{"short secret key", func(c *Config) { c.SecretKey = "abcd" }, "SECRET_KEY must be 32 hex encoded bytes"},
+		{"secret key not hex", func(c *Config) { c.SecretKey = strings.Repeat("zz", 32) }, "SECRET_KEY"},
+		{"short session secret", func(c *Config) { c.SessionSecret = "secret" }, "SESSION_SECRET"},
+		{"base URL", func(c *Config) { c.BaseURL = "limiter.example.com" }, "BASE_URL"},
+		{"port", func(c *Config) { c.Port = 70000 }, "PORT"},
+		{"log level", func(c *Config) { c.Log.Level = "verbose" }, "LOG_LEVEL"},
+		{"sample ratio", func(c *Config) { c.Tracing.SampleRatio = 2 }, "TRACING_SAMPLE_RATIO"},
+		{"otlp endpoint", func(c *Config) { c.Tracing.Endpoint = "collector:4318" }, "OTEL_EXPORTER_OTLP_ENDPOINT"},
+		{"database", func(c *Config) { c.Database.Driver = "mysql" }, "DB_DRIVER"},
+		{"postgres", func(c *Config) { c.Database.Driver = database.DriverPostgres }, "DB_HOST"},
+		{"session max age", func(c *Config) { c.Sessions.MaxAge = 0 }, "SESSION_MAX_AGE"},
+		{"smtp", func(c *Config) { c.SMTP.Host = "smtp.example.com" }, "SMTP_FROM"},
+		{"signup", func(c *Config) { c.Local.Signup = "anyone" }, "LOCAL_SIGNUP"},
+		{"oidc", func(c *Config) { c.OIDC = []auth.OIDCConfig{{ID: "corp"}} }, "OIDC_CORP_ISSUER"},
+		{"reserved provider", func(c *Config) {
+			c.OIDC = []auth.OIDCConfig{{ID: "local", Issuer: "https://sso.example.com", ClientID: "id"}}
+		}, "reserved"},
+		{"no login", func(c *Config) { c.GitHub = auth.GitHubConfig{} }, "no login method"},
+		{"trusted proxies", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"} }, "TRUSTED_PROXIES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Fatalf("expected an error containing %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package connection writes how clients reach the proxy through a config: the
+// proxy URL, which authenticates with the access key of the project or with
+// the secret of a config client, and ready to use snippets for common
+// clients.
+package connection
+
+import (
+	"configuration-management/internal/models"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+)
+
+// ExampleURL is requested through the proxy by the client snippets.
+const ExampleURL = "https://api.example.com/"
+
+const (
+	FormatURL    = "url"
+	FormatEnv    = "env"
+	FormatDotenv = "dotenv"
+	FormatCurl   = "curl"
+	FormatPython = "python"
+	FormatGo     = "go"
+	FormatNode   = "node"
+)
+
+type Format struct {
+	Name  string
+	Title string
+}
+
+// Formats are listed in the order the connection panel shows them.
+var Formats = []Format{
+	{FormatURL, "Proxy URL"},
+	{FormatEnv, "HTTPS_PROXY"},
+	{FormatCurl, "curl"},
+	{FormatPython, "Python requests"},
+	{FormatGo, "Go http.Transport"},
+	{FormatNode, "Node"},
+	{FormatDotenv, ".env"},
+}
+
+//go:embed snippets.tmpl
+var snippets string
+
+var templates = template.Must(template.New("snippets").Parse(snippets))
+
+// Connection holds what the snippets are written from.
+type Connection struct {
+	ConfigID    uuid.UUID
+	ConfigName  string
+	ProjectName string
+	// ClientName is empty when connecting with the project access key.
+	ClientName string
+	// CredentialID is the project id with the access key as Secret, or the
+	// id of a config client with its secret.
+	CredentialID uuid.UUID
+	Secret       string
+	ProxyHost    string
+	ExampleURL   string
+}
+
+type Snippet struct {
+	Format
+	Text string
+}
+
+// New connects with the access key of the project.
+func New(config models.Config, project models.Project, proxyHost string) Connection {
+	return Connection{
+		ConfigID:     config.ID,
+		ConfigName:   config.Name,
+		ProjectName:  project.Name,
+		CredentialID: project.ID,
+		Secret:       project.AccessKey,
+		ProxyHost:    proxyHost,
+		ExampleURL:   ExampleURL,
+	}
+}
+
+// NewClient connects as a client of the config, its secret is only known
+// when it is created.
+func NewClient(config models.Config, project models.Project, client models.ConfigClient, secret string, proxyHost string) Connection {
+	conn := New(config, project, proxyHost)
+	conn.ClientName = client.Name
+	conn.CredentialID = client.ID
+	conn.Secret = secret
+	return conn
+}
+
+// URL is the proxy URL, such as
+// https://configID:projectID:accessKey@proxy.example.com, or
+// https://configID:clientID:secret@proxy.example.com for a client.
+func (c Connection) URL() string {
+	url, _ := c.Snippet(FormatURL)
+	return url
+}
+
+// Snippet writes the connection in one of the Formats.
+func (c Connection) Snippet(format string) (string, error) {
+	if !isFormat(format) {
+		return "", fmt.Errorf("unknown connection format %q", format)
+	}
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, format, c); err != nil {
+		return "", fmt.Errorf("failed to write %s connection: %v", format, err)
+	}
+	return b.String(), nil
+}
+
+// Snippets writes the connection in every format.
+func (c Connection) Snippets() []Snippet {
+	snippets := make([]Snippet, 0, len(Formats))
+	for _, format := range Formats {
+		text, _ := c.Snippet(format.Name)
+		snippets = append(snippets, Snippet{format, text})
+	}
+	return snippets
+}
+
+func isFormat(name string) bool {
+	return slices.ContainsFunc(Formats, func(format Format) bool { return format.Name == name })
+}
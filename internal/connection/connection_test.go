@@ -0,0 +1,49 @@
+package connection
+
+import (
+	"configuration-management/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSnippets(t *testing.T) {
+	config := models.Config{ID: uuid.New(), Name: "stripe"}
+	project := models.Project{ID: uuid.New(), Name: "payments", AccessKey: "key"}
+	conn := New(config, project, "proxy.example.com")
+
+	url := "https://" + config.ID.String() + ":" + project.ID.String() + ":key@proxy.example.com"
+	if conn.URL() != url {
+		t.Fatalf("expected the URL %q, got %q", url, conn.URL())
+	}
+
+	snippets := conn.Snippets()
+	if len(snippets) != len(Formats) {
+		t.Fatalf("expected a snippet per format, got %d", len(snippets))
+	}
+	for _, snippet := range snippets {
+		if !strings.Contains(snippet.Text, url) {
+			t.Fatalf("expected the %s snippet to contain the URL, got %q", snippet.Name, snippet.Text)
+		}
+	}
+
+	if env, _ := conn.Snippet(FormatEnv); env != "export HTTPS_PROXY='"+url+"'" {
+		t.Fatalf("unexpected env snippet %q", env)
+	}
+	if dotenv, _ := conn.Snippet(FormatDotenv); !strings.HasSuffix(dotenv, "\nHTTPS_PROXY="+url+"\n") {
+		t.Fatalf("unexpected .env snippet %q", dotenv)
+	}
+	if _, err := conn.Snippet("ruby"); err == nil {
+		t.Fatalf("expected an unknown format to be rejected")
+	}
+
+	client := models.ConfigClient{ID: uuid.New(), Name: "billing"}
+	conn = NewClient(config, project, client, "secret", "proxy.example.com")
+	if want := "https://" + config.ID.String() + ":" + client.ID.String() + ":secret@proxy.example.com"; conn.URL() != want {
+		t.Fatalf("expected the client URL %q, got %q", want, conn.URL())
+	}
+	if dotenv, _ := conn.Snippet(FormatDotenv); !strings.HasPrefix(dotenv, "# Proxy of config stripe of project payments for client billing\n") {
+		t.Fatalf("expected the .env snippet to name the client, got %q", dotenv)
+	}
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *DatabaseHandler) ListAlerts(ctx context.Context, configID uuid.UUID) (_ []models.Alert, err error) {
+	ctx, end := s.startQuery(ctx, "ListAlerts")
+	defer func() { end(err) }()
+
+	return s.listAlertsWhere(ctx, "c.id = $1", configID)
+}
+
+// listAlertsWhere lists the alerts of the configs matching the condition,
+// the configs table is aliased as c.
+func (s *DatabaseHandler) listAlertsWhere(ctx context.Context, condition string, args ...any) ([]models.Alert, error) {
+	query := `
+		SELECT a.id, a.config_id, a.kind, a.threshold, a.window_minutes, a.channel, a.target,
+			a.state, a.last_value, a.last_evaluated_at, a.last_triggered_at
+		FROM alerts a
+		JOIN configs c ON c.id = a.config_id
+		WHERE ` + condition
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %v", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			return nil, fmt.Errorf("failed to scan alert row: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+func (s *DatabaseHandler) ListAllAlerts(ctx context.Context) (_ []models.Alert, err error) {
+	ctx, end := s.startQuery(ctx, "ListAllAlerts")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT id, config_id, kind, threshold, window_minutes, channel, target,
+			state, last_value, last_evaluated_at, last_triggered_at
+		FROM alerts
+	`
+	rows, err := s.conn().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %v", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			return nil, fmt.Errorf("failed to scan alert row: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+func (s *DatabaseHandler) GetAlert(ctx context.Context, alertID uuid.UUID) (_ *models.Alert, err error) {
+	ctx, end := s.startQuery(ctx, "GetAlert")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT id, config_id, kind, threshold, window_minutes, channel, target,
+			state, last_value, last_evaluated_at, last_triggered_at
+		FROM alerts
+		WHERE id = $1
+	`
+	var alert models.Alert
+	if err := scanAlert(s.conn().QueryRowContext(ctx, query, alertID), &alert); err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", notFound(err))
+	}
+
+	return &alert, nil
+}
+
+func (s *DatabaseHandler) CreateAlert(ctx context.Context, configID uuid.UUID, kind string, threshold int,
+	windowMinutes int, channel string, target string) (_ *models.Alert, err error) {
+	ctx, end := s.startQuery(ctx, "CreateAlert")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO alerts (config_id, kind, threshold, window_minutes, channel, target)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, config_id, kind, threshold, window_minutes, channel, target,
+			state, last_value, last_evaluated_at, last_triggered_at
+	`
+	var alert models.Alert
+	row := s.conn().QueryRowContext(ctx, query, configID, kind, threshold, windowMinutes, channel, target)
+	if err := scanAlert(row, &alert); err != nil {
+		return nil, fmt.Errorf("failed to create alert: %v", err)
+	}
+
+	return &alert, nil
+}
+
+func (s *DatabaseHandler) UpdateAlertState(ctx context.Context, alertID uuid.UUID, state string, value int,
+	evaluatedAt time.Time, triggeredAt *time.Time) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateAlertState")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE alerts
+		SET state = $2, last_value = $3, last_evaluated_at = $4,
+			last_triggered_at = COALESCE($5, last_triggered_at)
+		WHERE id = $1
+	`
+	// Times are stored in UTC so that SQLite, which compares them as text,
+	// orders them the same way Postgres does.
+	if triggeredAt != nil {
+		utc := triggeredAt.UTC()
+		triggeredAt = &utc
+	}
+	if _, err := s.conn().ExecContext(ctx, query, alertID, state, value, evaluatedAt.UTC(), triggeredAt); err != nil {
+		return fmt.Errorf("failed to update alert state: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteAlert(ctx context.Context, alertID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteAlert")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM alerts WHERE id=$1
+	`
+	_, err = s.conn().ExecContext(ctx, query, alertID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert: %v", err)
+	}
+
+	return nil
+}
+
+// CountConfigUsage returns the number of proxied and rejected requests
+// recorded for the config since the given time.
+func (s *DatabaseHandler) CountConfigUsage(ctx context.Context, configID uuid.UUID, since time.Time) (_ int, _ int, err error) {
+	ctx, end := s.startQuery(ctx, "CountConfigUsage")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE rejected)
+		FROM config_usage
+		WHERE config_id = $1 AND created_at >= $2
+	`
+	var requests, rejected int
+	if err := s.conn().QueryRowContext(ctx, query, configID, since.UTC()).Scan(&requests, &rejected); err != nil {
+		return 0, 0, fmt.Errorf("failed to count config usage: %v", err)
+	}
+
+	return requests, rejected, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAlert(row rowScanner, alert *models.Alert) error {
+	return row.Scan(
+		&alert.ID, &alert.ConfigID, &alert.Kind, &alert.Threshold, &alert.WindowMinutes,
+		&alert.Channel, &alert.Target, &alert.State, &alert.LastValue,
+		&alert.LastEvaluatedAt, &alert.LastTriggeredAt,
+	)
+}
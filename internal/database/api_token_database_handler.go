@@ -0,0 +1,114 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const apiTokenColumns = "id, user_id, name, token_hash, last_used_at, created_at"
+
+func scanAPIToken(row rowScanner, token *models.APIToken) error {
+	return row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.LastUsedAt, &token.CreatedAt)
+}
+
+func (s *DatabaseHandler) CreateAPIToken(ctx context.Context, userID uuid.UUID, name string, tokenHash string) (_ *models.APIToken, err error) {
+	ctx, end := s.startQuery(ctx, "CreateAPIToken")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_hash)
+		VALUES ($1, $2, $3)
+		RETURNING ` + apiTokenColumns
+	var token models.APIToken
+	if err := scanAPIToken(s.conn().QueryRowContext(ctx, query, userID, name, tokenHash), &token); err != nil {
+		return nil, fmt.Errorf("failed to create api token: %v", err)
+	}
+
+	return &token, nil
+}
+
+func (s *DatabaseHandler) GetAPIToken(ctx context.Context, tokenHash string) (_ *models.APIToken, err error) {
+	ctx, end := s.startQuery(ctx, "GetAPIToken")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = $1
+	`
+	var token models.APIToken
+	if err := scanAPIToken(s.conn().QueryRowContext(ctx, query, tokenHash), &token); err != nil {
+		return nil, fmt.Errorf("failed to get api token: %w", notFound(err))
+	}
+
+	return &token, nil
+}
+
+func (s *DatabaseHandler) ListAPITokens(ctx context.Context, userID uuid.UUID) (_ []models.APIToken, err error) {
+	ctx, end := s.startQuery(ctx, "ListAPITokens")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := s.conn().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api tokens: %v", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		var token models.APIToken
+		if err := scanAPIToken(rows, &token); err != nil {
+			return nil, fmt.Errorf("failed to scan api token row: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query api tokens: %v", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *DatabaseHandler) TouchAPIToken(ctx context.Context, tokenID uuid.UUID, lastUsedAt time.Time) (err error) {
+	ctx, end := s.startQuery(ctx, "TouchAPIToken")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE api_tokens SET last_used_at = $2 WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, tokenID, lastUsedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to update api token: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteAPIToken(ctx context.Context, userID uuid.UUID, tokenID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteAPIToken")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM api_tokens WHERE id = $1 AND user_id = $2
+	`
+	result, err := s.conn().ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete api token: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete api token: %v", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("failed to delete api token: %w", ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const configClientColumns = "id, config_id, name, secret_hash, expires_at, limit_requests_count, last_used_at, created_at"
+
+func scanConfigClient(row rowScanner, client *models.ConfigClient) error {
+	return row.Scan(&client.ID, &client.ConfigID, &client.Name, &client.SecretHash, &client.ExpiresAt,
+		&client.LimitNumberOfRequests, &client.LastUsedAt, &client.CreatedAt)
+}
+
+func (s *DatabaseHandler) CreateConfigClient(ctx context.Context, client models.ConfigClient) (_ *models.ConfigClient, err error) {
+	ctx, end := s.startQuery(ctx, "CreateConfigClient")
+	defer func() { end(err) }()
+
+	if client.ExpiresAt != nil {
+		expiresAt := client.ExpiresAt.UTC()
+		client.ExpiresAt = &expiresAt
+	}
+	query := `
+		INSERT INTO config_clients (config_id, name, secret_hash, expires_at, limit_requests_count)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + configClientColumns
+	row := s.conn().QueryRowContext(ctx, query, client.ConfigID, client.Name, client.SecretHash,
+		client.ExpiresAt, client.LimitNumberOfRequests)
+	var created models.ConfigClient
+	if err := scanConfigClient(row, &created); err != nil {
+		return nil, fmt.Errorf("failed to create config client: %v", err)
+	}
+
+	return &created, nil
+}
+
+func (s *DatabaseHandler) ListConfigClients(ctx context.Context, configID uuid.UUID) (_ []models.ConfigClient, err error) {
+	ctx, end := s.startQuery(ctx, "ListConfigClients")
+	defer func() { end(err) }()
+
+	return s.listConfigClientsWhere(ctx, "c.id = $1", configID)
+}
+
+// listConfigClientsWhere lists the clients of the configs matching the
+// condition, the configs table is aliased as c.
+func (s *DatabaseHandler) listConfigClientsWhere(ctx context.Context, condition string, args ...any) ([]models.ConfigClient, error) {
+	query := `
+		SELECT cc.id, cc.config_id, cc.name, cc.secret_hash, cc.expires_at, cc.limit_requests_count,
+			cc.last_used_at, cc.created_at
+		FROM config_clients cc
+		JOIN configs c ON c.id = cc.config_id
+		WHERE ` + condition + `
+		ORDER BY cc.created_at
+	`
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query config clients: %v", err)
+	}
+	defer rows.Close()
+
+	var clients []models.ConfigClient
+	for rows.Next() {
+		var client models.ConfigClient
+		if err := scanConfigClient(rows, &client); err != nil {
+			return nil, fmt.Errorf("failed to scan config client row: %v", err)
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+func (s *DatabaseHandler) DeleteConfigClient(ctx context.Context, configID uuid.UUID, clientID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteConfigClient")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM config_clients WHERE id = $1 AND config_id = $2
+	`
+	result, err := s.conn().ExecContext(ctx, query, clientID, configID)
+	if err != nil {
+		return fmt.Errorf("failed to delete config client: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete config client: %v", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("failed to delete config client: %w", ErrNotFound)
+	}
+
+	return nil
+}
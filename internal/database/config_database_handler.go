@@ -2,85 +2,282 @@ package database
 
 import (
 	"configuration-management/internal/models"
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-func (s *DatabaseHandler) GetConfig(configID uuid.UUID) (*models.Config, error) {
-	query := `
-		SELECT id, project_id, name, limit_requests_count, limit_duration
-		FROM configs
-		WHERE id = $1
-	`
-	var config models.Config
-	if err := s.DB.QueryRow(query, configID).Scan(
+const configColumns = `c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration,
+	c.active_from, c.expires_at, c.disabled_at, c.disabled_reason, c.disabled_by`
+
+// scanConfig scans the configColumns.
+func scanConfig(row rowScanner, config *models.Config) error {
+	var disabledAt *time.Time
+	var disabledReason, disabledBy string
+	if err := row.Scan(
 		&config.ID, &config.ProjectID, &config.Name,
 		&config.LimitNumberOfRequests, &config.LimitPer,
+		&config.ActiveFrom, &config.ExpiresAt, &disabledAt, &disabledReason, &disabledBy,
 	); err != nil {
-		return nil, fmt.Errorf("failed to scan config: %v", err)
+		return err
+	}
+	config.Disabled = models.NewDisablement(disabledAt, disabledReason, disabledBy)
+	return nil
+}
+
+func (s *DatabaseHandler) GetConfig(ctx context.Context, configID uuid.UUID) (_ *models.Config, err error) {
+	ctx, end := s.startQuery(ctx, "GetConfig")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + configColumns + `
+		FROM configs c
+		WHERE c.id = $1
+	`
+	var config models.Config
+	if err := scanConfig(s.conn().QueryRowContext(ctx, query, configID), &config); err != nil {
+		return nil, fmt.Errorf("failed to scan config: %w", notFound(err))
 	}
 
+	schedules, err := s.listConfigSchedulesWhere(ctx, "c.id = $1", configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config schedules: %v", err)
+	}
+	config.Schedule = schedules[config.ID]
+
 	return &config, nil
 }
 
-func (s *DatabaseHandler) ListConfigs(projectID uuid.UUID) ([]models.Config, error) {
+func (s *DatabaseHandler) ListConfigs(ctx context.Context, projectID uuid.UUID) (_ []models.Config, err error) {
+	ctx, end := s.startQuery(ctx, "ListConfigs")
+	defer func() { end(err) }()
+
+	return s.listConfigsWhere(ctx, "c.project_id = $1", projectID)
+}
+
+// listConfigsWhere loads the configs matching the condition, together with
+// their header replacements, alerts, clients and schedules, with one query
+// per table.
+// The condition refers to the configs table as c.
+func (s *DatabaseHandler) listConfigsWhere(ctx context.Context, condition string, args ...any) ([]models.Config, error) {
 	query := `
-		SELECT id, project_id, name, limit_requests_count, limit_duration
-		FROM configs
-		WHERE project_id = $1
-	`
+		SELECT ` + configColumns + `
+		FROM configs c
+		WHERE ` + condition
 
-	rows, err := s.DB.Query(query, projectID)
+	rows, err := s.conn().QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query projects: %v", err)
+		return nil, fmt.Errorf("failed to query configs: %v", err)
 	}
 	defer rows.Close()
 
 	var configs []models.Config
+	configsByID := make(map[uuid.UUID]int)
 	for rows.Next() {
 		var config models.Config
-		if err := rows.Scan(
-			&config.ID, &config.ProjectID, &config.Name,
-			&config.LimitNumberOfRequests, &config.LimitPer,
-		); err != nil {
+		if err := scanConfig(rows, &config); err != nil {
 			return nil, fmt.Errorf("failed to scan config row: %v", err)
 		}
-		// list header replacements
-		replacements, replacementsErr := s.ListHeaderReplacements(config.ID)
-		if replacementsErr != nil {
-			return nil, fmt.Errorf("failed to list header replacements for configID: %s:  %v", config.ID.String(), replacementsErr)
-		}
-		config.HeaderReplacements = replacements
+		configsByID[config.ID] = len(configs)
 		configs = append(configs, config)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query configs: %v", err)
+	}
+
+	if len(configs) == 0 {
+		return configs, nil
+	}
+
+	replacements, replacementsErr := s.listHeaderReplacementsWhere(ctx, condition, args...)
+	if replacementsErr != nil {
+		return nil, fmt.Errorf("failed to list header replacements: %v", replacementsErr)
+	}
+	for _, replacement := range replacements {
+		config := &configs[configsByID[replacement.ConfigID]]
+		config.HeaderReplacements = append(config.HeaderReplacements, replacement)
+	}
+
+	alerts, alertsErr := s.listAlertsWhere(ctx, condition, args...)
+	if alertsErr != nil {
+		return nil, fmt.Errorf("failed to list alerts: %v", alertsErr)
+	}
+	for _, alert := range alerts {
+		config := &configs[configsByID[alert.ConfigID]]
+		config.Alerts = append(config.Alerts, alert)
+	}
+
+	clients, clientsErr := s.listConfigClientsWhere(ctx, condition, args...)
+	if clientsErr != nil {
+		return nil, fmt.Errorf("failed to list config clients: %v", clientsErr)
+	}
+	for _, client := range clients {
+		config := &configs[configsByID[client.ConfigID]]
+		config.Clients = append(config.Clients, client)
+	}
+
+	schedules, schedulesErr := s.listConfigSchedulesWhere(ctx, condition, args...)
+	if schedulesErr != nil {
+		return nil, fmt.Errorf("failed to list config schedules: %v", schedulesErr)
+	}
+	for configID, windows := range schedules {
+		configs[configsByID[configID]].Schedule = windows
+	}
 
 	return configs, nil
 }
 
-func (s *DatabaseHandler) CreateConfig(projectID uuid.UUID, name string,
-	numberOfRequests int, per string) (*models.Config, error) {
+func (s *DatabaseHandler) CreateConfig(ctx context.Context, projectID uuid.UUID, name string,
+	numberOfRequests int, per string) (_ *models.Config, err error) {
+	ctx, end := s.startQuery(ctx, "CreateConfig")
+	defer func() { end(err) }()
+
 	query := `
 		INSERT into configs (project_id, name, limit_requests_count, limit_duration)
 		VALUES ($1, $2, $3, $4) 
-		RETURNING id, project_id, name, limit_requests_count, limit_duration
+		RETURNING id, project_id, name, limit_requests_count, limit_duration,
+			active_from, expires_at, disabled_at, disabled_reason, disabled_by
 	`
 	var config models.Config
-	if err := s.DB.QueryRow(query, projectID, name, numberOfRequests, per).Scan(
-		&config.ID, &config.ProjectID, &config.Name,
-		&config.LimitNumberOfRequests, &config.LimitPer,
-	); err != nil {
+	if err := scanConfig(s.conn().QueryRowContext(ctx, query, projectID, name, numberOfRequests, per), &config); err != nil {
 		return nil, fmt.Errorf("failed to create config: %v", err)
 	}
 
 	return &config, nil
 }
 
-func (s *DatabaseHandler) DeleteConfig(configID uuid.UUID) error {
+func (s *DatabaseHandler) UpdateConfigLimit(ctx context.Context, configID uuid.UUID, numberOfRequests int, per string) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateConfigLimit")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE configs SET limit_requests_count = $1, limit_duration = $2 WHERE id = $3
+	`
+	if _, err := s.conn().ExecContext(ctx, query, numberOfRequests, per, configID); err != nil {
+		return fmt.Errorf("failed to update config limit: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateConfigAvailability enables the config again when it was disabled on
+// expiry, the assignments all read the row as it was before the update.
+func (s *DatabaseHandler) UpdateConfigAvailability(ctx context.Context, configID uuid.UUID, activeFrom *time.Time, expiresAt *time.Time) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateConfigAvailability")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE configs SET active_from = $2, expires_at = $3,
+			disabled_at = CASE WHEN disabled_reason = $4 THEN NULL ELSE disabled_at END,
+			disabled_by = CASE WHEN disabled_reason = $4 THEN '' ELSE disabled_by END,
+			disabled_reason = CASE WHEN disabled_reason = $4 THEN '' ELSE disabled_reason END
+		WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, configID, utcOrNil(activeFrom), utcOrNil(expiresAt), models.DisabledReasonExpired); err != nil {
+		return fmt.Errorf("failed to update config availability: %v", err)
+	}
+
+	return nil
+}
+
+// ListExpiredConfigs lists the configs that expired at now and are not
+// disabled yet.
+func (s *DatabaseHandler) ListExpiredConfigs(ctx context.Context, now time.Time) (_ []models.Config, err error) {
+	ctx, end := s.startQuery(ctx, "ListExpiredConfigs")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + configColumns + `
+		FROM configs c
+		WHERE c.expires_at <= $1 AND c.disabled_at IS NULL
+	`
+	rows, err := s.conn().QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired configs: %v", err)
+	}
+	defer rows.Close()
+
+	var configs []models.Config
+	for rows.Next() {
+		var config models.Config
+		if err := scanConfig(rows, &config); err != nil {
+			return nil, fmt.Errorf("failed to scan config row: %v", err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+func (s *DatabaseHandler) DisableConfig(ctx context.Context, configID uuid.UUID, disablement models.Disablement) (_ bool, err error) {
+	ctx, end := s.startQuery(ctx, "DisableConfig")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE configs SET disabled_at = $2, disabled_reason = $3, disabled_by = $4
+		WHERE id = $1 AND disabled_at IS NULL
+	`
+	result, err := s.conn().ExecContext(ctx, query, configID, disablement.At.UTC(), disablement.Reason, disablement.ByColumn())
+	if err != nil {
+		return false, fmt.Errorf("failed to disable config: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to disable config: %v", err)
+	}
+
+	return updated == 1, nil
+}
+
+func (s *DatabaseHandler) EnableConfig(ctx context.Context, configID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "EnableConfig")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE configs SET disabled_at = NULL, disabled_reason = '', disabled_by = '' WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, configID); err != nil {
+		return fmt.Errorf("failed to enable config: %v", err)
+	}
+
+	return nil
+}
+
+// IsConfigServed reads the proxy_configs view, which holds the configs the
+// proxy serves right now.
+func (s *DatabaseHandler) IsConfigServed(ctx context.Context, configID uuid.UUID) (_ bool, err error) {
+	ctx, end := s.startQuery(ctx, "IsConfigServed")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT COUNT(*) FROM proxy_configs WHERE id = $1
+	`
+	var count int
+	if err := s.conn().QueryRowContext(ctx, query, configID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to query proxy configs: %v", err)
+	}
+
+	return count > 0, nil
+}
+
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func (s *DatabaseHandler) DeleteConfig(ctx context.Context, configID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteConfig")
+	defer func() { end(err) }()
+
 	query := `
 		DELETE FROM configs WHERE id=$1
 	`
-	_, err := s.DB.Exec(query, configID)
+	_, err = s.conn().ExecContext(ctx, query, configID)
 	if err != nil {
 		return fmt.Errorf("failed to delete config: %v", err)
 	}
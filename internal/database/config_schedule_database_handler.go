@@ -0,0 +1,63 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// SetConfigSchedule replaces the weekly windows of the config, none serves
+// it at every time.
+func (s *DatabaseHandler) SetConfigSchedule(ctx context.Context, configID uuid.UUID, windows []models.ScheduleWindow) (err error) {
+	ctx, end := s.startQuery(ctx, "SetConfigSchedule")
+	defer func() { end(err) }()
+
+	return s.Transaction(ctx, func(ctx context.Context, tx Store) error {
+		conn := tx.(*DatabaseHandler).conn()
+		if _, err := conn.ExecContext(ctx, `DELETE FROM config_schedules WHERE config_id = $1`, configID); err != nil {
+			return fmt.Errorf("failed to clear config schedule: %v", err)
+		}
+
+		query := `
+			INSERT INTO config_schedules (config_id, weekday, start_minute, end_minute)
+			VALUES ($1, $2, $3, $4)
+		`
+		for _, window := range windows {
+			if _, err := conn.ExecContext(ctx, query, configID, int(window.Weekday), window.StartMinute, window.EndMinute); err != nil {
+				return fmt.Errorf("failed to set config schedule: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// listConfigSchedulesWhere lists the windows of the configs matching the
+// condition by config, the configs table is aliased as c.
+func (s *DatabaseHandler) listConfigSchedulesWhere(ctx context.Context, condition string, args ...any) (map[uuid.UUID][]models.ScheduleWindow, error) {
+	query := `
+		SELECT cs.config_id, cs.weekday, cs.start_minute, cs.end_minute
+		FROM config_schedules cs
+		JOIN configs c ON c.id = cs.config_id
+		WHERE ` + condition + `
+		ORDER BY cs.weekday, cs.start_minute
+	`
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query config schedules: %v", err)
+	}
+	defer rows.Close()
+
+	schedules := make(map[uuid.UUID][]models.ScheduleWindow)
+	for rows.Next() {
+		var configID uuid.UUID
+		var window models.ScheduleWindow
+		if err := rows.Scan(&configID, &window.Weekday, &window.StartMinute, &window.EndMinute); err != nil {
+			return nil, fmt.Errorf("failed to scan config schedule row: %v", err)
+		}
+		schedules[configID] = append(schedules[configID], window)
+	}
+
+	return schedules, rows.Err()
+}
@@ -0,0 +1,29 @@
+package database_test
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/migrations"
+	"configuration-management/internal/database/storetest"
+	"testing"
+)
+
+func TestPostgresConformance(t *testing.T) {
+	store, err := database.Open(database.TestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.DB.Close()
+
+	migrator, err := migrations.New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	storetest.Run(t, func(t *testing.T) database.Store {
+		return store
+	})
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const credentialsColumns = "user_id, username, password_hash, totp_secret, totp_enabled, totp_failures, totp_failed_at, COALESCE(first_account, FALSE), created_at"
+
+func scanCredentials(row rowScanner, credentials *models.Credentials) error {
+	return row.Scan(&credentials.UserID, &credentials.Username, &credentials.PasswordHash,
+		&credentials.TOTPSecret, &credentials.TOTPEnabled, &credentials.TOTPFailures, &credentials.TOTPFailedAt,
+		&credentials.FirstAccount, &credentials.CreatedAt)
+}
+
+func (s *DatabaseHandler) CreateCredentials(ctx context.Context, credentials models.Credentials) (_ *models.Credentials, err error) {
+	ctx, end := s.startQuery(ctx, "CreateCredentials")
+	defer func() { end(err) }()
+
+	// first_account is NULL rather than FALSE on the other accounts, its
+	// unique index only allows one TRUE
+	var firstAccount *bool
+	if credentials.FirstAccount {
+		firstAccount = &credentials.FirstAccount
+	}
+	query := `
+		INSERT INTO local_credentials (user_id, username, password_hash, totp_secret, totp_enabled, first_account)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + credentialsColumns
+	row := s.conn().QueryRowContext(ctx, query, credentials.UserID, credentials.Username,
+		credentials.PasswordHash, credentials.TOTPSecret, credentials.TOTPEnabled, firstAccount)
+	if err := scanCredentials(row, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to create credentials: %v", err)
+	}
+
+	return &credentials, nil
+}
+
+func (s *DatabaseHandler) GetCredentials(ctx context.Context, username string) (_ *models.Credentials, err error) {
+	ctx, end := s.startQuery(ctx, "GetCredentials")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + credentialsColumns + ` FROM local_credentials WHERE username = $1
+	`
+	var credentials models.Credentials
+	if err := scanCredentials(s.conn().QueryRowContext(ctx, query, username), &credentials); err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", notFound(err))
+	}
+
+	return &credentials, nil
+}
+
+func (s *DatabaseHandler) GetUserCredentials(ctx context.Context, userID uuid.UUID) (_ *models.Credentials, err error) {
+	ctx, end := s.startQuery(ctx, "GetUserCredentials")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + credentialsColumns + ` FROM local_credentials WHERE user_id = $1
+	`
+	var credentials models.Credentials
+	if err := scanCredentials(s.conn().QueryRowContext(ctx, query, userID), &credentials); err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", notFound(err))
+	}
+
+	return &credentials, nil
+}
+
+func (s *DatabaseHandler) CountCredentials(ctx context.Context) (_ int, err error) {
+	ctx, end := s.startQuery(ctx, "CountCredentials")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT COUNT(*) FROM local_credentials
+	`
+	var count int
+	if err := s.conn().QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count credentials: %v", err)
+	}
+
+	return count, nil
+}
+
+func (s *DatabaseHandler) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdatePassword")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE local_credentials SET password_hash = $2 WHERE user_id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) UpdateTOTP(ctx context.Context, userID uuid.UUID, secret string, enabled bool) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateTOTP")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE local_credentials SET totp_secret = $2, totp_enabled = $3 WHERE user_id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, userID, secret, enabled); err != nil {
+		return fmt.Errorf("failed to update two-factor authentication: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (_ bool, err error) {
+	ctx, end := s.startQuery(ctx, "UseTOTPStep")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE local_credentials SET totp_last_step = $2, totp_failures = 0, totp_failed_at = NULL
+		WHERE user_id = $1 AND totp_last_step < $2
+	`
+	result, err := s.conn().ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %v", err)
+	}
+
+	return updated == 1, nil
+}
+
+func (s *DatabaseHandler) RecordTOTPFailure(ctx context.Context, userID uuid.UUID, failedAt time.Time) (_ int, err error) {
+	ctx, end := s.startQuery(ctx, "RecordTOTPFailure")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE local_credentials SET totp_failures = totp_failures + 1, totp_failed_at = $2
+		WHERE user_id = $1
+		RETURNING totp_failures
+	`
+	var failures int
+	if err := s.conn().QueryRowContext(ctx, query, userID, failedAt.UTC()).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to record totp failure: %w", notFound(err))
+	}
+
+	return failures, nil
+}
+
+const authTokenColumns = "id, kind, token_hash, user_id, email, created_by, expires_at, used_at, created_at"
+
+func scanAuthToken(row rowScanner, token *models.AuthToken) error {
+	return row.Scan(&token.ID, &token.Kind, &token.TokenHash, &token.UserID, &token.Email,
+		&token.CreatedBy, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+}
+
+func (s *DatabaseHandler) CreateAuthToken(ctx context.Context, token models.AuthToken) (_ *models.AuthToken, err error) {
+	ctx, end := s.startQuery(ctx, "CreateAuthToken")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO auth_tokens (kind, token_hash, user_id, email, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + authTokenColumns
+	row := s.conn().QueryRowContext(ctx, query, token.Kind, token.TokenHash, token.UserID,
+		token.Email, token.CreatedBy, token.ExpiresAt.UTC())
+	if err := scanAuthToken(row, &token); err != nil {
+		return nil, fmt.Errorf("failed to create auth token: %v", err)
+	}
+
+	return &token, nil
+}
+
+func (s *DatabaseHandler) GetAuthToken(ctx context.Context, kind string, tokenHash string) (_ *models.AuthToken, err error) {
+	ctx, end := s.startQuery(ctx, "GetAuthToken")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + authTokenColumns + ` FROM auth_tokens WHERE kind = $1 AND token_hash = $2
+	`
+	var token models.AuthToken
+	if err := scanAuthToken(s.conn().QueryRowContext(ctx, query, kind, tokenHash), &token); err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", notFound(err))
+	}
+
+	return &token, nil
+}
+
+func (s *DatabaseHandler) UseAuthToken(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) (_ bool, err error) {
+	ctx, end := s.startQuery(ctx, "UseAuthToken")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE auth_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL
+	`
+	result, err := s.conn().ExecContext(ctx, query, tokenID, usedAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to use auth token: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use auth token: %v", err)
+	}
+
+	return updated == 1, nil
+}
@@ -1,46 +1,170 @@
 package database
 
 import (
+	"configuration-management/internal/tracing"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"strconv"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/joho/godotenv/autoload"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 type DatabaseHandler struct {
 	DB *sql.DB
+	// Driver is either DriverPostgres or DriverSQLite.
+	Driver string
+	// QueryTimeout bounds every store query, zero disables the timeout.
+	QueryTimeout time.Duration
+
+	tx *sql.Tx
+	// name is the database name or file, for logging.
+	name string
 }
 
-var (
-	database   = os.Getenv("DB_DATABASE")
-	password   = os.Getenv("DB_PASSWORD")
-	username   = os.Getenv("DB_USERNAME")
-	port       = os.Getenv("DB_PORT")
-	host       = os.Getenv("DB_HOST")
-	schema     = os.Getenv("DB_SCHEMA")
-	dbInstance *DatabaseHandler
-)
+// Config selects and locates the database, it is loaded with the rest of
+// the application configuration.
+type Config struct {
+	// Driver is DriverPostgres unless set to DriverSQLite.
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Database string `yaml:"database"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Schema   string `yaml:"schema"`
+	// Path is the database file used by the sqlite driver.
+	Path         string        `yaml:"path"`
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+}
+
+const DefaultQueryTimeout = 5 * time.Second
+
+// Validate reports the settings missing for the selected driver.
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Driver {
+	case DriverPostgres:
+		for _, setting := range []struct{ name, value string }{
+			{"DB_HOST", c.Host}, {"DB_PORT", c.Port}, {"DB_DATABASE", c.Database}, {"DB_USERNAME", c.Username},
+		} {
+			if setting.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required for the postgres driver", setting.name))
+			}
+		}
+	case DriverSQLite:
+		if c.Path == "" {
+			errs = append(errs, fmt.Errorf("DB_PATH is required for the sqlite driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be %q or %q, got %q", DriverPostgres, DriverSQLite, c.Driver))
+	}
+	if c.QueryTimeout < 0 {
+		errs = append(errs, fmt.Errorf("DB_QUERY_TIMEOUT must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
 
-func New() *DatabaseHandler {
-	// Reuse Connection
-	if dbInstance != nil {
-		return dbInstance
+// Open connects to the configured database.
+func Open(config Config) (*DatabaseHandler, error) {
+	var (
+		handler *DatabaseHandler
+		err     error
+	)
+	switch config.Driver {
+	case DriverSQLite:
+		handler, err = NewSQLite(config.Path)
+	default:
+		handler, err = NewPostgres(config.PostgresDSN())
+		if err == nil {
+			handler.name = config.Database
+		}
 	}
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	handler.QueryTimeout = config.QueryTimeout
+	return handler, nil
+}
+
+// PostgresDSN returns the connection string of the postgres database.
+func (c Config) PostgresDSN() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s",
+		c.Username, c.Password, c.Host, c.Port, c.Database, c.Schema)
+}
+
+// NewPostgres opens a Postgres backed store for the given connection string.
+func NewPostgres(connStr string) (*DatabaseHandler, error) {
 	db, err := sql.Open("pgx", connStr)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to open postgres database: %v", err)
 	}
-	dbInstance = &DatabaseHandler{
-		DB: db,
+
+	return &DatabaseHandler{
+		DB:           db,
+		Driver:       DriverPostgres,
+		QueryTimeout: DefaultQueryTimeout,
+	}, nil
+}
+
+// NewSQLite opens a SQLite backed store for the database file at path.
+func NewSQLite(path string) (*DatabaseHandler, error) {
+	if path == "" {
+		return nil, fmt.Errorf("DB_PATH is required for the sqlite driver")
+	}
+	db, err := sql.Open("sqlite", SQLiteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+	// SQLite allows a single writer, serialising access through one
+	// connection avoids SQLITE_BUSY errors between concurrent transactions.
+	db.SetMaxOpenConns(1)
+
+	return &DatabaseHandler{
+		DB:           db,
+		Driver:       DriverSQLite,
+		QueryTimeout: DefaultQueryTimeout,
+		name:         path,
+	}, nil
+}
+
+// SQLiteDSN returns the connection string for the database file at path,
+// with foreign keys enforced and times stored in a sortable text format.
+func SQLiteDSN(path string) string {
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+}
+
+// startQuery bounds the store operation by the query timeout and traces it
+// as a span named after the operation. The statements and their arguments
+// are left out of the span, they may contain secrets. end records the error
+// the operation returns, if any, on the span.
+func (s *DatabaseHandler) startQuery(ctx context.Context, operation string) (context.Context, func(err error)) {
+	system := semconv.DBSystemPostgreSQL
+	if s.Driver == DriverSQLite {
+		system = semconv.DBSystemSqlite
+	}
+	ctx, span := tracing.Start(ctx, "store."+operation, system, semconv.DBOperationName(operation))
+	if s.QueryTimeout <= 0 {
+		return ctx, func(err error) { tracing.End(span, err) }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+	return ctx, func(err error) {
+		cancel()
+		tracing.End(span, err)
 	}
-	return dbInstance
 }
 
 func (s *DatabaseHandler) Health() map[string]string {
@@ -53,7 +177,7 @@ func (s *DatabaseHandler) Health() map[string]string {
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err) // Log the error and terminate the program
+		slog.Error("database is down", "error", err)
 		return stats
 	}
 
@@ -89,7 +213,17 @@ func (s *DatabaseHandler) Health() map[string]string {
 	return stats
 }
 
+func (s *DatabaseHandler) Ping(ctx context.Context) (err error) {
+	ctx, end := s.startQuery(ctx, "Ping")
+	defer func() { end(err) }()
+
+	if err := s.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach the database: %v", err)
+	}
+	return nil
+}
+
 func (s *DatabaseHandler) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	slog.Info("disconnected from database", "database", s.name)
 	return s.DB.Close()
 }
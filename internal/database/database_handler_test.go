@@ -11,6 +11,9 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// TestConfig points at the postgres container started for the tests.
+var TestConfig = Config{Driver: DriverPostgres, QueryTimeout: DefaultQueryTimeout}
+
 func mustStartPostgresContainer() (func(context.Context) error, error) {
 	var (
 		dbName = "database"
@@ -33,9 +36,9 @@ func mustStartPostgresContainer() (func(context.Context) error, error) {
 		return nil, err
 	}
 
-	database = dbName
-	password = dbPwd
-	username = dbUser
+	TestConfig.Database = dbName
+	TestConfig.Password = dbPwd
+	TestConfig.Username = dbUser
 
 	dbHost, err := dbContainer.Host(context.Background())
 	if err != nil {
@@ -47,8 +50,8 @@ func mustStartPostgresContainer() (func(context.Context) error, error) {
 		return dbContainer.Terminate, err
 	}
 
-	host = dbHost
-	port = dbPort.Port()
+	TestConfig.Host = dbHost
+	TestConfig.Port = dbPort.Port()
 
 	return dbContainer.Terminate, err
 }
@@ -66,15 +69,20 @@ func TestMain(m *testing.M) {
 	}
 }
 
-func TestNew(t *testing.T) {
-	srv := New()
-	if srv == nil {
-		t.Fatal("New() returned nil")
+func TestOpen(t *testing.T) {
+	srv, err := Open(TestConfig)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
 	}
+	defer srv.Close()
 }
 
 func TestHealth(t *testing.T) {
-	srv := New()
+	srv, err := Open(TestConfig)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	defer srv.Close()
 
 	stats := srv.Health()
 
@@ -92,7 +100,10 @@ func TestHealth(t *testing.T) {
 }
 
 func TestClose(t *testing.T) {
-	srv := New()
+	srv, err := Open(TestConfig)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
 
 	if srv.Close() != nil {
 		t.Fatalf("expected Close() to return nil")
@@ -2,18 +2,36 @@ package database
 
 import (
 	"configuration-management/internal/models"
+	"context"
 	"fmt"
 
 	"github.com/google/uuid"
 )
 
-func (s *DatabaseHandler) ListHeaderReplacements(configID uuid.UUID) ([]models.HeaderReplacement, error) {
+func (s *DatabaseHandler) ListHeaderReplacements(ctx context.Context, configID uuid.UUID) (_ []models.HeaderReplacement, err error) {
+	ctx, end := s.startQuery(ctx, "ListHeaderReplacements")
+	defer func() { end(err) }()
+
+	return s.listHeaderReplacementsWhere(ctx, "c.id = $1", configID)
+}
+
+func (s *DatabaseHandler) ListAllHeaderReplacements(ctx context.Context) (_ []models.HeaderReplacement, err error) {
+	ctx, end := s.startQuery(ctx, "ListAllHeaderReplacements")
+	defer func() { end(err) }()
+
+	return s.listHeaderReplacementsWhere(ctx, "1 = 1")
+}
+
+// listHeaderReplacementsWhere lists the header replacements of the configs
+// matching the condition, the configs table is aliased as c.
+func (s *DatabaseHandler) listHeaderReplacementsWhere(ctx context.Context, condition string, args ...any) ([]models.HeaderReplacement, error) {
 	query := `
-		SELECT id, config_id, header_name, header_value
-		FROM header_replacements
-		WHERE config_id = $1
-	`
-	rows, err := s.DB.Query(query, configID)
+		SELECT h.id, h.config_id, h.header_name, h.header_value
+		FROM header_replacements h
+		JOIN configs c ON c.id = h.config_id
+		WHERE ` + condition
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query header replacements: %v", err)
 	}
@@ -31,32 +49,50 @@ func (s *DatabaseHandler) ListHeaderReplacements(configID uuid.UUID) ([]models.H
 		replacements = append(replacements, replacement)
 	}
 
-	return replacements, nil
+	return replacements, rows.Err()
 }
 
-func (s *DatabaseHandler) GetHeaderReplacement(headerID uuid.UUID) (*models.HeaderReplacement, error) {
+func (s *DatabaseHandler) GetHeaderReplacement(ctx context.Context, headerID uuid.UUID) (_ *models.HeaderReplacement, err error) {
+	ctx, end := s.startQuery(ctx, "GetHeaderReplacement")
+	defer func() { end(err) }()
+
 	query := `
 		SELECT id, config_id, header_name, header_value
 		FROM header_replacements
 		WHERE id = $1
 	`
 	var replacement models.HeaderReplacement
-	if err := s.DB.QueryRow(query, headerID).Scan(
+	if err := s.conn().QueryRowContext(ctx, query, headerID).Scan(
 		&replacement.ID, &replacement.ConfigID, &replacement.HeaderName, &replacement.HeaderValue); err != nil {
-		return nil, fmt.Errorf("failed to get header replacement: %v", err)
+		return nil, fmt.Errorf("failed to get header replacement: %w", notFound(err))
 	}
 
 	return &replacement, nil
 }
 
-func (s *DatabaseHandler) CreateHeaderReplacement(configID uuid.UUID, name string, value string) (*models.HeaderReplacement, error) {
+func (s *DatabaseHandler) SampleHeaderValue(ctx context.Context) (_ string, err error) {
+	ctx, end := s.startQuery(ctx, "SampleHeaderValue")
+	defer func() { end(err) }()
+
+	var value string
+	if err := s.conn().QueryRowContext(ctx, `SELECT header_value FROM header_replacements LIMIT 1`).Scan(&value); err != nil {
+		return "", fmt.Errorf("failed to sample header value: %w", notFound(err))
+	}
+
+	return value, nil
+}
+
+func (s *DatabaseHandler) CreateHeaderReplacement(ctx context.Context, configID uuid.UUID, name string, value string) (_ *models.HeaderReplacement, err error) {
+	ctx, end := s.startQuery(ctx, "CreateHeaderReplacement")
+	defer func() { end(err) }()
+
 	query := `
 		INSERT INTO header_replacements (config_id, header_name, header_value)
 		VALUES ($1, $2, $3)
 		RETURNING id, config_id, header_name, header_value
 	`
 	var replacement models.HeaderReplacement
-	if err := s.DB.QueryRow(query, configID, name, value).Scan(
+	if err := s.conn().QueryRowContext(ctx, query, configID, name, value).Scan(
 		&replacement.ID, &replacement.ConfigID, &replacement.HeaderName, &replacement.HeaderValue,
 	); err != nil {
 		return nil, fmt.Errorf("failed to create header replacement: %v", err)
@@ -65,11 +101,28 @@ func (s *DatabaseHandler) CreateHeaderReplacement(configID uuid.UUID, name strin
 	return &replacement, nil
 }
 
-func (s *DatabaseHandler) DeleteHeaderReplacement(headerID uuid.UUID) error {
+func (s *DatabaseHandler) UpdateHeaderReplacementValue(ctx context.Context, headerID uuid.UUID, value string) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateHeaderReplacementValue")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE header_replacements SET header_value = $1 WHERE id = $2
+	`
+	if _, err := s.conn().ExecContext(ctx, query, value, headerID); err != nil {
+		return fmt.Errorf("failed to update header value: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteHeaderReplacement(ctx context.Context, headerID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteHeaderReplacement")
+	defer func() { end(err) }()
+
 	query := `
 		DELETE FROM header_replacements WHERE id=$1
 	`
-	_, err := s.DB.Exec(query, headerID)
+	_, err = s.conn().ExecContext(ctx, query, headerID)
 	if err != nil {
 		return fmt.Errorf("failed to delete header: %v", err)
 	}
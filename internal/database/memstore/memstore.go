@@ -0,0 +1,1221 @@
+// Package memstore provides an in-memory implementation of database.Store
+// for unit tests. It keeps the same relations and cascading deletes as the
+// Postgres schema.
+package memstore
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type usageRecord struct {
+	configID  uuid.UUID
+	rejected  bool
+	createdAt time.Time
+}
+
+type Store struct {
+	// mu is held for writing for the whole of a transaction.
+	mu sync.RWMutex
+
+	users       []models.User
+	sessions    []models.Session
+	credentials []models.Credentials
+	authTokens  []models.AuthToken
+	apiTokens   []models.APIToken
+	clients     []models.ConfigClient
+	projects    []models.Project
+	configs     []models.Config
+	headers     []models.HeaderReplacement
+	alerts      []models.Alert
+	usage       []usageRecord
+	webhooks    []models.Webhook
+	deliveries  []models.WebhookDelivery
+
+	// projectSeq orders projects by creation, newest first
+	projectSeq map[uuid.UUID]int
+	nextSeq    int
+	// totpSteps holds the time step of the last accepted code of each user
+	totpSteps map[uuid.UUID]int64
+}
+
+var _ database.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{projectSeq: make(map[uuid.UUID]int), totpSteps: make(map[uuid.UUID]int64)}
+}
+
+// clone copies the state of the store, the caller holds the lock.
+func (s *Store) clone() *Store {
+	return &Store{
+		users:       slices.Clone(s.users),
+		sessions:    slices.Clone(s.sessions),
+		credentials: slices.Clone(s.credentials),
+		authTokens:  slices.Clone(s.authTokens),
+		apiTokens:   slices.Clone(s.apiTokens),
+		clients:     slices.Clone(s.clients),
+		projects:    slices.Clone(s.projects),
+		configs:     slices.Clone(s.configs),
+		headers:     slices.Clone(s.headers),
+		alerts:      slices.Clone(s.alerts),
+		usage:       slices.Clone(s.usage),
+		webhooks:    slices.Clone(s.webhooks),
+		deliveries:  slices.Clone(s.deliveries),
+		projectSeq:  maps.Clone(s.projectSeq),
+		nextSeq:     s.nextSeq,
+		totpSteps:   maps.Clone(s.totpSteps),
+	}
+}
+
+// commit takes the state of the transaction tx, the caller holds the lock.
+func (s *Store) commit(tx *Store) {
+	s.users = tx.users
+	s.sessions = tx.sessions
+	s.credentials = tx.credentials
+	s.authTokens = tx.authTokens
+	s.apiTokens = tx.apiTokens
+	s.clients = tx.clients
+	s.projects = tx.projects
+	s.configs = tx.configs
+	s.headers = tx.headers
+	s.alerts = tx.alerts
+	s.usage = tx.usage
+	s.webhooks = tx.webhooks
+	s.deliveries = tx.deliveries
+	s.projectSeq = tx.projectSeq
+	s.nextSeq = tx.nextSeq
+	s.totpSteps = tx.totpSteps
+}
+
+// transactionKey marks the context passed to fn of Transaction with the
+// store of the transaction.
+type transactionKey struct{}
+
+// lock and rLock panic with database.ErrOutsideTransaction when fn of a
+// transaction uses the store instead of the one it receives, the store stays
+// locked until fn returns.
+func (s *Store) lock(ctx context.Context) {
+	s.checkOutsideTransaction(ctx)
+	s.mu.Lock()
+}
+
+func (s *Store) rLock(ctx context.Context) {
+	s.checkOutsideTransaction(ctx)
+	s.mu.RLock()
+}
+
+func (s *Store) checkOutsideTransaction(ctx context.Context) {
+	if open, _ := ctx.Value(transactionKey{}).(*Store); open == s {
+		panic(database.ErrOutsideTransaction)
+	}
+}
+
+// Transaction runs fn on a copy of the store, which replaces the store when
+// fn succeeds. The store is locked until fn returns, concurrent operations
+// wait for the transaction like on a serializable database, and a failed
+// transaction discards its own changes only.
+func (s *Store) Transaction(ctx context.Context, fn func(ctx context.Context, tx database.Store) error) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(context.WithValue(ctx, transactionKey{}, s), txStore{tx}); err != nil {
+		return err
+	}
+	s.commit(tx)
+
+	return nil
+}
+
+// txStore runs nested transactions in the enclosing one.
+type txStore struct {
+	*Store
+}
+
+func (t txStore) Transaction(ctx context.Context, fn func(ctx context.Context, tx database.Store) error) error {
+	return fn(ctx, t)
+}
+
+func (s *Store) Health() map[string]string {
+	return map[string]string{"status": "up", "message": "It's healthy"}
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Close() error {
+	return nil
+}
+
+// RecordUsage stores a proxied request the way the proxy records it in the
+// config_usage table.
+func (s *Store) RecordUsage(configID uuid.UUID, rejected bool, createdAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = append(s.usage, usageRecord{configID, rejected, createdAt})
+}
+
+func notFound(kind string, id uuid.UUID) error {
+	return fmt.Errorf("failed to get %s %s: %w", kind, id, database.ErrNotFound)
+}
+
+func (s *Store) ListProjects(ctx context.Context, userID uuid.UUID, options database.ProjectListOptions) ([]models.Project, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	projects := s.userProjects(userID)
+	if options.Offset >= len(projects) {
+		return nil, nil
+	}
+	projects = projects[options.Offset:]
+	if options.Limit > 0 && options.Limit < len(projects) {
+		projects = projects[:options.Limit]
+	}
+
+	for i := range projects {
+		if options.WithConfigs {
+			projects[i].Configs = s.listConfigs(projects[i].ID)
+		}
+		projects[i].Webhooks = s.listWebhooks(projects[i].ID)
+	}
+
+	return projects, nil
+}
+
+func (s *Store) userProjects(userID uuid.UUID) []models.Project {
+	projects := filter(s.projects, func(p models.Project) bool { return p.UserID == userID })
+	sort.SliceStable(projects, func(i, j int) bool {
+		return s.projectSeq[projects[i].ID] > s.projectSeq[projects[j].ID]
+	})
+
+	return projects
+}
+
+func (s *Store) CountProjects(ctx context.Context, userID uuid.UUID) (int, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return len(s.userProjects(userID)), nil
+}
+
+func (s *Store) CountProjectsByUser(ctx context.Context) (map[uuid.UUID]int, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	counts := make(map[uuid.UUID]int)
+	for _, project := range s.projects {
+		counts[project.UserID]++
+	}
+	return counts, nil
+}
+
+func (s *Store) CountUserConfigs(ctx context.Context, userID uuid.UUID) (int, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, project := range s.userProjects(userID) {
+		count += len(s.listConfigs(project.ID))
+	}
+
+	return count, nil
+}
+
+func (s *Store) GetProject(ctx context.Context, projectID uuid.UUID) (*models.Project, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, project := range s.projects {
+		if project.ID == projectID {
+			return &project, nil
+		}
+	}
+
+	return nil, notFound("project", projectID)
+}
+
+func (s *Store) CreateProject(ctx context.Context, name string, description string, accessKey string, userID uuid.UUID) (*models.Project, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	project := models.Project{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		AccessKey:   accessKey,
+		UserID:      userID,
+	}
+	s.projects = append(s.projects, project)
+	s.nextSeq++
+	s.projectSeq[project.ID] = s.nextSeq
+
+	return &project, nil
+}
+
+func (s *Store) UpdateProjectAccessKey(ctx context.Context, projectID uuid.UUID, accessKey string) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.projects {
+		if s.projects[i].ID == projectID {
+			s.projects[i].AccessKey = accessKey
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateProjectOwner(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.projects {
+		if s.projects[i].ID == projectID {
+			s.projects[i].UserID = userID
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DisableProject(ctx context.Context, projectID uuid.UUID, disablement models.Disablement) (bool, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.projects {
+		if s.projects[i].ID == projectID && s.projects[i].Disabled == nil {
+			s.projects[i].Disabled = &disablement
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *Store) EnableProject(ctx context.Context, projectID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.projects {
+		if s.projects[i].ID == projectID {
+			s.projects[i].Disabled = nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateProjectDescription(ctx context.Context, projectID uuid.UUID, description string) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.projects {
+		if s.projects[i].ID == projectID {
+			s.projects[i].Description = description
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteProject(ctx context.Context, projectID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.projects = filter(s.projects, func(p models.Project) bool { return p.ID != projectID })
+	delete(s.projectSeq, projectID)
+	for _, config := range s.configs {
+		if config.ProjectID == projectID {
+			s.deleteConfig(config.ID)
+		}
+	}
+	for _, webhook := range s.webhooks {
+		if webhook.ProjectID == projectID {
+			s.deleteWebhook(webhook.ID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) GetConfig(ctx context.Context, configID uuid.UUID) (*models.Config, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, config := range s.configs {
+		if config.ID == configID {
+			return &config, nil
+		}
+	}
+
+	return nil, notFound("config", configID)
+}
+
+func (s *Store) ListConfigs(ctx context.Context, projectID uuid.UUID) ([]models.Config, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return s.listConfigs(projectID), nil
+}
+
+func (s *Store) listConfigs(projectID uuid.UUID) []models.Config {
+	var configs []models.Config
+	for _, config := range s.configs {
+		if config.ProjectID == projectID {
+			config.HeaderReplacements = s.listHeaders(config.ID)
+			config.Alerts = s.listAlerts(config.ID)
+			config.Clients = s.listConfigClients(config.ID)
+			configs = append(configs, config)
+		}
+	}
+
+	return configs
+}
+
+func (s *Store) CreateConfig(ctx context.Context, projectID uuid.UUID, name string, numberOfRequests int, per string) (*models.Config, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	config := models.Config{
+		ID:                    uuid.New(),
+		ProjectID:             projectID,
+		Name:                  name,
+		LimitNumberOfRequests: numberOfRequests,
+		LimitPer:              per,
+	}
+	s.configs = append(s.configs, config)
+
+	return &config, nil
+}
+
+func (s *Store) UpdateConfigLimit(ctx context.Context, configID uuid.UUID, numberOfRequests int, per string) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.configs {
+		if s.configs[i].ID == configID {
+			s.configs[i].LimitNumberOfRequests = numberOfRequests
+			s.configs[i].LimitPer = per
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateConfigAvailability(ctx context.Context, configID uuid.UUID, activeFrom *time.Time, expiresAt *time.Time) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.configs {
+		if s.configs[i].ID == configID {
+			s.configs[i].ActiveFrom = activeFrom
+			s.configs[i].ExpiresAt = expiresAt
+			if disabled := s.configs[i].Disabled; disabled != nil && disabled.Reason == models.DisabledReasonExpired {
+				s.configs[i].Disabled = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetConfigSchedule keeps the windows on the config, sorted as the database
+// lists them.
+func (s *Store) SetConfigSchedule(ctx context.Context, configID uuid.UUID, windows []models.ScheduleWindow) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	schedule := slices.Clone(windows)
+	slices.SortFunc(schedule, func(a, b models.ScheduleWindow) int {
+		if a.Weekday != b.Weekday {
+			return int(a.Weekday) - int(b.Weekday)
+		}
+		return a.StartMinute - b.StartMinute
+	})
+	if len(schedule) == 0 {
+		schedule = nil
+	}
+	for i := range s.configs {
+		if s.configs[i].ID == configID {
+			s.configs[i].Schedule = schedule
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) ListExpiredConfigs(ctx context.Context, now time.Time) ([]models.Config, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return filter(s.configs, func(c models.Config) bool {
+		return c.ExpiresAt != nil && !c.ExpiresAt.After(now) && c.Disabled == nil
+	}), nil
+}
+
+func (s *Store) DisableConfig(ctx context.Context, configID uuid.UUID, disablement models.Disablement) (bool, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.configs {
+		if s.configs[i].ID == configID && s.configs[i].Disabled == nil {
+			s.configs[i].Disabled = &disablement
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *Store) EnableConfig(ctx context.Context, configID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.configs {
+		if s.configs[i].ID == configID {
+			s.configs[i].Disabled = nil
+		}
+	}
+
+	return nil
+}
+
+// IsConfigServed evaluates the status of the config and of its project, the
+// database reads it from the proxy_configs view instead.
+func (s *Store) IsConfigServed(ctx context.Context, configID uuid.UUID) (bool, error) {
+	config, err := s.GetConfig(ctx, configID)
+	if err != nil {
+		return false, nil
+	}
+	if project, err := s.GetProject(ctx, config.ProjectID); err != nil || project.Disabled != nil {
+		return false, nil
+	}
+
+	return config.Status(time.Now()) == models.ConfigStatusActive, nil
+}
+
+func (s *Store) DeleteConfig(ctx context.Context, configID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.deleteConfig(configID)
+	return nil
+}
+
+func (s *Store) deleteConfig(configID uuid.UUID) {
+	s.configs = filter(s.configs, func(c models.Config) bool { return c.ID != configID })
+	s.headers = filter(s.headers, func(h models.HeaderReplacement) bool { return h.ConfigID != configID })
+	s.alerts = filter(s.alerts, func(a models.Alert) bool { return a.ConfigID != configID })
+	s.clients = filter(s.clients, func(c models.ConfigClient) bool { return c.ConfigID != configID })
+	s.usage = filter(s.usage, func(u usageRecord) bool { return u.configID != configID })
+}
+
+func (s *Store) ListHeaderReplacements(ctx context.Context, configID uuid.UUID) ([]models.HeaderReplacement, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return s.listHeaders(configID), nil
+}
+
+func (s *Store) ListAllHeaderReplacements(ctx context.Context) ([]models.HeaderReplacement, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.headers), nil
+}
+
+func (s *Store) listHeaders(configID uuid.UUID) []models.HeaderReplacement {
+	return filter(s.headers, func(h models.HeaderReplacement) bool { return h.ConfigID == configID })
+}
+
+func (s *Store) GetHeaderReplacement(ctx context.Context, headerID uuid.UUID) (*models.HeaderReplacement, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, header := range s.headers {
+		if header.ID == headerID {
+			return &header, nil
+		}
+	}
+
+	return nil, notFound("header replacement", headerID)
+}
+
+func (s *Store) SampleHeaderValue(ctx context.Context) (string, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	if len(s.headers) == 0 {
+		return "", fmt.Errorf("failed to sample header value: %w", database.ErrNotFound)
+	}
+	return s.headers[0].HeaderValue, nil
+}
+
+func (s *Store) CreateHeaderReplacement(ctx context.Context, configID uuid.UUID, name string, value string) (*models.HeaderReplacement, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	header := models.HeaderReplacement{
+		ID:          uuid.New(),
+		ConfigID:    configID,
+		HeaderName:  name,
+		HeaderValue: value,
+	}
+	s.headers = append(s.headers, header)
+
+	return &header, nil
+}
+
+func (s *Store) UpdateHeaderReplacementValue(ctx context.Context, headerID uuid.UUID, value string) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.headers {
+		if s.headers[i].ID == headerID {
+			s.headers[i].HeaderValue = value
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteHeaderReplacement(ctx context.Context, headerID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.headers = filter(s.headers, func(h models.HeaderReplacement) bool { return h.ID != headerID })
+	return nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	if existing := s.userBySubject(user.Provider, user.Subject); existing != nil {
+		return existing, nil
+	}
+	user.ID = uuid.New()
+	s.users = append(s.users, user)
+
+	return &user, nil
+}
+
+func (s *Store) GetUserBySubject(ctx context.Context, provider string, subject string) (*models.User, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	if user := s.userBySubject(provider, subject); user != nil {
+		return user, nil
+	}
+
+	return nil, fmt.Errorf("failed to get user: %w", database.ErrNotFound)
+}
+
+func (s *Store) userBySubject(provider string, subject string) *models.User {
+	for _, user := range s.users {
+		if user.Provider == provider && user.Subject == subject {
+			return &user
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.ID == userID {
+			return &user, nil
+		}
+	}
+
+	return nil, notFound("user", userID)
+}
+
+func (s *Store) ListUsers(ctx context.Context) ([]models.User, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	users := slices.Clone(s.users)
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Provider != users[j].Provider {
+			return users[i].Provider < users[j].Provider
+		}
+		return users[i].Subject < users[j].Subject
+	})
+	return users, nil
+}
+
+func (s *Store) CreateUserSession(ctx context.Context, userID uuid.UUID, userAgent string,
+	ipAddress string, expiresAt time.Time) (*models.Session, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		CreatedAt:  now,
+		ExpiresAt:  expiresAt.UTC(),
+		LastSeenAt: now,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+	}
+	s.sessions = append(s.sessions, session)
+
+	return &session, nil
+}
+
+func (s *Store) GetUserSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.ID == sessionID {
+			return &session, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	sessions := filter(s.sessions, func(session models.Session) bool { return session.UserID == userID })
+	slices.SortStableFunc(sessions, func(a, b models.Session) int { return b.LastSeenAt.Compare(a.LastSeenAt) })
+	return sessions, nil
+}
+
+func (s *Store) TouchUserSession(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.sessions {
+		if s.sessions[i].ID == sessionID {
+			s.sessions[i].LastSeenAt = lastSeenAt.UTC()
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteUserSession(ctx context.Context, sessionID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.sessions = filter(s.sessions, func(session models.Session) bool { return session.ID != sessionID })
+	return nil
+}
+
+func (s *Store) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.sessions = filter(s.sessions, func(session models.Session) bool { return session.UserID != userID })
+	return nil
+}
+
+func (s *Store) DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time, idleSince time.Time) (int, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	count := len(s.sessions)
+	s.sessions = filter(s.sessions, func(session models.Session) bool {
+		return session.ExpiresAt.After(expiredBefore) && !session.LastSeenAt.Before(idleSince)
+	})
+	return count - len(s.sessions), nil
+}
+
+func (s *Store) CreateCredentials(ctx context.Context, credentials models.Credentials) (*models.Credentials, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for _, existing := range s.credentials {
+		if existing.UserID == credentials.UserID || existing.Username == credentials.Username {
+			return nil, fmt.Errorf("failed to create credentials: duplicate user or username")
+		}
+		if existing.FirstAccount && credentials.FirstAccount {
+			return nil, fmt.Errorf("failed to create credentials: duplicate first account")
+		}
+	}
+	credentials.CreatedAt = time.Now().UTC()
+	s.credentials = append(s.credentials, credentials)
+
+	return &credentials, nil
+}
+
+func (s *Store) GetCredentials(ctx context.Context, username string) (*models.Credentials, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, credentials := range s.credentials {
+		if credentials.Username == username {
+			return &credentials, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to get credentials: %w", database.ErrNotFound)
+}
+
+func (s *Store) GetUserCredentials(ctx context.Context, userID uuid.UUID) (*models.Credentials, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, credentials := range s.credentials {
+		if credentials.UserID == userID {
+			return &credentials, nil
+		}
+	}
+
+	return nil, notFound("credentials", userID)
+}
+
+func (s *Store) CountResources(ctx context.Context) (*models.ResourceCounts, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return &models.ResourceCounts{
+		Users:              len(s.users),
+		Projects:           len(s.projects),
+		Configs:            len(s.configs),
+		HeaderReplacements: len(s.headers),
+	}, nil
+}
+
+func (s *Store) CountCredentials(ctx context.Context) (int, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return len(s.credentials), nil
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.credentials {
+		if s.credentials[i].UserID == userID {
+			s.credentials[i].PasswordHash = passwordHash
+		}
+	}
+	return nil
+}
+
+func (s *Store) UpdateTOTP(ctx context.Context, userID uuid.UUID, secret string, enabled bool) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.credentials {
+		if s.credentials[i].UserID == userID {
+			s.credentials[i].TOTPSecret = secret
+			s.credentials[i].TOTPEnabled = enabled
+		}
+	}
+	return nil
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	if s.totpSteps[userID] >= step {
+		return false, nil
+	}
+	s.totpSteps[userID] = step
+	for i := range s.credentials {
+		if s.credentials[i].UserID == userID {
+			s.credentials[i].TOTPFailures = 0
+			s.credentials[i].TOTPFailedAt = nil
+		}
+	}
+	return true, nil
+}
+
+func (s *Store) RecordTOTPFailure(ctx context.Context, userID uuid.UUID, failedAt time.Time) (int, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.credentials {
+		if s.credentials[i].UserID == userID {
+			failedAt = failedAt.UTC()
+			s.credentials[i].TOTPFailures++
+			s.credentials[i].TOTPFailedAt = &failedAt
+			return s.credentials[i].TOTPFailures, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to record totp failure: %w", notFound("credentials", userID))
+}
+
+func (s *Store) CreateAuthToken(ctx context.Context, token models.AuthToken) (*models.AuthToken, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for _, existing := range s.authTokens {
+		if existing.TokenHash == token.TokenHash {
+			return nil, fmt.Errorf("failed to create auth token: duplicate token")
+		}
+	}
+	token.ID = uuid.New()
+	token.ExpiresAt = token.ExpiresAt.UTC()
+	token.UsedAt = nil
+	token.CreatedAt = time.Now().UTC()
+	s.authTokens = append(s.authTokens, token)
+
+	return &token, nil
+}
+
+func (s *Store) GetAuthToken(ctx context.Context, kind string, tokenHash string) (*models.AuthToken, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, token := range s.authTokens {
+		if token.Kind == kind && token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to get auth token: %w", database.ErrNotFound)
+}
+
+func (s *Store) UseAuthToken(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) (bool, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.authTokens {
+		if s.authTokens[i].ID == tokenID && s.authTokens[i].UsedAt == nil {
+			usedAt := usedAt.UTC()
+			s.authTokens[i].UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) CreateAPIToken(ctx context.Context, userID uuid.UUID, name string, tokenHash string) (*models.APIToken, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for _, existing := range s.apiTokens {
+		if existing.TokenHash == tokenHash {
+			return nil, fmt.Errorf("failed to create api token: duplicate token")
+		}
+	}
+	token := models.APIToken{ID: uuid.New(), UserID: userID, Name: name, TokenHash: tokenHash, CreatedAt: time.Now().UTC()}
+	s.apiTokens = append(s.apiTokens, token)
+
+	return &token, nil
+}
+
+func (s *Store) GetAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, token := range s.apiTokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to get api token: %w", database.ErrNotFound)
+}
+
+func (s *Store) ListAPITokens(ctx context.Context, userID uuid.UUID) ([]models.APIToken, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return filter(s.apiTokens, func(token models.APIToken) bool { return token.UserID == userID }), nil
+}
+
+func (s *Store) TouchAPIToken(ctx context.Context, tokenID uuid.UUID, lastUsedAt time.Time) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.apiTokens {
+		if s.apiTokens[i].ID == tokenID {
+			lastUsedAt := lastUsedAt.UTC()
+			s.apiTokens[i].LastUsedAt = &lastUsedAt
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteAPIToken(ctx context.Context, userID uuid.UUID, tokenID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	count := len(s.apiTokens)
+	s.apiTokens = filter(s.apiTokens, func(token models.APIToken) bool {
+		return token.ID != tokenID || token.UserID != userID
+	})
+	if len(s.apiTokens) == count {
+		return fmt.Errorf("failed to delete api token: %w", database.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) CreateConfigClient(ctx context.Context, client models.ConfigClient) (*models.ConfigClient, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for _, existing := range s.clients {
+		if existing.SecretHash == client.SecretHash {
+			return nil, fmt.Errorf("failed to create config client: duplicate secret")
+		}
+	}
+	client.ID = uuid.New()
+	client.LastUsedAt = nil
+	client.CreatedAt = time.Now().UTC()
+	if client.ExpiresAt != nil {
+		expiresAt := client.ExpiresAt.UTC()
+		client.ExpiresAt = &expiresAt
+	}
+	s.clients = append(s.clients, client)
+
+	return &client, nil
+}
+
+func (s *Store) ListConfigClients(ctx context.Context, configID uuid.UUID) ([]models.ConfigClient, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return s.listConfigClients(configID), nil
+}
+
+func (s *Store) listConfigClients(configID uuid.UUID) []models.ConfigClient {
+	return filter(s.clients, func(c models.ConfigClient) bool { return c.ConfigID == configID })
+}
+
+func (s *Store) DeleteConfigClient(ctx context.Context, configID uuid.UUID, clientID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	count := len(s.clients)
+	s.clients = filter(s.clients, func(c models.ConfigClient) bool {
+		return c.ID != clientID || c.ConfigID != configID
+	})
+	if len(s.clients) == count {
+		return fmt.Errorf("failed to delete config client: %w", database.ErrNotFound)
+	}
+	return nil
+}
+
+// UseConfigClient records a request of the client the way the proxy does.
+func (s *Store) UseConfigClient(clientID uuid.UUID, usedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.clients {
+		if s.clients[i].ID == clientID {
+			usedAt := usedAt.UTC()
+			s.clients[i].LastUsedAt = &usedAt
+		}
+	}
+}
+
+func (s *Store) ListAlerts(ctx context.Context, configID uuid.UUID) ([]models.Alert, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return s.listAlerts(configID), nil
+}
+
+func (s *Store) listAlerts(configID uuid.UUID) []models.Alert {
+	return filter(s.alerts, func(a models.Alert) bool { return a.ConfigID == configID })
+}
+
+func (s *Store) ListAllAlerts(ctx context.Context) ([]models.Alert, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return append([]models.Alert(nil), s.alerts...), nil
+}
+
+func (s *Store) GetAlert(ctx context.Context, alertID uuid.UUID) (*models.Alert, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, alert := range s.alerts {
+		if alert.ID == alertID {
+			return &alert, nil
+		}
+	}
+
+	return nil, notFound("alert", alertID)
+}
+
+func (s *Store) CreateAlert(ctx context.Context, configID uuid.UUID, kind string, threshold int,
+	windowMinutes int, channel string, target string) (*models.Alert, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	alert := models.Alert{
+		ID:            uuid.New(),
+		ConfigID:      configID,
+		Kind:          kind,
+		Threshold:     threshold,
+		WindowMinutes: windowMinutes,
+		Channel:       channel,
+		Target:        target,
+		State:         models.AlertStateOK,
+	}
+	s.alerts = append(s.alerts, alert)
+
+	return &alert, nil
+}
+
+func (s *Store) UpdateAlertState(ctx context.Context, alertID uuid.UUID, state string, value int,
+	evaluatedAt time.Time, triggeredAt *time.Time) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	for i := range s.alerts {
+		if s.alerts[i].ID != alertID {
+			continue
+		}
+		s.alerts[i].State = state
+		s.alerts[i].LastValue = value
+		s.alerts[i].LastEvaluatedAt = &evaluatedAt
+		if triggeredAt != nil {
+			s.alerts[i].LastTriggeredAt = triggeredAt
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteAlert(ctx context.Context, alertID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.alerts = filter(s.alerts, func(a models.Alert) bool { return a.ID != alertID })
+	return nil
+}
+
+func (s *Store) CountConfigUsage(ctx context.Context, configID uuid.UUID, since time.Time) (int, int, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	var requests, rejected int
+	for _, record := range s.usage {
+		if record.configID != configID || record.createdAt.Before(since) {
+			continue
+		}
+		requests++
+		if record.rejected {
+			rejected++
+		}
+	}
+
+	return requests, rejected, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, projectID uuid.UUID) ([]models.Webhook, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	return s.listWebhooks(projectID), nil
+}
+
+func (s *Store) listWebhooks(projectID uuid.UUID) []models.Webhook {
+	return filter(s.webhooks, func(w models.Webhook) bool { return w.ProjectID == projectID })
+}
+
+func (s *Store) GetWebhook(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	for _, webhook := range s.webhooks {
+		if webhook.ID == webhookID {
+			return &webhook, nil
+		}
+	}
+
+	return nil, notFound("webhook", webhookID)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, projectID uuid.UUID, url string, secret string, events []string) (*models.Webhook, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	webhook := models.Webhook{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		URL:       url,
+		Secret:    secret,
+		Events:    append([]string(nil), events...),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.webhooks = append(s.webhooks, webhook)
+
+	return &webhook, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	s.deleteWebhook(webhookID)
+	return nil
+}
+
+func (s *Store) deleteWebhook(webhookID uuid.UUID) {
+	s.webhooks = filter(s.webhooks, func(w models.Webhook) bool { return w.ID != webhookID })
+	s.deliveries = filter(s.deliveries, func(d models.WebhookDelivery) bool { return d.WebhookID != webhookID })
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	s.lock(ctx)
+	defer s.mu.Unlock()
+
+	exists := false
+	for _, webhook := range s.webhooks {
+		exists = exists || webhook.ID == delivery.WebhookID
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", notFound("webhook", delivery.WebhookID))
+	}
+
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = time.Now().UTC()
+	s.deliveries = append(s.deliveries, delivery)
+
+	return &delivery, nil
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	s.rLock(ctx)
+	defer s.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, s.deliveries[i])
+		}
+	}
+
+	return deliveries, nil
+}
+
+func filter[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+
+	return kept
+}
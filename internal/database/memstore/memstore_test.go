@@ -0,0 +1,68 @@
+package memstore_test
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/database/storetest"
+	"configuration-management/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Store {
+		return memstore.New()
+	})
+}
+
+// A failed transaction must not undo what other goroutines wrote meanwhile,
+// they wait for it instead.
+func TestTransactionRollbackKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice"})
+
+	rollback := errors.New("rollback")
+	created := make(chan struct{})
+	err := store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if _, err := tx.CreateProject(ctx, "payments", "", "key", user.ID); err != nil {
+			return err
+		}
+		go func() {
+			store.CreateProject(context.Background(), "billing", "", "key", user.ID)
+			close(created)
+		}()
+		select {
+		case <-created:
+			t.Error("expected the concurrent write to wait for the transaction")
+		case <-time.After(50 * time.Millisecond):
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+	<-created
+
+	projects, _ := store.ListProjects(ctx, user.ID, database.ProjectListOptions{})
+	if len(projects) != 1 || projects[0].Name != "billing" {
+		t.Fatalf("expected only the concurrent write to be kept, got %+v", projects)
+	}
+}
+
+func TestTransactionOutsideStore(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+
+	defer func() {
+		if recovered := recover(); recovered != database.ErrOutsideTransaction {
+			t.Fatalf("expected a panic with ErrOutsideTransaction, got %v", recovered)
+		}
+	}()
+	store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		_, err := store.ListUsers(ctx)
+		return err
+	})
+}
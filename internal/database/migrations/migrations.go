@@ -0,0 +1,316 @@
+// Package migrations embeds the schema migrations of every storage backend
+// so that they can be applied by the migrate command or at API startup.
+package migrations
+
+import (
+	"configuration-management/internal/database"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// Step is a migration that a command would run, in the given direction.
+type Step struct {
+	Migration
+	Up bool
+}
+
+type Status struct {
+	// Version is zero when no migration has been applied.
+	Version uint
+	Dirty   bool
+	Applied []Migration
+	Pending []Migration
+}
+
+type Migrator struct {
+	m          *migrate.Migrate
+	source     source.Driver
+	conn       *sql.Conn
+	migrations []Migration
+}
+
+// New returns a migrator for the database. It does not take ownership of
+// the database connection pool, Close only releases the migrator resources.
+func New(db *database.DatabaseHandler) (*Migrator, error) {
+	src, err := iofs.New(files, db.Driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s migrations: %v", db.Driver, err)
+	}
+
+	migrator := &Migrator{source: src}
+	var driver migratedb.Driver
+	switch db.Driver {
+	case database.DriverSQLite:
+		// SQLite migrations from 000011 on manage their own transaction, so
+		// that table rebuilds can disable foreign keys outside of it. The
+		// earlier ones are left as released and run without a transaction.
+		driver, err = sqlite.WithInstance(db.DB, &sqlite.Config{NoTxWrap: true})
+	case database.DriverPostgres:
+		// The postgres driver locks the schema through a dedicated
+		// connection, which is closed with the migrator.
+		migrator.conn, err = db.DB.Conn(context.Background())
+		if err == nil {
+			driver, err = postgres.WithConnection(context.Background(), migrator.conn, &postgres.Config{})
+		}
+	default:
+		err = fmt.Errorf("unsupported driver %q", db.Driver)
+	}
+	if err != nil {
+		migrator.Close()
+		return nil, fmt.Errorf("failed to open migrations database: %v", err)
+	}
+
+	migrator.m, err = migrate.NewWithInstance("iofs", src, db.Driver, driver)
+	if err != nil {
+		migrator.Close()
+		return nil, fmt.Errorf("failed to initialise migrations: %v", err)
+	}
+
+	if err := migrator.loadMigrations(); err != nil {
+		migrator.Close()
+		return nil, err
+	}
+
+	return migrator, nil
+}
+
+func (m *Migrator) loadMigrations() error {
+	version, err := m.source.First()
+	for err == nil {
+		body, name, readErr := m.source.ReadUp(version)
+		if readErr != nil {
+			return fmt.Errorf("failed to read migration %d: %v", version, readErr)
+		}
+		body.Close()
+		m.migrations = append(m.migrations, Migration{Version: version, Name: name})
+		version, err = m.source.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to list migrations: %v", err)
+	}
+
+	return nil
+}
+
+// Close releases the migration source and the dedicated connection, the
+// database itself stays open.
+func (m *Migrator) Close() error {
+	var connErr error
+	if m.conn != nil {
+		connErr = m.conn.Close()
+	}
+	if err := m.source.Close(); err != nil {
+		return err
+	}
+
+	return connErr
+}
+
+// Migrations lists every known migration in ascending order.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Version returns the current version, zero when no migration has been
+// applied yet.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %v", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Latest returns the version of the newest migration.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status() (*Status, error) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Version: version, Dirty: dirty}
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	return status, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down rolls back the last n applied migrations.
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("the number of migrations to roll back must be positive")
+	}
+	return ignoreNoChange(m.m.Steps(-n))
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	if version == 0 {
+		return ignoreNoChange(m.m.Down())
+	}
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the version without running any migration, it is used to
+// recover from a dirty state after fixing a failed migration by hand. A
+// version of -1 marks the database as not migrated.
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version %d: %v", version, err)
+	}
+	return nil
+}
+
+// PlanUp returns the migrations Up would run.
+func (m *Migrator) PlanUp() ([]Step, error) {
+	return m.plan(m.Latest())
+}
+
+// PlanDown returns the migrations Down would run.
+func (m *Migrator) PlanDown(n int) ([]Step, error) {
+	version, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	target := uint(0)
+	applied := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if m.migrations[i].Version > version {
+			continue
+		}
+		if applied == n {
+			target = m.migrations[i].Version
+			break
+		}
+		applied++
+	}
+
+	return m.plan(target)
+}
+
+// PlanGoto returns the migrations Goto would run.
+func (m *Migrator) PlanGoto(version uint) ([]Step, error) {
+	return m.plan(version)
+}
+
+func (m *Migrator) plan(target uint) ([]Step, error) {
+	version, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	if target >= version {
+		for _, migration := range m.migrations {
+			if migration.Version > version && migration.Version <= target {
+				steps = append(steps, Step{Migration: migration, Up: true})
+			}
+		}
+		return steps, nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= version && migration.Version > target {
+			steps = append(steps, Step{Migration: migration, Up: false})
+		}
+	}
+
+	return steps, nil
+}
+
+// Check reports an error unless the database is at the newest migration
+// embedded in the binary. Unlike New it neither locks the schema nor holds
+// a dedicated connection, so that it can run on every readiness probe.
+func Check(ctx context.Context, db *database.DatabaseHandler) error {
+	latest, err := latestVersion(db.Driver)
+	if err != nil {
+		return err
+	}
+
+	var (
+		version uint
+		dirty   bool
+	)
+	err = db.DB.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read migration version: %v", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed and left the database dirty", version)
+	}
+	if version != latest {
+		return fmt.Errorf("the database is at migration %d, this binary expects %d", version, latest)
+	}
+
+	return nil
+}
+
+func latestVersion(driver string) (uint, error) {
+	src, err := iofs.New(files, driver)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s migrations: %v", driver, err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		var next uint
+		if next, err = src.Next(version); err == nil {
+			version = next
+		}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to list migrations: %v", err)
+	}
+
+	return version, nil
+}
+
+func ignoreNoChange(err error) error {
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"configuration-management/internal/database"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newMigrator(t *testing.T) *Migrator {
+	migrator, _ := newMigratorWithDB(t)
+	return migrator
+}
+
+func newMigratorWithDB(t *testing.T) (*Migrator, *database.DatabaseHandler) {
+	db, err := database.NewSQLite(filepath.Join(t.TempDir(), "migrations.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { migrator.Close() })
+	return migrator, db
+}
+
+func TestUpAndDown(t *testing.T) {
+	migrator := newMigrator(t)
+	latest := migrator.Latest()
+
+	plan, err := migrator.PlanUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != len(migrator.Migrations()) || !plan[0].Up {
+		t.Fatalf("expected every migration to be planned up, got %+v", plan)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if version, dirty, _ := migrator.Version(); version != latest || dirty {
+		t.Fatalf("expected version %d, got %d (dirty %v)", latest, version, dirty)
+	}
+	// Running up again is not an error.
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = migrator.PlanDown(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 2 || plan[0].Up || plan[0].Version != latest {
+		t.Fatalf("expected the last two migrations to be planned down, got %+v", plan)
+	}
+	if err := migrator.Down(2); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Pending) != 2 || status.Version != plan[1].Version-1 {
+		t.Fatalf("unexpected status after rolling back %+v", status)
+	}
+}
+
+func TestGoto(t *testing.T) {
+	migrator := newMigrator(t)
+
+	plan, err := migrator.PlanGoto(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("expected three migrations to be planned, got %+v", plan)
+	}
+	if err := migrator.Goto(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Goto(0); err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ := migrator.Version(); version != 0 {
+		t.Fatalf("expected every migration to be rolled back, got version %d", version)
+	}
+}
+
+func TestUsersKeyedByProviderKeepData(t *testing.T) {
+	migrator, db := newMigratorWithDB(t)
+	if err := migrator.Goto(10); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID string
+	if err := db.DB.QueryRow(
+		`INSERT INTO users (oauth2_id, name, avatarUrl) VALUES (42, 'octocat', '') RETURNING id`,
+	).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec(
+		`INSERT INTO projects (name, description, access_key, user_id) VALUES ('project', '', 'key', $1)`, userID,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var provider, subject string
+	if err := db.DB.QueryRow(`SELECT provider, subject FROM users WHERE id = $1`, userID).Scan(&provider, &subject); err != nil {
+		t.Fatal(err)
+	}
+	if provider != "github" || subject != "42" {
+		t.Fatalf("expected the GitHub id to become the subject, got %s/%s", provider, subject)
+	}
+
+	var projects int
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM projects WHERE user_id = $1`, userID).Scan(&projects); err != nil {
+		t.Fatal(err)
+	}
+	if projects != 1 {
+		t.Fatalf("expected the user's project to survive the migration, got %d", projects)
+	}
+
+	var foreignKeys int
+	if err := db.DB.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+		t.Fatal(err)
+	}
+	if foreignKeys != 1 {
+		t.Fatalf("expected foreign keys to be enabled again")
+	}
+
+	if err := migrator.Goto(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM projects WHERE user_id = $1`, userID).Scan(&projects); err != nil || projects != 1 {
+		t.Fatalf("expected the project to survive rolling back, got %d, %v", projects, err)
+	}
+}
+
+func TestUsersKeyedByProviderRollbackKeepsOtherProviders(t *testing.T) {
+	migrator, db := newMigratorWithDB(t)
+	if err := migrator.Goto(11); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec(
+		`INSERT INTO users (provider, subject, name, avatarUrl) VALUES ('oidc', 'alice', 'alice', '')`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Goto(10); err == nil {
+		t.Fatalf("expected rolling back with users of other providers to fail")
+	}
+	var users int
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE provider = 'oidc'`).Scan(&users); err != nil || users != 1 {
+		t.Fatalf("expected the user to be kept, got %d, %v", users, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	migrator, db := newMigratorWithDB(t)
+	ctx := context.Background()
+
+	if err := Check(ctx, db); err == nil || !strings.Contains(err.Error(), "at migration 0") {
+		t.Fatalf("expected an unmigrated database to fail the check, got %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(ctx, db); err != nil {
+		t.Fatalf("expected a migrated database to pass the check, got %v", err)
+	}
+	if err := migrator.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(ctx, db); err == nil {
+		t.Fatalf("expected an outdated database to fail the check")
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alerts;
+DROP TYPE IF EXISTS ALERT_KIND;
+DROP TYPE IF EXISTS ALERT_CHANNEL;
+DROP TYPE IF EXISTS ALERT_STATE;
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS alerts;
+DROP TYPE IF EXISTS ALERT_KIND;
+DROP TYPE IF EXISTS ALERT_CHANNEL;
+DROP TYPE IF EXISTS ALERT_STATE;
+CREATE TYPE ALERT_KIND AS ENUM ('quota', 'rejections');
+CREATE TYPE ALERT_CHANNEL AS ENUM ('webhook', 'email');
+CREATE TYPE ALERT_STATE AS ENUM ('ok', 'firing');
+
+CREATE TABLE alerts (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    config_id UUID NOT NULL,
+    kind ALERT_KIND NOT NULL,
+    threshold INT NOT NULL,
+    window_minutes INT NOT NULL DEFAULT 0,
+    channel ALERT_CHANNEL NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    state ALERT_STATE NOT NULL DEFAULT 'ok',
+    last_value INT NOT NULL DEFAULT 0,
+    last_evaluated_at TIMESTAMP,
+    last_triggered_at TIMESTAMP,
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS config_usage;
+CREATE TABLE config_usage (
+    id BIGSERIAL PRIMARY KEY,
+    config_id UUID NOT NULL,
+    rejected BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
+CREATE INDEX config_usage_config_id_created_at_idx ON config_usage (config_id, created_at);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS webhooks;
+CREATE TABLE webhooks (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    project_id UUID NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
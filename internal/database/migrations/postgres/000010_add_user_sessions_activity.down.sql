@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS user_sessions_user_id_idx;
+ALTER TABLE user_sessions
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address;
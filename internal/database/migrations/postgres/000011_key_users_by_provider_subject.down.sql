@@ -0,0 +1,18 @@
+-- Only GitHub users can be represented with a numeric id, the other users
+-- have to be deleted by hand before rolling back.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE provider <> 'github') THEN
+        RAISE EXCEPTION 'users of providers other than github exist, delete them to roll back';
+    END IF;
+END
+$$;
+ALTER TABLE users ADD COLUMN oauth2_id INT;
+UPDATE users SET oauth2_id = CAST(subject AS INT);
+ALTER TABLE users
+    ALTER COLUMN oauth2_id SET NOT NULL,
+    ADD CONSTRAINT users_oauth2_id_key UNIQUE (oauth2_id),
+    DROP CONSTRAINT IF EXISTS users_provider_subject_key,
+    DROP COLUMN IF EXISTS provider,
+    DROP COLUMN IF EXISTS subject,
+    DROP COLUMN IF EXISTS email;
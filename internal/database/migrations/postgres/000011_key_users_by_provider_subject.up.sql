@@ -0,0 +1,11 @@
+-- Existing users signed in with GitHub, their subject is the GitHub user id.
+ALTER TABLE users
+    ADD COLUMN provider VARCHAR(64) NOT NULL DEFAULT 'github',
+    ADD COLUMN subject VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE users SET subject = CAST(oauth2_id AS TEXT);
+ALTER TABLE users
+    ALTER COLUMN provider DROP DEFAULT,
+    ALTER COLUMN subject DROP DEFAULT,
+    DROP COLUMN oauth2_id,
+    ADD CONSTRAINT users_provider_subject_key UNIQUE (provider, subject);
@@ -0,0 +1,23 @@
+CREATE TABLE local_credentials (
+    user_id UUID PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    totp_secret TEXT NOT NULL DEFAULT '',
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+-- Invites and password resets, only a hash of the token is stored.
+CREATE TABLE auth_tokens (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('invite', 'password_reset')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id UUID,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
@@ -0,0 +1,13 @@
+-- Clients of a config, each with its own proxy URL. Only a hash of the
+-- secret is stored, the proxy records when a client was last used.
+CREATE TABLE config_clients (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    config_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    limit_requests_count INT,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
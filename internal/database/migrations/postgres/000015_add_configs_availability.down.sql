@@ -0,0 +1,6 @@
+DROP VIEW IF EXISTS proxy_configs;
+DROP TABLE IF EXISTS config_schedules;
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS active_from,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS disabled_at;
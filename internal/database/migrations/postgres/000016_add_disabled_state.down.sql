@@ -0,0 +1,28 @@
+DROP VIEW IF EXISTS proxy_disabled_configs;
+
+CREATE OR REPLACE VIEW proxy_configs AS
+SELECT c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration
+FROM configs c
+WHERE c.disabled_at IS NULL
+    AND (c.active_from IS NULL OR c.active_from <= NOW() AT TIME ZONE 'UTC')
+    AND (c.expires_at IS NULL OR c.expires_at > NOW() AT TIME ZONE 'UTC')
+    AND (
+        NOT EXISTS (SELECT 1 FROM config_schedules s WHERE s.config_id = c.id)
+        OR EXISTS (
+            SELECT 1 FROM config_schedules s
+            WHERE s.config_id = c.id
+                AND s.weekday = EXTRACT(DOW FROM NOW() AT TIME ZONE 'UTC')
+                AND s.start_minute <= EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC') * 60 + EXTRACT(MINUTE FROM NOW() AT TIME ZONE 'UTC')
+                AND s.end_minute > EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC') * 60 + EXTRACT(MINUTE FROM NOW() AT TIME ZONE 'UTC')
+        )
+    );
+
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_by;
+
+-- disabled_at stays, configs disabled by hand remain disabled.
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_by;
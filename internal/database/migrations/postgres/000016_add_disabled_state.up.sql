@@ -0,0 +1,43 @@
+-- Configs and projects can be switched off by hand, disabled_by names the
+-- user who did, it is empty when the application disabled a config.
+ALTER TABLE configs
+    ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN disabled_by VARCHAR(255) NOT NULL DEFAULT '';
+-- Configs were only disabled on expiry so far.
+UPDATE configs SET disabled_reason = 'expired' WHERE disabled_at IS NOT NULL;
+
+ALTER TABLE projects
+    ADD COLUMN disabled_at TIMESTAMP,
+    ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN disabled_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The configs of a disabled project are not served either.
+CREATE OR REPLACE VIEW proxy_configs AS
+SELECT c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration
+FROM configs c
+JOIN projects p ON p.id = c.project_id
+WHERE c.disabled_at IS NULL
+    AND p.disabled_at IS NULL
+    AND (c.active_from IS NULL OR c.active_from <= NOW() AT TIME ZONE 'UTC')
+    AND (c.expires_at IS NULL OR c.expires_at > NOW() AT TIME ZONE 'UTC')
+    AND (
+        NOT EXISTS (SELECT 1 FROM config_schedules s WHERE s.config_id = c.id)
+        OR EXISTS (
+            SELECT 1 FROM config_schedules s
+            WHERE s.config_id = c.id
+                AND s.weekday = EXTRACT(DOW FROM NOW() AT TIME ZONE 'UTC')
+                AND s.start_minute <= EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC') * 60 + EXTRACT(MINUTE FROM NOW() AT TIME ZONE 'UTC')
+                AND s.end_minute > EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC') * 60 + EXTRACT(MINUTE FROM NOW() AT TIME ZONE 'UTC')
+        )
+    );
+
+-- The proxy reads why a config is switched off from this view, to tell its
+-- clients instead of rejecting them as unknown. A config disabled on its own
+-- is listed with its own reason, before the one of its project.
+CREATE VIEW proxy_disabled_configs AS
+SELECT c.id, c.project_id,
+    COALESCE(c.disabled_at, p.disabled_at) AS disabled_at,
+    CASE WHEN c.disabled_at IS NOT NULL THEN c.disabled_reason ELSE p.disabled_reason END AS disabled_reason
+FROM configs c
+JOIN projects p ON p.id = c.project_id
+WHERE c.disabled_at IS NOT NULL OR p.disabled_at IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE local_credentials
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_failures,
+    DROP COLUMN IF EXISTS totp_failed_at;
@@ -0,0 +1,6 @@
+-- totp_last_step is the time step of the last accepted code, a code is only
+-- accepted once. totp_failures counts the invalid codes since then.
+ALTER TABLE local_credentials
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN totp_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN totp_failed_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS local_credentials_first_account;
+ALTER TABLE local_credentials DROP COLUMN IF EXISTS first_account;
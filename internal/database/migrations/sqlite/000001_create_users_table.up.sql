@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS users;
+CREATE TABLE users (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    oauth2_id INT NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    avatarUrl VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS projects;
+CREATE TABLE projects (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    access_key VARCHAR(255) NOT NULL,
+    user_id TEXT NOT NULL,
+    timestamp TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
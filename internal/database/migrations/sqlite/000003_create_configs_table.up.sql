@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS configs;
+CREATE TABLE configs (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    project_id TEXT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    limit_requests_count INT NOT NULL,
+    limit_duration TEXT NOT NULL CHECK (limit_duration IN ('second', 'minute', 'hour', 'day', 'week', 'month', 'year', 'forever')),
+    CONSTRAINT fk_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
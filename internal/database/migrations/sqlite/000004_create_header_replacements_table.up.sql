@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS header_replacements;
+CREATE TABLE header_replacements (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    config_id TEXT NOT NULL,
+    header_name VARCHAR(255) NOT NULL,
+    header_value VARCHAR(255) NOT NULL,
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS alerts;
+CREATE TABLE alerts (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    config_id TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('quota', 'rejections')),
+    threshold INT NOT NULL,
+    window_minutes INT NOT NULL DEFAULT 0,
+    channel TEXT NOT NULL CHECK (channel IN ('webhook', 'email')),
+    target VARCHAR(255) NOT NULL,
+    state TEXT NOT NULL DEFAULT 'ok' CHECK (state IN ('ok', 'firing')),
+    last_value INT NOT NULL DEFAULT 0,
+    last_evaluated_at TIMESTAMP,
+    last_triggered_at TIMESTAMP,
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
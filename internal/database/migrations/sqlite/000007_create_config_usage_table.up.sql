@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS config_usage;
+CREATE TABLE config_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    config_id TEXT NOT NULL,
+    rejected BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
+CREATE INDEX config_usage_config_id_created_at_idx ON config_usage (config_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_sessions_user_id_idx;
+ALTER TABLE user_sessions DROP COLUMN expires_at;
+ALTER TABLE user_sessions DROP COLUMN last_seen_at;
+ALTER TABLE user_sessions DROP COLUMN user_agent;
+ALTER TABLE user_sessions DROP COLUMN ip_address;
@@ -0,0 +1,7 @@
+-- Sessions created before expiry was enforced are expired right away.
+ALTER TABLE user_sessions ADD COLUMN expires_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE user_sessions ADD COLUMN last_seen_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE user_sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sessions ADD COLUMN ip_address VARCHAR(64) NOT NULL DEFAULT '';
+UPDATE user_sessions SET last_seen_at = created_at;
+CREATE INDEX user_sessions_user_id_idx ON user_sessions (user_id);
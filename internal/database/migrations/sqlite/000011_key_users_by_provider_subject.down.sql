@@ -0,0 +1,20 @@
+-- Only GitHub users can be represented with a numeric id, the other users
+-- have to be deleted by hand before rolling back. SQLite only raises errors
+-- in triggers, the check constraint fails instead.
+CREATE TEMP TABLE users_of_other_providers (count INTEGER CHECK (count = 0));
+INSERT INTO users_of_other_providers SELECT COUNT(*) FROM users WHERE provider <> 'github';
+DROP TABLE users_of_other_providers;
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE users_old (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    oauth2_id INT NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    avatarUrl VARCHAR(255) NOT NULL
+);
+INSERT INTO users_old (id, oauth2_id, name, avatarUrl)
+SELECT id, CAST(subject AS INT), name, avatarUrl FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+COMMIT;
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,21 @@
+-- SQLite cannot drop a unique column, so the table is rebuilt. Foreign keys
+-- are disabled for the rebuild, otherwise dropping the old table would
+-- cascade to every project and session.
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE users_new (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    avatarUrl VARCHAR(255) NOT NULL,
+    UNIQUE (provider, subject)
+);
+-- Existing users signed in with GitHub, their subject is the GitHub user id.
+INSERT INTO users_new (id, provider, subject, name, avatarUrl)
+SELECT id, 'github', CAST(oauth2_id AS TEXT), name, avatarUrl FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+COMMIT;
+PRAGMA foreign_keys = ON;
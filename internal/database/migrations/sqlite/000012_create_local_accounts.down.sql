@@ -0,0 +1,4 @@
+BEGIN;
+DROP TABLE IF EXISTS auth_tokens;
+DROP TABLE IF EXISTS local_credentials;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+DROP VIEW IF EXISTS proxy_configs;
+DROP TABLE IF EXISTS config_schedules;
+ALTER TABLE configs DROP COLUMN active_from;
+ALTER TABLE configs DROP COLUMN expires_at;
+ALTER TABLE configs DROP COLUMN disabled_at;
+COMMIT;
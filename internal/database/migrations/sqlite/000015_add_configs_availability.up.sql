@@ -0,0 +1,39 @@
+BEGIN;
+-- Configs can be limited to a period and to weekly windows in UTC, the
+-- expiry job sets disabled_at once a config expired.
+ALTER TABLE configs ADD COLUMN active_from TIMESTAMP;
+ALTER TABLE configs ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE configs ADD COLUMN disabled_at TIMESTAMP;
+
+-- weekday is 0 for Sunday, a config with windows is only served within one
+-- of them, from start_minute included to end_minute excluded.
+CREATE TABLE config_schedules (
+    id TEXT DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))) PRIMARY KEY,
+    config_id TEXT NOT NULL,
+    weekday INT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_minute INT NOT NULL CHECK (start_minute BETWEEN 0 AND 1439),
+    end_minute INT NOT NULL CHECK (end_minute BETWEEN 1 AND 1440),
+    CHECK (start_minute < end_minute),
+    CONSTRAINT fk_config FOREIGN KEY (config_id) REFERENCES configs (id) ON DELETE CASCADE
+);
+CREATE INDEX config_schedules_config_id_idx ON config_schedules (config_id);
+
+-- The proxy reads the configs it may serve right now from this view. The
+-- timestamps are compared as julian days as they are stored as text.
+CREATE VIEW proxy_configs AS
+SELECT c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration
+FROM configs c
+WHERE c.disabled_at IS NULL
+    AND (c.active_from IS NULL OR julianday(c.active_from) <= julianday('now'))
+    AND (c.expires_at IS NULL OR julianday(c.expires_at) > julianday('now'))
+    AND (
+        NOT EXISTS (SELECT 1 FROM config_schedules s WHERE s.config_id = c.id)
+        OR EXISTS (
+            SELECT 1 FROM config_schedules s
+            WHERE s.config_id = c.id
+                AND s.weekday = CAST(strftime('%w', 'now') AS INTEGER)
+                AND s.start_minute <= CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+                AND s.end_minute > CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+        )
+    );
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+DROP VIEW IF EXISTS proxy_disabled_configs;
+
+DROP VIEW proxy_configs;
+CREATE VIEW proxy_configs AS
+SELECT c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration
+FROM configs c
+WHERE c.disabled_at IS NULL
+    AND (c.active_from IS NULL OR julianday(c.active_from) <= julianday('now'))
+    AND (c.expires_at IS NULL OR julianday(c.expires_at) > julianday('now'))
+    AND (
+        NOT EXISTS (SELECT 1 FROM config_schedules s WHERE s.config_id = c.id)
+        OR EXISTS (
+            SELECT 1 FROM config_schedules s
+            WHERE s.config_id = c.id
+                AND s.weekday = CAST(strftime('%w', 'now') AS INTEGER)
+                AND s.start_minute <= CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+                AND s.end_minute > CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+        )
+    );
+
+ALTER TABLE projects DROP COLUMN disabled_at;
+ALTER TABLE projects DROP COLUMN disabled_reason;
+ALTER TABLE projects DROP COLUMN disabled_by;
+
+-- disabled_at stays, configs disabled by hand remain disabled.
+ALTER TABLE configs DROP COLUMN disabled_reason;
+ALTER TABLE configs DROP COLUMN disabled_by;
+COMMIT;
@@ -0,0 +1,44 @@
+BEGIN;
+-- Configs and projects can be switched off by hand, disabled_by names the
+-- user who did, it is empty when the application disabled a config.
+ALTER TABLE configs ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE configs ADD COLUMN disabled_by TEXT NOT NULL DEFAULT '';
+-- Configs were only disabled on expiry so far.
+UPDATE configs SET disabled_reason = 'expired' WHERE disabled_at IS NOT NULL;
+
+ALTER TABLE projects ADD COLUMN disabled_at TIMESTAMP;
+ALTER TABLE projects ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE projects ADD COLUMN disabled_by TEXT NOT NULL DEFAULT '';
+
+-- The configs of a disabled project are not served either.
+DROP VIEW proxy_configs;
+CREATE VIEW proxy_configs AS
+SELECT c.id, c.project_id, c.name, c.limit_requests_count, c.limit_duration
+FROM configs c
+JOIN projects p ON p.id = c.project_id
+WHERE c.disabled_at IS NULL
+    AND p.disabled_at IS NULL
+    AND (c.active_from IS NULL OR julianday(c.active_from) <= julianday('now'))
+    AND (c.expires_at IS NULL OR julianday(c.expires_at) > julianday('now'))
+    AND (
+        NOT EXISTS (SELECT 1 FROM config_schedules s WHERE s.config_id = c.id)
+        OR EXISTS (
+            SELECT 1 FROM config_schedules s
+            WHERE s.config_id = c.id
+                AND s.weekday = CAST(strftime('%w', 'now') AS INTEGER)
+                AND s.start_minute <= CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+                AND s.end_minute > CAST(strftime('%H', 'now') AS INTEGER) * 60 + CAST(strftime('%M', 'now') AS INTEGER)
+        )
+    );
+
+-- The proxy reads why a config is switched off from this view, to tell its
+-- clients instead of rejecting them as unknown. A config disabled on its own
+-- is listed with its own reason, before the one of its project.
+CREATE VIEW proxy_disabled_configs AS
+SELECT c.id, c.project_id,
+    COALESCE(c.disabled_at, p.disabled_at) AS disabled_at,
+    CASE WHEN c.disabled_at IS NOT NULL THEN c.disabled_reason ELSE p.disabled_reason END AS disabled_reason
+FROM configs c
+JOIN projects p ON p.id = c.project_id
+WHERE c.disabled_at IS NOT NULL OR p.disabled_at IS NOT NULL;
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE local_credentials DROP COLUMN totp_last_step;
+ALTER TABLE local_credentials DROP COLUMN totp_failures;
+ALTER TABLE local_credentials DROP COLUMN totp_failed_at;
+COMMIT;
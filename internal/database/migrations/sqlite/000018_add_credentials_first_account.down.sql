@@ -0,0 +1,4 @@
+BEGIN;
+DROP INDEX IF EXISTS local_credentials_first_account;
+ALTER TABLE local_credentials DROP COLUMN first_account;
+COMMIT;
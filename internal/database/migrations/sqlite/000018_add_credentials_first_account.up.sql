@@ -0,0 +1,7 @@
+BEGIN;
+-- first_account is TRUE on the account created without an invite while there
+-- was no account yet, and NULL otherwise. The unique index lets a single
+-- concurrent sign-up create it.
+ALTER TABLE local_credentials ADD COLUMN first_account BOOLEAN;
+CREATE UNIQUE INDEX local_credentials_first_account ON local_credentials (first_account);
+COMMIT;
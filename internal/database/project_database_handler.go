@@ -2,83 +2,279 @@ package database
 
 import (
 	"configuration-management/internal/models"
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-func (s *DatabaseHandler) ListProjects(userID uuid.UUID) ([]models.Project, error) {
+const projectColumns = `id, name, description, access_key, user_id, disabled_at, disabled_reason, disabled_by`
+
+// scanProject scans the projectColumns.
+func scanProject(row rowScanner, project *models.Project) error {
+	var disabledAt *time.Time
+	var disabledReason, disabledBy string
+	if err := row.Scan(&project.ID, &project.Name, &project.Description, &project.AccessKey, &project.UserID,
+		&disabledAt, &disabledReason, &disabledBy); err != nil {
+		return err
+	}
+	project.Disabled = models.NewDisablement(disabledAt, disabledReason, disabledBy)
+	return nil
+}
+
+// ListProjects returns a page of the user's projects, newest first. The
+// project tree is loaded with a constant number of queries regardless of the
+// number of projects and configs.
+func (s *DatabaseHandler) ListProjects(ctx context.Context, userID uuid.UUID, options ProjectListOptions) (_ []models.Project, err error) {
+	ctx, end := s.startQuery(ctx, "ListProjects")
+	defer func() { end(err) }()
+
+	limit, offset := options.page()
+	projectIDs := `
+		SELECT id FROM projects
+		WHERE user_id = $1
+		ORDER BY timestamp DESC, id
+		LIMIT $2 OFFSET $3
+	`
 	query := `
-		SELECT id, name, description, access_key
+		SELECT ` + projectColumns + `
 		FROM projects
-		WHERE user_id = $1
-		ORDER BY timestamp DESC
+		WHERE id IN (` + projectIDs + `)
+		ORDER BY timestamp DESC, id
 	`
 
-	rows, err := s.DB.Query(query, userID)
+	rows, err := s.conn().QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query projects: %v", err)
 	}
 	defer rows.Close()
 
 	var projects []models.Project
+	projectsByID := make(map[uuid.UUID]int)
 	for rows.Next() {
 		var project models.Project
-		if err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.AccessKey); err != nil {
+		if err := scanProject(rows, &project); err != nil {
 			return nil, fmt.Errorf("failed to scan project row: %v", err)
 		}
-		// list configs
-		configs, configsErr := s.ListConfigs(project.ID)
+		projectsByID[project.ID] = len(projects)
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query projects: %v", err)
+	}
+
+	if len(projects) == 0 {
+		return projects, nil
+	}
+
+	if options.WithConfigs {
+		configs, configsErr := s.listConfigsWhere(ctx, "c.project_id IN ("+projectIDs+")", userID, limit, offset)
 		if configsErr != nil {
-			return nil, fmt.Errorf("failed to list configs for projectID: %s:  %v", project.ID.String(), configsErr)
+			return nil, fmt.Errorf("failed to list configs: %v", configsErr)
+		}
+		for _, config := range configs {
+			project := &projects[projectsByID[config.ProjectID]]
+			project.Configs = append(project.Configs, config)
 		}
-		project.Configs = configs
+	}
 
-		projects = append(projects, project)
+	webhooks, webhooksErr := s.listWebhooksWhere(ctx, "w.project_id IN ("+projectIDs+")", userID, limit, offset)
+	if webhooksErr != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", webhooksErr)
+	}
+	for _, webhook := range webhooks {
+		project := &projects[projectsByID[webhook.ProjectID]]
+		project.Webhooks = append(project.Webhooks, webhook)
 	}
 
 	return projects, nil
 }
 
-func (s *DatabaseHandler) GetProject(projectID uuid.UUID) (*models.Project, error) {
+func (s *DatabaseHandler) CountProjects(ctx context.Context, userID uuid.UUID) (_ int, err error) {
+	ctx, end := s.startQuery(ctx, "CountProjects")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT COUNT(*) FROM projects WHERE user_id = $1
+	`
+	var count int
+	if err := s.conn().QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count projects: %v", err)
+	}
+
+	return count, nil
+}
+
+func (s *DatabaseHandler) CountProjectsByUser(ctx context.Context) (_ map[uuid.UUID]int, err error) {
+	ctx, end := s.startQuery(ctx, "CountProjectsByUser")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT user_id, COUNT(*) FROM projects GROUP BY user_id
+	`
+	rows, err := s.conn().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count projects: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			userID uuid.UUID
+			count  int
+		)
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan project count: %v", err)
+		}
+		counts[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count projects: %v", err)
+	}
+
+	return counts, nil
+}
+
+func (s *DatabaseHandler) CountUserConfigs(ctx context.Context, userID uuid.UUID) (_ int, err error) {
+	ctx, end := s.startQuery(ctx, "CountUserConfigs")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT COUNT(*)
+		FROM configs c
+		JOIN projects p ON p.id = c.project_id
+		WHERE p.user_id = $1
+	`
+	var count int
+	if err := s.conn().QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count configs: %v", err)
+	}
+
+	return count, nil
+}
+
+func (s *DatabaseHandler) GetProject(ctx context.Context, projectID uuid.UUID) (_ *models.Project, err error) {
+	ctx, end := s.startQuery(ctx, "GetProject")
+	defer func() { end(err) }()
+
 	query := `
-		SELECT id, name, description, access_key, user_id
+		SELECT ` + projectColumns + `
 		FROM projects
 		WHERE id = $1
 	`
 
 	var project models.Project
-	if err := s.DB.QueryRow(query, projectID).Scan(&project.ID, &project.Name,
-		&project.Description, &project.AccessKey, &project.UserID); err != nil {
-		return nil, fmt.Errorf("failed to query project: %v", err)
+	if err := scanProject(s.conn().QueryRowContext(ctx, query, projectID), &project); err != nil {
+		return nil, fmt.Errorf("failed to query project: %w", notFound(err))
 	}
 
 	return &project, nil
 }
 
-func (s *DatabaseHandler) CreateProject(name string, description string, accessKey string, userID uuid.UUID) (*models.Project, error) {
+func (s *DatabaseHandler) UpdateProjectDescription(ctx context.Context, projectID uuid.UUID, description string) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateProjectDescription")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE projects SET description = $1 WHERE id = $2
+	`
+	if _, err := s.conn().ExecContext(ctx, query, description, projectID); err != nil {
+		return fmt.Errorf("failed to update project description: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) CreateProject(ctx context.Context, name string, description string, accessKey string, userID uuid.UUID) (_ *models.Project, err error) {
+	ctx, end := s.startQuery(ctx, "CreateProject")
+	defer func() { end(err) }()
+
 	query := `
 		INSERT into projects (name, description, access_key, user_id)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, description, access_key 
+		RETURNING ` + projectColumns + `
 	`
 
 	var project models.Project
-	err := s.DB.QueryRow(query, name, description, accessKey, userID).Scan(
-		&project.ID, &project.Name, &project.Description, &project.AccessKey,
-	)
-	if err != nil {
+	if err := scanProject(s.conn().QueryRowContext(ctx, query, name, description, accessKey, userID), &project); err != nil {
 		return nil, fmt.Errorf("failed to create project: %v", err)
 	}
 
 	return &project, nil
 }
 
-func (s *DatabaseHandler) DeleteProject(projectID uuid.UUID) error {
+func (s *DatabaseHandler) UpdateProjectAccessKey(ctx context.Context, projectID uuid.UUID, accessKey string) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateProjectAccessKey")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE projects SET access_key = $2 WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, projectID, accessKey); err != nil {
+		return fmt.Errorf("failed to update project access key: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) UpdateProjectOwner(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "UpdateProjectOwner")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE projects SET user_id = $2 WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, projectID, userID); err != nil {
+		return fmt.Errorf("failed to update project owner: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DisableProject(ctx context.Context, projectID uuid.UUID, disablement models.Disablement) (_ bool, err error) {
+	ctx, end := s.startQuery(ctx, "DisableProject")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE projects SET disabled_at = $2, disabled_reason = $3, disabled_by = $4
+		WHERE id = $1 AND disabled_at IS NULL
+	`
+	result, err := s.conn().ExecContext(ctx, query, projectID, disablement.At.UTC(), disablement.Reason, disablement.ByColumn())
+	if err != nil {
+		return false, fmt.Errorf("failed to disable project: %v", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to disable project: %v", err)
+	}
+
+	return updated == 1, nil
+}
+
+func (s *DatabaseHandler) EnableProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "EnableProject")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE projects SET disabled_at = NULL, disabled_reason = '', disabled_by = '' WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, projectID); err != nil {
+		return fmt.Errorf("failed to enable project: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteProject")
+	defer func() { end(err) }()
+
 	query := `
 		DELETE FROM projects WHERE id=$1
 	`
-	_, err := s.DB.Exec(query, projectID)
+	_, err = s.conn().ExecContext(ctx, query, projectID)
 	if err != nil {
 		return fmt.Errorf("failed to delete project: %v", err)
 	}
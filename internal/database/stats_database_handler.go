@@ -0,0 +1,28 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+)
+
+func (s *DatabaseHandler) CountResources(ctx context.Context) (_ *models.ResourceCounts, err error) {
+	ctx, end := s.startQuery(ctx, "CountResources")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM projects),
+			(SELECT COUNT(*) FROM configs),
+			(SELECT COUNT(*) FROM header_replacements)
+	`
+	var counts models.ResourceCounts
+	if err := s.conn().QueryRowContext(ctx, query).Scan(
+		&counts.Users, &counts.Projects, &counts.Configs, &counts.HeaderReplacements,
+	); err != nil {
+		return nil, fmt.Errorf("failed to count resources: %v", err)
+	}
+
+	return &counts, nil
+}
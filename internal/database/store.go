@@ -0,0 +1,236 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type ProjectListOptions struct {
+	// Limit of zero lists every project.
+	Limit  int
+	Offset int
+	// WithConfigs loads the project configs with their headers and alerts.
+	WithConfigs bool
+}
+
+func (o ProjectListOptions) page() (int, int) {
+	if o.Limit <= 0 {
+		return math.MaxInt32, o.Offset
+	}
+	return o.Limit, o.Offset
+}
+
+type ProjectStore interface {
+	ListProjects(ctx context.Context, userID uuid.UUID, options ProjectListOptions) ([]models.Project, error)
+	CountProjects(ctx context.Context, userID uuid.UUID) (int, error)
+	// CountProjectsByUser counts the projects of every user in one query,
+	// users without projects are left out.
+	CountProjectsByUser(ctx context.Context) (map[uuid.UUID]int, error)
+	CountUserConfigs(ctx context.Context, userID uuid.UUID) (int, error)
+	GetProject(ctx context.Context, projectID uuid.UUID) (*models.Project, error)
+	CreateProject(ctx context.Context, name string, description string, accessKey string, userID uuid.UUID) (*models.Project, error)
+	UpdateProjectAccessKey(ctx context.Context, projectID uuid.UUID, accessKey string) error
+	UpdateProjectDescription(ctx context.Context, projectID uuid.UUID, description string) error
+	UpdateProjectOwner(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) error
+	// DisableProject stops the proxy from serving any config of the
+	// project, the configs keep their own state.
+	DisableProject(ctx context.Context, projectID uuid.UUID, disablement models.Disablement) (bool, error)
+	EnableProject(ctx context.Context, projectID uuid.UUID) error
+	DeleteProject(ctx context.Context, projectID uuid.UUID) error
+}
+
+type ConfigStore interface {
+	GetConfig(ctx context.Context, configID uuid.UUID) (*models.Config, error)
+	ListConfigs(ctx context.Context, projectID uuid.UUID) ([]models.Config, error)
+	CreateConfig(ctx context.Context, projectID uuid.UUID, name string, numberOfRequests int, per string) (*models.Config, error)
+	UpdateConfigLimit(ctx context.Context, configID uuid.UUID, numberOfRequests int, per string) error
+	// UpdateConfigAvailability sets the period the config is served in, nil
+	// leaves it open, and enables the config again when it was disabled on
+	// expiry.
+	UpdateConfigAvailability(ctx context.Context, configID uuid.UUID, activeFrom *time.Time, expiresAt *time.Time) error
+	// SetConfigSchedule replaces the weekly windows the config is served in,
+	// a config without windows is served at any time.
+	SetConfigSchedule(ctx context.Context, configID uuid.UUID, windows []models.ScheduleWindow) error
+	// ListExpiredConfigs lists the configs expired at now which are not
+	// disabled yet.
+	ListExpiredConfigs(ctx context.Context, now time.Time) ([]models.Config, error)
+	// DisableConfig returns false when the config is already disabled, the
+	// first reason is kept, so that the expiry job never overwrites a
+	// config disabled by hand.
+	DisableConfig(ctx context.Context, configID uuid.UUID, disablement models.Disablement) (bool, error)
+	EnableConfig(ctx context.Context, configID uuid.UUID) error
+	// IsConfigServed reports whether the proxy serves the config right now,
+	// as read from the proxy_configs view, which also leaves out the configs
+	// of disabled projects.
+	IsConfigServed(ctx context.Context, configID uuid.UUID) (bool, error)
+	DeleteConfig(ctx context.Context, configID uuid.UUID) error
+}
+
+type HeaderStore interface {
+	ListHeaderReplacements(ctx context.Context, configID uuid.UUID) ([]models.HeaderReplacement, error)
+	// ListAllHeaderReplacements lists the header replacements of every
+	// project, for maintenance.
+	ListAllHeaderReplacements(ctx context.Context) ([]models.HeaderReplacement, error)
+	GetHeaderReplacement(ctx context.Context, headerID uuid.UUID) (*models.HeaderReplacement, error)
+	CreateHeaderReplacement(ctx context.Context, configID uuid.UUID, name string, value string) (*models.HeaderReplacement, error)
+	UpdateHeaderReplacementValue(ctx context.Context, headerID uuid.UUID, value string) error
+	DeleteHeaderReplacement(ctx context.Context, headerID uuid.UUID) error
+	// SampleHeaderValue returns the encrypted value of any header
+	// replacement, or ErrNotFound when there is none.
+	SampleHeaderValue(ctx context.Context) (string, error)
+}
+
+type UserStore interface {
+	// CreateUser returns the existing user when one is already known by
+	// the same provider and subject.
+	CreateUser(ctx context.Context, user models.User) (*models.User, error)
+	GetUserBySubject(ctx context.Context, provider string, subject string) (*models.User, error)
+	GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error)
+	ListUsers(ctx context.Context) ([]models.User, error)
+}
+
+// SessionStore returns a nil session without an error when the session
+// does not exist.
+type SessionStore interface {
+	CreateUserSession(ctx context.Context, userID uuid.UUID, userAgent string, ipAddress string, expiresAt time.Time) (*models.Session, error)
+	GetUserSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error)
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	TouchUserSession(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) error
+	DeleteUserSession(ctx context.Context, sessionID uuid.UUID) error
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+	// DeleteExpiredSessions removes the sessions that expired before
+	// expiredBefore or were last seen before idleSince.
+	DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time, idleSince time.Time) (int, error)
+}
+
+// CredentialStore keeps the passwords and second factors of the local
+// users.
+type CredentialStore interface {
+	CreateCredentials(ctx context.Context, credentials models.Credentials) (*models.Credentials, error)
+	GetCredentials(ctx context.Context, username string) (*models.Credentials, error)
+	GetUserCredentials(ctx context.Context, userID uuid.UUID) (*models.Credentials, error)
+	CountCredentials(ctx context.Context) (int, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	UpdateTOTP(ctx context.Context, userID uuid.UUID, secret string, enabled bool) error
+	// UseTOTPStep records the time step of an accepted code and resets the
+	// failures, it returns false when a code of this step or a later one was
+	// already accepted.
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// RecordTOTPFailure returns the number of invalid codes since the last
+	// accepted one.
+	RecordTOTPFailure(ctx context.Context, userID uuid.UUID, failedAt time.Time) (int, error)
+}
+
+type AuthTokenStore interface {
+	CreateAuthToken(ctx context.Context, token models.AuthToken) (*models.AuthToken, error)
+	GetAuthToken(ctx context.Context, kind string, tokenHash string) (*models.AuthToken, error)
+	// UseAuthToken marks the token as used, it returns false when the token
+	// was already used.
+	UseAuthToken(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) (bool, error)
+}
+
+type APITokenStore interface {
+	CreateAPIToken(ctx context.Context, userID uuid.UUID, name string, tokenHash string) (*models.APIToken, error)
+	GetAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	ListAPITokens(ctx context.Context, userID uuid.UUID) ([]models.APIToken, error)
+	TouchAPIToken(ctx context.Context, tokenID uuid.UUID, lastUsedAt time.Time) error
+	// DeleteAPIToken returns ErrNotFound when the user has no such token.
+	DeleteAPIToken(ctx context.Context, userID uuid.UUID, tokenID uuid.UUID) error
+}
+
+// ConfigClientStore keeps the clients of the configs, the proxy
+// authenticates them with the hash of their secret.
+type ConfigClientStore interface {
+	CreateConfigClient(ctx context.Context, client models.ConfigClient) (*models.ConfigClient, error)
+	ListConfigClients(ctx context.Context, configID uuid.UUID) ([]models.ConfigClient, error)
+	// DeleteConfigClient returns ErrNotFound when the config has no such
+	// client.
+	DeleteConfigClient(ctx context.Context, configID uuid.UUID, clientID uuid.UUID) error
+}
+
+type AlertStore interface {
+	ListAlerts(ctx context.Context, configID uuid.UUID) ([]models.Alert, error)
+	ListAllAlerts(ctx context.Context) ([]models.Alert, error)
+	GetAlert(ctx context.Context, alertID uuid.UUID) (*models.Alert, error)
+	CreateAlert(ctx context.Context, configID uuid.UUID, kind string, threshold int, windowMinutes int, channel string, target string) (*models.Alert, error)
+	UpdateAlertState(ctx context.Context, alertID uuid.UUID, state string, value int, evaluatedAt time.Time, triggeredAt *time.Time) error
+	DeleteAlert(ctx context.Context, alertID uuid.UUID) error
+	CountConfigUsage(ctx context.Context, configID uuid.UUID, since time.Time) (int, int, error)
+}
+
+type WebhookStore interface {
+	ListWebhooks(ctx context.Context, projectID uuid.UUID) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error)
+	CreateWebhook(ctx context.Context, projectID uuid.UUID, url string, secret string, events []string) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (*models.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+}
+
+type StatsStore interface {
+	CountResources(ctx context.Context) (*models.ResourceCounts, error)
+}
+
+// Transactor groups multi-step operations into a unit of work that either
+// commits or rolls back as a whole. fn must run every operation through tx
+// with the context it receives, using another store from fn waits for the
+// transaction to end, which with SQLite never happens. Stores detect it and
+// fail the query with ErrOutsideTransaction, memstore panics with it.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context, tx Store) error) error
+}
+
+// Store is implemented by every storage backend.
+type Store interface {
+	Transactor
+	ProjectStore
+	ConfigStore
+	HeaderStore
+	UserStore
+	SessionStore
+	CredentialStore
+	AuthTokenStore
+	APITokenStore
+	ConfigClientStore
+	AlertStore
+	WebhookStore
+	StatsStore
+
+	Health() map[string]string
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+var _ Store = (*DatabaseHandler)(nil)
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// FindUser looks a user up by id, or by provider and subject written as
+// provider:subject such as local:alice, the way the command-line tools name
+// users.
+func FindUser(ctx context.Context, users UserStore, reference string) (*models.User, error) {
+	if id, err := uuid.Parse(reference); err == nil {
+		return users.GetUser(ctx, id)
+	}
+	provider, subject, found := strings.Cut(reference, ":")
+	if !found {
+		return nil, fmt.Errorf("a user is named by id or provider:subject, got %q", reference)
+	}
+	return users.GetUserBySubject(ctx, provider, subject)
+}
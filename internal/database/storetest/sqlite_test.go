@@ -0,0 +1,97 @@
+package storetest_test
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/migrations"
+	"configuration-management/internal/database/storetest"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// The SQLite backend runs without external services, unlike the Postgres
+// tests in the database package which need a container.
+func TestSQLiteConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Store {
+		return newSQLite(t)
+	})
+}
+
+// The single SQLite connection is held by the transaction, a query through
+// the outer store would wait for it forever.
+func TestSQLiteOutsideTransaction(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLite(t)
+
+	err := store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		// The store methods report the cause of a failed query as text.
+		if _, err := store.ListUsers(ctx); err == nil || !strings.Contains(err.Error(), database.ErrOutsideTransaction.Error()) {
+			t.Errorf("expected ErrOutsideTransaction from the outer store, got %v", err)
+		}
+		if err := store.Transaction(ctx, func(ctx context.Context, tx database.Store) error { return nil }); !errors.Is(err, database.ErrOutsideTransaction) {
+			t.Errorf("expected ErrOutsideTransaction from a transaction of the outer store, got %v", err)
+		}
+		_, err := tx.ListUsers(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if _, err := store.ListUsers(ctx); err != nil {
+		t.Fatalf("expected the outer store to work after the transaction, got %v", err)
+	}
+}
+
+func newSQLite(t *testing.T) *database.DatabaseHandler {
+	store, err := database.NewSQLite(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.DB.Close() })
+
+	migrator, err := migrations.New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestSQLiteQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	ctx := context.Background()
+	store := newSQLite(t)
+
+	if _, err := store.ListUsers(ctx); err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if _, err := store.GetProject(ctx, uuid.New()); !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a span per query, got %d", len(spans))
+	}
+	if spans[0].Name() != "store.ListUsers" || spans[0].Status().Code != codes.Unset {
+		t.Fatalf("expected the successful query to leave the status unset, got %s %v", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Name() != "store.GetProject" || spans[1].Status().Code != codes.Error || len(spans[1].Events()) != 1 {
+		t.Fatalf("expected the failed query to record the error, got %s %v %v", spans[1].Name(), spans[1].Status(), spans[1].Events())
+	}
+}
@@ -0,0 +1,1051 @@
+// Package storetest is a conformance suite run against every database.Store
+// implementation, so that the backends stay interchangeable.
+package storetest
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Run executes the suite. newStore is called once per subtest and may return
+// a store shared between subtests, every subtest works on its own user.
+func Run(t *testing.T, newStore func(t *testing.T) database.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store database.Store)
+	}{
+		{"Users", testUsers},
+		{"Sessions", testSessions},
+		{"ExpiredSessions", testExpiredSessions},
+		{"Credentials", testCredentials},
+		{"AuthTokens", testAuthTokens},
+		{"APITokens", testAPITokens},
+		{"ConfigClients", testConfigClients},
+		{"Projects", testProjects},
+		{"ProjectPagination", testProjectPagination},
+		{"Configs", testConfigs},
+		{"ConfigAvailability", testConfigAvailability},
+		{"DisabledState", testDisabledState},
+		{"HeaderReplacements", testHeaderReplacements},
+		{"Alerts", testAlerts},
+		{"Webhooks", testWebhooks},
+		{"CascadingDeletes", testCascadingDeletes},
+		{"ResourceCounts", testResourceCounts},
+		{"TransactionCommit", testTransactionCommit},
+		{"TransactionRollback", testTransactionRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, store database.Store) *models.User {
+	t.Helper()
+	user, err := store.CreateUser(context.Background(), models.User{
+		Provider:  "github",
+		Subject:   strconv.Itoa(rand.Intn(1 << 30)),
+		Name:      "user",
+		AvatarUrl: "https://example.com/avatar.png",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func newProject(t *testing.T, store database.Store, userID uuid.UUID) *models.Project {
+	t.Helper()
+	project, err := store.CreateProject(context.Background(), "project", "description", "access key", userID)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	return project
+}
+
+func newConfig(t *testing.T, store database.Store, projectID uuid.UUID) *models.Config {
+	t.Helper()
+	config, err := store.CreateConfig(context.Background(), projectID, "config", 100, "minute")
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	return config
+}
+
+func expectNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("%s: expected ErrNotFound, got %v", what, err)
+	}
+}
+
+func testUsers(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	subject := strconv.Itoa(rand.Intn(1 << 30))
+	user, err := store.CreateUser(ctx, models.User{
+		Provider: "corp", Subject: subject, Name: "name", Email: "name@example.com", AvatarUrl: "avatar",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.Provider != "corp" || user.Subject != subject || user.Name != "name" ||
+		user.Email != "name@example.com" || user.AvatarUrl != "avatar" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	again, err := store.CreateUser(ctx, models.User{Provider: "corp", Subject: subject, Name: "other name"})
+	if err != nil {
+		t.Fatalf("CreateUser for an existing user: %v", err)
+	}
+	if again.ID != user.ID || again.Name != "name" {
+		t.Fatalf("expected the existing user to be returned, got %+v", again)
+	}
+
+	// The same subject from another provider is a different user.
+	other, err := store.CreateUser(ctx, models.User{Provider: "github", Subject: subject, Name: "other"})
+	if err != nil {
+		t.Fatalf("CreateUser for another provider: %v", err)
+	}
+	if other.ID == user.ID {
+		t.Fatalf("expected users to be keyed by provider and subject")
+	}
+
+	byID, err := store.GetUser(ctx, user.ID)
+	if err != nil || byID.Subject != subject {
+		t.Fatalf("GetUser: %+v, %v", byID, err)
+	}
+	bySubject, err := store.GetUserBySubject(ctx, "corp", subject)
+	if err != nil || bySubject.ID != user.ID {
+		t.Fatalf("GetUserBySubject: %+v, %v", bySubject, err)
+	}
+
+	_, err = store.GetUser(ctx, uuid.New())
+	expectNotFound(t, "GetUser", err)
+	_, err = store.GetUserBySubject(ctx, "unknown", subject)
+	expectNotFound(t, "GetUserBySubject", err)
+
+	users, err := store.ListUsers(ctx)
+	if err != nil || !slices.ContainsFunc(users, func(listed models.User) bool { return listed.ID == user.ID }) {
+		t.Fatalf("ListUsers: %+v, %v", users, err)
+	}
+}
+
+func testSessions(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	expiresAt := time.Now().Add(time.Hour)
+
+	session, err := store.CreateUserSession(ctx, user.ID, "Mozilla/5.0", "203.0.113.7", expiresAt)
+	if err != nil {
+		t.Fatalf("CreateUserSession: %v", err)
+	}
+	if session.UserID != user.ID || session.CreatedAt.IsZero() || session.LastSeenAt.IsZero() ||
+		session.UserAgent != "Mozilla/5.0" || session.IPAddress != "203.0.113.7" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+	if session.ExpiresAt.Sub(expiresAt).Abs() > time.Millisecond {
+		t.Fatalf("expected the session to expire at %v, got %v", expiresAt, session.ExpiresAt)
+	}
+
+	found, err := store.GetUserSession(ctx, session.ID)
+	if err != nil || found == nil || found.UserID != user.ID {
+		t.Fatalf("GetUserSession: %+v, %v", found, err)
+	}
+
+	missing, err := store.GetUserSession(ctx, uuid.New())
+	if err != nil || missing != nil {
+		t.Fatalf("expected a missing session to return nil, got %+v, %v", missing, err)
+	}
+
+	other, _ := store.CreateUserSession(ctx, user.ID, "curl/8.5.0", "198.51.100.1", expiresAt)
+	lastSeenAt := time.Now().Add(time.Minute)
+	if err := store.TouchUserSession(ctx, other.ID, lastSeenAt); err != nil {
+		t.Fatalf("TouchUserSession: %v", err)
+	}
+	sessions, err := store.ListUserSessions(ctx, user.ID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListUserSessions: %+v, %v", sessions, err)
+	}
+	if sessions[0].ID != other.ID || sessions[0].LastSeenAt.Sub(lastSeenAt).Abs() > time.Millisecond {
+		t.Fatalf("expected the most recently seen session first, got %+v", sessions)
+	}
+
+	if err := store.DeleteUserSession(ctx, session.ID); err != nil {
+		t.Fatalf("DeleteUserSession: %v", err)
+	}
+	if found, _ := store.GetUserSession(ctx, session.ID); found != nil {
+		t.Fatalf("expected the session to be deleted")
+	}
+
+	if err := store.DeleteUserSessions(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+	if sessions, _ := store.ListUserSessions(ctx, user.ID); len(sessions) != 0 {
+		t.Fatalf("expected every session of the user to be deleted, got %+v", sessions)
+	}
+}
+
+func testExpiredSessions(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	now := time.Now()
+
+	active, _ := store.CreateUserSession(ctx, user.ID, "", "", now.Add(time.Hour))
+	expired, _ := store.CreateUserSession(ctx, user.ID, "", "", now.Add(-time.Second))
+	idle, _ := store.CreateUserSession(ctx, user.ID, "", "", now.Add(time.Hour))
+	if err := store.TouchUserSession(ctx, idle.ID, now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("TouchUserSession: %v", err)
+	}
+
+	deleted, err := store.DeleteExpiredSessions(ctx, now, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	// Other subtests may share the store, only this user's sessions are
+	// checked precisely.
+	if deleted < 2 {
+		t.Fatalf("expected at least two sessions to be deleted, got %d", deleted)
+	}
+	if found, _ := store.GetUserSession(ctx, active.ID); found == nil {
+		t.Fatalf("expected the active session to be kept")
+	}
+	for _, session := range []*models.Session{expired, idle} {
+		if found, _ := store.GetUserSession(ctx, session.ID); found != nil {
+			t.Fatalf("expected session %s to be deleted", session.ID)
+		}
+	}
+}
+
+func testCredentials(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	username := "user-" + user.ID.String()
+
+	count, err := store.CountCredentials(ctx)
+	if err != nil {
+		t.Fatalf("CountCredentials: %v", err)
+	}
+
+	credentials, err := store.CreateCredentials(ctx, models.Credentials{
+		UserID:       user.ID,
+		Username:     username,
+		PasswordHash: "hash",
+	})
+	if err != nil {
+		t.Fatalf("CreateCredentials: %v", err)
+	}
+	if credentials.UserID != user.ID || credentials.Username != username || credentials.TOTPEnabled ||
+		credentials.CreatedAt.IsZero() {
+		t.Fatalf("unexpected credentials %+v", credentials)
+	}
+	if newCount, _ := store.CountCredentials(ctx); newCount != count+1 {
+		t.Fatalf("expected %d credentials, got %d", count+1, newCount)
+	}
+
+	other := newUser(t, store)
+	if _, err := store.CreateCredentials(ctx, models.Credentials{UserID: other.ID, Username: username}); err == nil {
+		t.Fatalf("expected a duplicate username to be rejected")
+	}
+
+	first, second := newUser(t, store), newUser(t, store)
+	created, err := store.CreateCredentials(ctx, models.Credentials{UserID: first.ID, Username: "first-" + first.ID.String(), FirstAccount: true})
+	if err != nil || !created.FirstAccount {
+		t.Fatalf("CreateCredentials of the first account: %+v, %v", created, err)
+	}
+	if _, err := store.CreateCredentials(ctx, models.Credentials{UserID: second.ID, Username: "first-" + second.ID.String(), FirstAccount: true}); err == nil {
+		t.Fatalf("expected a second first account to be rejected")
+	}
+
+	if err := store.UpdatePassword(ctx, user.ID, "new hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	if err := store.UpdateTOTP(ctx, user.ID, "secret", true); err != nil {
+		t.Fatalf("UpdateTOTP: %v", err)
+	}
+	found, err := store.GetCredentials(ctx, username)
+	if err != nil {
+		t.Fatalf("GetCredentials: %v", err)
+	}
+	if found.PasswordHash != "new hash" || found.TOTPSecret != "secret" || !found.TOTPEnabled {
+		t.Fatalf("unexpected credentials %+v", found)
+	}
+	if found, err := store.GetUserCredentials(ctx, user.ID); err != nil || found.Username != username {
+		t.Fatalf("GetUserCredentials: %+v, %v", found, err)
+	}
+
+	for want := 1; want <= 2; want++ {
+		if failures, err := store.RecordTOTPFailure(ctx, user.ID, time.Now()); err != nil || failures != want {
+			t.Fatalf("RecordTOTPFailure: expected %d failures, got %d, %v", want, failures, err)
+		}
+	}
+	if found, _ := store.GetUserCredentials(ctx, user.ID); found.TOTPFailures != 2 || found.TOTPFailedAt == nil {
+		t.Fatalf("expected the failures to be stored, got %+v", found)
+	}
+	if used, err := store.UseTOTPStep(ctx, user.ID, 100); err != nil || !used {
+		t.Fatalf("UseTOTPStep: %t, %v", used, err)
+	}
+	if found, _ := store.GetUserCredentials(ctx, user.ID); found.TOTPFailures != 0 || found.TOTPFailedAt != nil {
+		t.Fatalf("expected an accepted code to reset the failures, got %+v", found)
+	}
+	for _, step := range []int64{100, 99} {
+		if used, err := store.UseTOTPStep(ctx, user.ID, step); err != nil || used {
+			t.Fatalf("UseTOTPStep(%d): expected the step to be refused, got %t, %v", step, used, err)
+		}
+	}
+	if used, err := store.UseTOTPStep(ctx, user.ID, 101); err != nil || !used {
+		t.Fatalf("UseTOTPStep(101): %t, %v", used, err)
+	}
+	_, err = store.RecordTOTPFailure(ctx, other.ID, time.Now())
+	expectNotFound(t, "RecordTOTPFailure", err)
+
+	_, err = store.GetCredentials(ctx, "missing-"+username)
+	expectNotFound(t, "GetCredentials", err)
+	_, err = store.GetUserCredentials(ctx, other.ID)
+	expectNotFound(t, "GetUserCredentials", err)
+}
+
+func testAuthTokens(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	expiresAt := time.Now().Add(time.Hour)
+	tokenHash := "hash-" + uuid.NewString()
+
+	invite, err := store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenInvite,
+		TokenHash: tokenHash,
+		Email:     "invitee@example.com",
+		CreatedBy: &user.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthToken: %v", err)
+	}
+	if invite.UserID != nil || invite.CreatedBy == nil || *invite.CreatedBy != user.ID ||
+		invite.UsedAt != nil || invite.Email != "invitee@example.com" {
+		t.Fatalf("unexpected invite %+v", invite)
+	}
+	if invite.ExpiresAt.Sub(expiresAt).Abs() > time.Millisecond {
+		t.Fatalf("expected the invite to expire at %v, got %v", expiresAt, invite.ExpiresAt)
+	}
+
+	_, err = store.GetAuthToken(ctx, models.AuthTokenPasswordReset, tokenHash)
+	expectNotFound(t, "GetAuthToken with another kind", err)
+
+	reset, err := store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenPasswordReset,
+		TokenHash: "hash-" + uuid.NewString(),
+		UserID:    &user.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthToken: %v", err)
+	}
+	found, err := store.GetAuthToken(ctx, models.AuthTokenPasswordReset, reset.TokenHash)
+	if err != nil || found.ID != reset.ID || found.UserID == nil || *found.UserID != user.ID {
+		t.Fatalf("GetAuthToken: %+v, %v", found, err)
+	}
+
+	if used, err := store.UseAuthToken(ctx, invite.ID, time.Now()); err != nil || !used {
+		t.Fatalf("UseAuthToken: %v, %v", used, err)
+	}
+	if used, err := store.UseAuthToken(ctx, invite.ID, time.Now()); err != nil || used {
+		t.Fatalf("expected a used token not to be used twice, got %v, %v", used, err)
+	}
+	if found, _ := store.GetAuthToken(ctx, models.AuthTokenInvite, tokenHash); found == nil || found.UsedAt == nil {
+		t.Fatalf("expected the invite to be marked as used, got %+v", found)
+	}
+}
+
+func testAPITokens(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	other := newUser(t, store)
+	tokenHash := "hash-" + uuid.NewString()
+
+	token, err := store.CreateAPIToken(ctx, user.ID, "laptop", tokenHash)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if token.UserID != user.ID || token.Name != "laptop" || token.LastUsedAt != nil || token.CreatedAt.IsZero() {
+		t.Fatalf("unexpected api token %+v", token)
+	}
+	if _, err := store.CreateAPIToken(ctx, other.ID, "ci", "hash-"+uuid.NewString()); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	found, err := store.GetAPIToken(ctx, tokenHash)
+	if err != nil || found.ID != token.ID {
+		t.Fatalf("GetAPIToken: %+v, %v", found, err)
+	}
+	_, err = store.GetAPIToken(ctx, "hash-unknown")
+	expectNotFound(t, "GetAPIToken", err)
+
+	lastUsedAt := time.Now().Add(-time.Minute)
+	if err := store.TouchAPIToken(ctx, token.ID, lastUsedAt); err != nil {
+		t.Fatalf("TouchAPIToken: %v", err)
+	}
+	tokens, err := store.ListAPITokens(ctx, user.ID)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil || tokens[0].LastUsedAt.Sub(lastUsedAt).Abs() > time.Millisecond {
+		t.Fatalf("ListAPITokens: %+v, %v", tokens, err)
+	}
+
+	expectNotFound(t, "DeleteAPIToken of another user", store.DeleteAPIToken(ctx, other.ID, token.ID))
+	if err := store.DeleteAPIToken(ctx, user.ID, token.ID); err != nil {
+		t.Fatalf("DeleteAPIToken: %v", err)
+	}
+	_, err = store.GetAPIToken(ctx, tokenHash)
+	expectNotFound(t, "GetAPIToken after DeleteAPIToken", err)
+}
+
+func testConfigClients(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+	config := newConfig(t, store, project.ID)
+	other := newConfig(t, store, project.ID)
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	limit := 10
+	client, err := store.CreateConfigClient(ctx, models.ConfigClient{
+		ConfigID: config.ID, Name: "billing", SecretHash: "hash-" + uuid.NewString(),
+		ExpiresAt: &expiresAt, LimitNumberOfRequests: &limit,
+	})
+	if err != nil {
+		t.Fatalf("CreateConfigClient: %v", err)
+	}
+	if client.ConfigID != config.ID || client.Name != "billing" || client.LastUsedAt != nil || client.CreatedAt.IsZero() ||
+		client.ExpiresAt == nil || client.ExpiresAt.Sub(expiresAt).Abs() > time.Millisecond ||
+		client.LimitNumberOfRequests == nil || *client.LimitNumberOfRequests != 10 {
+		t.Fatalf("unexpected config client %+v", client)
+	}
+	unlimited, err := store.CreateConfigClient(ctx, models.ConfigClient{ConfigID: config.ID, Name: "reports", SecretHash: "hash-" + uuid.NewString()})
+	if err != nil || unlimited.ExpiresAt != nil || unlimited.LimitNumberOfRequests != nil {
+		t.Fatalf("CreateConfigClient without expiry and limit: %+v, %v", unlimited, err)
+	}
+
+	clients, err := store.ListConfigClients(ctx, config.ID)
+	if err != nil || len(clients) != 2 || clients[0].ID != client.ID || clients[1].ID != unlimited.ID {
+		t.Fatalf("ListConfigClients: %+v, %v", clients, err)
+	}
+	configs, err := store.ListConfigs(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListConfigs: %v", err)
+	}
+	for _, listed := range configs {
+		if listed.ID == config.ID && len(listed.Clients) != 2 || listed.ID == other.ID && len(listed.Clients) != 0 {
+			t.Fatalf("expected ListConfigs to load the clients of each config, got %+v", listed)
+		}
+	}
+
+	expectNotFound(t, "DeleteConfigClient of another config", store.DeleteConfigClient(ctx, other.ID, client.ID))
+	if err := store.DeleteConfigClient(ctx, config.ID, client.ID); err != nil {
+		t.Fatalf("DeleteConfigClient: %v", err)
+	}
+	if clients, err := store.ListConfigClients(ctx, config.ID); err != nil || len(clients) != 1 {
+		t.Fatalf("ListConfigClients after DeleteConfigClient: %+v, %v", clients, err)
+	}
+
+	if err := store.DeleteConfig(ctx, config.ID); err != nil {
+		t.Fatalf("DeleteConfig: %v", err)
+	}
+	if clients, err := store.ListConfigClients(ctx, config.ID); err != nil || len(clients) != 0 {
+		t.Fatalf("expected the clients to be deleted with the config, got %+v, %v", clients, err)
+	}
+}
+
+func testProjects(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	other := newUser(t, store)
+	project := newProject(t, store, user.ID)
+	newProject(t, store, other.ID)
+
+	if project.Name != "project" || project.Description != "description" ||
+		project.AccessKey != "access key" || project.UserID != user.ID {
+		t.Fatalf("unexpected project %+v", project)
+	}
+
+	found, err := store.GetProject(ctx, project.ID)
+	if err != nil || found.Name != "project" {
+		t.Fatalf("GetProject: %+v, %v", found, err)
+	}
+
+	if err := store.UpdateProjectAccessKey(ctx, project.ID, "rotated"); err != nil {
+		t.Fatalf("UpdateProjectAccessKey: %v", err)
+	}
+	found, _ = store.GetProject(ctx, project.ID)
+	if found.AccessKey != "rotated" {
+		t.Fatalf("expected the access key to be rotated, got %q", found.AccessKey)
+	}
+	if err := store.UpdateProjectDescription(ctx, project.ID, "updated"); err != nil {
+		t.Fatalf("UpdateProjectDescription: %v", err)
+	}
+	if found, _ = store.GetProject(ctx, project.ID); found.Description != "updated" {
+		t.Fatalf("expected the description to be updated, got %q", found.Description)
+	}
+
+	projects, err := store.ListProjects(ctx, user.ID, database.ProjectListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(projects) != 1 || projects[0].ID != project.ID {
+		t.Fatalf("expected only the user's project, got %+v", projects)
+	}
+
+	if err := store.UpdateProjectOwner(ctx, project.ID, other.ID); err != nil {
+		t.Fatalf("UpdateProjectOwner: %v", err)
+	}
+	if count, _ := store.CountProjects(ctx, other.ID); count != 2 {
+		t.Fatalf("expected the project to be transferred, got %d projects", count)
+	}
+	if count, _ := store.CountProjects(ctx, user.ID); count != 0 {
+		t.Fatalf("expected the project to leave its owner, got %d projects", count)
+	}
+	counts, err := store.CountProjectsByUser(ctx)
+	if err != nil || counts[other.ID] != 2 || counts[user.ID] != 0 {
+		t.Fatalf("CountProjectsByUser: %v, %v", counts, err)
+	}
+
+	if err := store.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	_, err = store.GetProject(ctx, project.ID)
+	expectNotFound(t, "GetProject", err)
+}
+
+func testProjectPagination(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	for i := 0; i < 5; i++ {
+		project := newProject(t, store, user.ID)
+		newConfig(t, store, project.ID)
+	}
+
+	count, err := store.CountProjects(ctx, user.ID)
+	if err != nil || count != 5 {
+		t.Fatalf("CountProjects: %d, %v", count, err)
+	}
+	configs, err := store.CountUserConfigs(ctx, user.ID)
+	if err != nil || configs != 5 {
+		t.Fatalf("CountUserConfigs: %d, %v", configs, err)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for offset := 0; offset < 6; offset += 2 {
+		page, err := store.ListProjects(ctx, user.ID, database.ProjectListOptions{
+			Limit: 2, Offset: offset, WithConfigs: true,
+		})
+		if err != nil {
+			t.Fatalf("ListProjects: %v", err)
+		}
+		if want := min(2, 5-offset); len(page) != want {
+			t.Fatalf("expected %d projects at offset %d, got %d", want, offset, len(page))
+		}
+		for _, project := range page {
+			if seen[project.ID] {
+				t.Fatalf("project %s listed twice", project.ID)
+			}
+			seen[project.ID] = true
+			if len(project.Configs) != 1 {
+				t.Fatalf("expected the project configs to be loaded, got %+v", project.Configs)
+			}
+		}
+	}
+
+	page, err := store.ListProjects(ctx, user.ID, database.ProjectListOptions{Limit: 5})
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	for _, project := range page {
+		if len(project.Configs) != 0 {
+			t.Fatalf("expected configs not to be loaded without WithConfigs")
+		}
+	}
+}
+
+func testConfigs(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+
+	config, err := store.CreateConfig(ctx, project.ID, "config", 10, "hour")
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	if config.ProjectID != project.ID || config.Name != "config" ||
+		config.LimitNumberOfRequests != 10 || config.LimitPer != "hour" {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	if _, err := store.CreateHeaderReplacement(ctx, config.ID, "Authorization", "secret"); err != nil {
+		t.Fatalf("CreateHeaderReplacement: %v", err)
+	}
+	if _, err := store.CreateAlert(ctx, config.ID, models.AlertKindQuota, 80, 0,
+		models.AlertChannelEmail, "ops@example.com"); err != nil {
+		t.Fatalf("CreateAlert: %v", err)
+	}
+
+	configs, err := store.ListConfigs(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListConfigs: %v", err)
+	}
+	if len(configs) != 1 || len(configs[0].HeaderReplacements) != 1 || len(configs[0].Alerts) != 1 {
+		t.Fatalf("expected the config with its headers and alerts, got %+v", configs)
+	}
+
+	if err := store.UpdateConfigLimit(ctx, config.ID, 20, "day"); err != nil {
+		t.Fatalf("UpdateConfigLimit: %v", err)
+	}
+	if found, err := store.GetConfig(ctx, config.ID); err != nil || found.LimitNumberOfRequests != 20 || found.LimitPer != "day" {
+		t.Fatalf("expected the limit to be updated, got %+v, %v", found, err)
+	}
+
+	if err := store.DeleteConfig(ctx, config.ID); err != nil {
+		t.Fatalf("DeleteConfig: %v", err)
+	}
+	_, err = store.GetConfig(ctx, config.ID)
+	expectNotFound(t, "GetConfig", err)
+}
+
+func testConfigAvailability(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+	config := newConfig(t, store, project.ID)
+	expired := newConfig(t, store, project.ID)
+	offSchedule := newConfig(t, store, project.ID)
+
+	if served, err := store.IsConfigServed(ctx, config.ID); err != nil || !served {
+		t.Fatalf("expected a new config to be served, got %v, %v", served, err)
+	}
+
+	now := time.Now()
+	activeFrom := now.Add(-time.Hour)
+	expiresAt := now.Add(24 * time.Hour)
+	if err := store.UpdateConfigAvailability(ctx, config.ID, &activeFrom, &expiresAt); err != nil {
+		t.Fatalf("UpdateConfigAvailability: %v", err)
+	}
+	tomorrow := now.UTC().Add(24 * time.Hour).Weekday()
+	windows := []models.ScheduleWindow{
+		{Weekday: now.UTC().Weekday(), StartMinute: 0, EndMinute: 24 * 60},
+		{Weekday: tomorrow, StartMinute: 9 * 60, EndMinute: 17 * 60},
+	}
+	if err := store.SetConfigSchedule(ctx, config.ID, windows); err != nil {
+		t.Fatalf("SetConfigSchedule: %v", err)
+	}
+	got, err := store.GetConfig(ctx, config.ID)
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if got.ActiveFrom == nil || got.ActiveFrom.Sub(activeFrom).Abs() > time.Millisecond ||
+		got.ExpiresAt == nil || got.ExpiresAt.Sub(expiresAt).Abs() > time.Millisecond ||
+		got.Disabled != nil || len(got.Schedule) != 2 {
+		t.Fatalf("unexpected config availability %+v", got)
+	}
+	if served, err := store.IsConfigServed(ctx, config.ID); err != nil || !served {
+		t.Fatalf("expected the config to be served within its schedule, got %v, %v", served, err)
+	}
+
+	if err := store.SetConfigSchedule(ctx, offSchedule.ID, []models.ScheduleWindow{{Weekday: tomorrow, StartMinute: 0, EndMinute: 24 * 60}}); err != nil {
+		t.Fatalf("SetConfigSchedule: %v", err)
+	}
+	if served, err := store.IsConfigServed(ctx, offSchedule.ID); err != nil || served {
+		t.Fatalf("expected a config off schedule not to be served, got %v, %v", served, err)
+	}
+	if err := store.SetConfigSchedule(ctx, offSchedule.ID, nil); err != nil {
+		t.Fatalf("SetConfigSchedule: %v", err)
+	}
+	if got, err := store.GetConfig(ctx, offSchedule.ID); err != nil || len(got.Schedule) != 0 {
+		t.Fatalf("expected SetConfigSchedule to clear the schedule, got %+v, %v", got, err)
+	}
+
+	expiredAt := now.Add(-time.Minute)
+	if err := store.UpdateConfigAvailability(ctx, expired.ID, nil, &expiredAt); err != nil {
+		t.Fatalf("UpdateConfigAvailability: %v", err)
+	}
+	if served, err := store.IsConfigServed(ctx, expired.ID); err != nil || served {
+		t.Fatalf("expected an expired config not to be served, got %v, %v", served, err)
+	}
+	configs, err := store.ListExpiredConfigs(ctx, now)
+	if err != nil {
+		t.Fatalf("ListExpiredConfigs: %v", err)
+	}
+	if !slices.ContainsFunc(configs, func(c models.Config) bool { return c.ID == expired.ID }) ||
+		slices.ContainsFunc(configs, func(c models.Config) bool { return c.ID == config.ID }) {
+		t.Fatalf("expected ListExpiredConfigs to list the expired config only, got %+v", configs)
+	}
+
+	onExpiry := models.Disablement{At: now, Reason: models.DisabledReasonExpired}
+	if disabled, err := store.DisableConfig(ctx, expired.ID, onExpiry); err != nil || !disabled {
+		t.Fatalf("DisableConfig: %v, %v", disabled, err)
+	}
+	configs, err = store.ListExpiredConfigs(ctx, now)
+	if err != nil || slices.ContainsFunc(configs, func(c models.Config) bool { return c.ID == expired.ID }) {
+		t.Fatalf("expected ListExpiredConfigs to leave out disabled configs, got %+v, %v", configs, err)
+	}
+	if got, err := store.GetConfig(ctx, expired.ID); err != nil || got.Disabled == nil || got.Disabled.Reason != models.DisabledReasonExpired {
+		t.Fatalf("expected DisableConfig to set Disabled, got %+v, %v", got, err)
+	}
+	if disabled, err := store.DisableConfig(ctx, config.ID, onExpiry); err != nil || !disabled {
+		t.Fatalf("DisableConfig: %v, %v", disabled, err)
+	}
+	if served, err := store.IsConfigServed(ctx, config.ID); err != nil || served {
+		t.Fatalf("expected a disabled config not to be served, got %v, %v", served, err)
+	}
+	if err := store.UpdateConfigAvailability(ctx, config.ID, nil, nil); err != nil {
+		t.Fatalf("UpdateConfigAvailability: %v", err)
+	}
+	if got, err := store.GetConfig(ctx, config.ID); err != nil || got.Disabled != nil || got.ActiveFrom != nil || got.ExpiresAt != nil {
+		t.Fatalf("expected UpdateConfigAvailability to enable the expired config again, got %+v, %v", got, err)
+	}
+
+	if err := store.DeleteConfig(ctx, offSchedule.ID); err != nil {
+		t.Fatalf("DeleteConfig: %v", err)
+	}
+	configs, err = store.ListConfigs(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListConfigs: %v", err)
+	}
+	for _, listed := range configs {
+		if listed.ID == config.ID && len(listed.Schedule) != 2 {
+			t.Fatalf("expected ListConfigs to load the schedule of each config, got %+v", listed)
+		}
+	}
+}
+
+func testDisabledState(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+	config := newConfig(t, store, project.ID)
+	other := newConfig(t, store, project.ID)
+
+	now := time.Now()
+	incident := models.Disablement{At: now, Reason: "leaked key", By: &user.ID}
+	if disabled, err := store.DisableConfig(ctx, config.ID, incident); err != nil || !disabled {
+		t.Fatalf("DisableConfig: %v, %v", disabled, err)
+	}
+	onExpiry := models.Disablement{At: now.Add(time.Minute), Reason: models.DisabledReasonExpired}
+	if disabled, err := store.DisableConfig(ctx, config.ID, onExpiry); err != nil || disabled {
+		t.Fatalf("expected DisableConfig to leave a disabled config alone, got %v, %v", disabled, err)
+	}
+	got, err := store.GetConfig(ctx, config.ID)
+	if err != nil || got.Disabled == nil || got.Disabled.Reason != "leaked key" || got.Disabled.By == nil ||
+		*got.Disabled.By != user.ID || got.Disabled.At.Sub(now).Abs() > time.Millisecond {
+		t.Fatalf("unexpected disabled config %+v, %v", got, err)
+	}
+	if served, err := store.IsConfigServed(ctx, config.ID); err != nil || served {
+		t.Fatalf("expected a disabled config not to be served, got %v, %v", served, err)
+	}
+	if err := store.UpdateConfigAvailability(ctx, config.ID, nil, nil); err != nil {
+		t.Fatalf("UpdateConfigAvailability: %v", err)
+	}
+	if got, err := store.GetConfig(ctx, config.ID); err != nil || got.Disabled == nil {
+		t.Fatalf("expected UpdateConfigAvailability to keep a config disabled by hand, got %+v, %v", got, err)
+	}
+	if err := store.EnableConfig(ctx, config.ID); err != nil {
+		t.Fatalf("EnableConfig: %v", err)
+	}
+	if served, err := store.IsConfigServed(ctx, config.ID); err != nil || !served {
+		t.Fatalf("expected an enabled config to be served, got %v, %v", served, err)
+	}
+
+	if disabled, err := store.DisableProject(ctx, project.ID, incident); err != nil || !disabled {
+		t.Fatalf("DisableProject: %v, %v", disabled, err)
+	}
+	if disabled, err := store.DisableProject(ctx, project.ID, onExpiry); err != nil || disabled {
+		t.Fatalf("expected DisableProject to leave a disabled project alone, got %v, %v", disabled, err)
+	}
+	if got, err := store.GetProject(ctx, project.ID); err != nil || got.Disabled == nil || got.Disabled.Reason != "leaked key" ||
+		got.Disabled.By == nil || *got.Disabled.By != user.ID {
+		t.Fatalf("unexpected disabled project %+v, %v", got, err)
+	}
+	projects, err := store.ListProjects(ctx, user.ID, database.ProjectListOptions{})
+	if err != nil || len(projects) != 1 || projects[0].Disabled == nil {
+		t.Fatalf("expected ListProjects to load the disabled state, got %+v, %v", projects, err)
+	}
+	for _, id := range []uuid.UUID{config.ID, other.ID} {
+		if served, err := store.IsConfigServed(ctx, id); err != nil || served {
+			t.Fatalf("expected the configs of a disabled project not to be served, got %v, %v", served, err)
+		}
+	}
+	if got, err := store.GetConfig(ctx, other.ID); err != nil || got.Disabled != nil {
+		t.Fatalf("expected the configs to keep their own state, got %+v, %v", got, err)
+	}
+	if err := store.EnableProject(ctx, project.ID); err != nil {
+		t.Fatalf("EnableProject: %v", err)
+	}
+	if served, err := store.IsConfigServed(ctx, other.ID); err != nil || !served {
+		t.Fatalf("expected the configs of an enabled project to be served, got %v, %v", served, err)
+	}
+}
+
+func testHeaderReplacements(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	config := newConfig(t, store, newProject(t, store, user.ID).ID)
+
+	header, err := store.CreateHeaderReplacement(ctx, config.ID, "X-Api-Key", "value")
+	if err != nil {
+		t.Fatalf("CreateHeaderReplacement: %v", err)
+	}
+	if header.ConfigID != config.ID || header.HeaderName != "X-Api-Key" || header.HeaderValue != "value" {
+		t.Fatalf("unexpected header replacement %+v", header)
+	}
+
+	found, err := store.GetHeaderReplacement(ctx, header.ID)
+	if err != nil || found.HeaderName != "X-Api-Key" {
+		t.Fatalf("GetHeaderReplacement: %+v, %v", found, err)
+	}
+	headers, err := store.ListHeaderReplacements(ctx, config.ID)
+	if err != nil || len(headers) != 1 {
+		t.Fatalf("ListHeaderReplacements: %+v, %v", headers, err)
+	}
+	if sample, err := store.SampleHeaderValue(ctx); err != nil || sample == "" {
+		t.Fatalf("SampleHeaderValue: %q, %v", sample, err)
+	}
+	if all, err := store.ListAllHeaderReplacements(ctx); err != nil ||
+		!slices.ContainsFunc(all, func(listed models.HeaderReplacement) bool { return listed.ID == header.ID }) {
+		t.Fatalf("ListAllHeaderReplacements: %+v, %v", all, err)
+	}
+
+	if err := store.UpdateHeaderReplacementValue(ctx, header.ID, "rotated"); err != nil {
+		t.Fatalf("UpdateHeaderReplacementValue: %v", err)
+	}
+	if found, _ := store.GetHeaderReplacement(ctx, header.ID); found.HeaderValue != "rotated" {
+		t.Fatalf("expected the header value to be updated, got %q", found.HeaderValue)
+	}
+
+	if err := store.DeleteHeaderReplacement(ctx, header.ID); err != nil {
+		t.Fatalf("DeleteHeaderReplacement: %v", err)
+	}
+	_, err = store.GetHeaderReplacement(ctx, header.ID)
+	expectNotFound(t, "GetHeaderReplacement", err)
+}
+
+func testAlerts(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	config := newConfig(t, store, newProject(t, store, user.ID).ID)
+
+	alert, err := store.CreateAlert(ctx, config.ID, models.AlertKindRejections, 5, 15,
+		models.AlertChannelWebhook, "https://example.com/hook")
+	if err != nil {
+		t.Fatalf("CreateAlert: %v", err)
+	}
+	if alert.State != models.AlertStateOK || alert.LastEvaluatedAt != nil || alert.LastTriggeredAt != nil {
+		t.Fatalf("expected a new alert to be ok and never evaluated, got %+v", alert)
+	}
+
+	evaluatedAt := time.Now()
+	if err := store.UpdateAlertState(ctx, alert.ID, models.AlertStateFiring, 7, evaluatedAt, &evaluatedAt); err != nil {
+		t.Fatalf("UpdateAlertState: %v", err)
+	}
+	// A nil trigger time keeps the previous one.
+	if err := store.UpdateAlertState(ctx, alert.ID, models.AlertStateFiring, 8, evaluatedAt, nil); err != nil {
+		t.Fatalf("UpdateAlertState: %v", err)
+	}
+	found, err := store.GetAlert(ctx, alert.ID)
+	if err != nil {
+		t.Fatalf("GetAlert: %v", err)
+	}
+	if found.State != models.AlertStateFiring || found.LastValue != 8 {
+		t.Fatalf("unexpected alert state %+v", found)
+	}
+	if found.LastTriggeredAt == nil || found.LastTriggeredAt.Sub(evaluatedAt).Abs() > time.Millisecond {
+		t.Fatalf("expected the trigger time to be kept, got %v", found.LastTriggeredAt)
+	}
+
+	alerts, err := store.ListAlerts(ctx, config.ID)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("ListAlerts: %+v, %v", alerts, err)
+	}
+	all, err := store.ListAllAlerts(ctx)
+	if err != nil {
+		t.Fatalf("ListAllAlerts: %v", err)
+	}
+	listed := false
+	for _, a := range all {
+		listed = listed || a.ID == alert.ID
+	}
+	if !listed {
+		t.Fatalf("expected ListAllAlerts to include the alert")
+	}
+
+	requests, rejected, err := store.CountConfigUsage(ctx, config.ID, time.Now().Add(-time.Hour))
+	if err != nil || requests != 0 || rejected != 0 {
+		t.Fatalf("CountConfigUsage: %d, %d, %v", requests, rejected, err)
+	}
+
+	if err := store.DeleteAlert(ctx, alert.ID); err != nil {
+		t.Fatalf("DeleteAlert: %v", err)
+	}
+	_, err = store.GetAlert(ctx, alert.ID)
+	expectNotFound(t, "GetAlert", err)
+}
+
+func testWebhooks(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+
+	events := []string{models.EventConfigCreated, models.EventConfigDeleted}
+	webhook, err := store.CreateWebhook(ctx, project.ID, "https://example.com/hook", "secret", events)
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if webhook.ProjectID != project.ID || webhook.URL != "https://example.com/hook" || webhook.Secret != "secret" {
+		t.Fatalf("unexpected webhook %+v", webhook)
+	}
+
+	found, err := store.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if !found.Subscribes(models.EventConfigCreated) || found.Subscribes(models.EventProjectDeleted) {
+		t.Fatalf("unexpected webhook events %v", found.Events)
+	}
+
+	webhooks, err := store.ListWebhooks(ctx, project.ID)
+	if err != nil || len(webhooks) != 1 {
+		t.Fatalf("ListWebhooks: %+v, %v", webhooks, err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		delivery, err := store.CreateWebhookDelivery(ctx, models.WebhookDelivery{
+			WebhookID:  webhook.ID,
+			EventID:    uuid.New(),
+			Event:      models.EventConfigCreated,
+			Attempt:    attempt,
+			StatusCode: 500,
+			Error:      "server error",
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookDelivery: %v", err)
+		}
+		if delivery.Attempt != attempt || delivery.CreatedAt.IsZero() {
+			t.Fatalf("unexpected delivery %+v", delivery)
+		}
+	}
+	deliveries, err := store.ListWebhookDeliveries(ctx, webhook.ID, 2)
+	if err != nil || len(deliveries) != 2 {
+		t.Fatalf("ListWebhookDeliveries: %+v, %v", deliveries, err)
+	}
+
+	if err := store.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	_, err = store.GetWebhook(ctx, webhook.ID)
+	expectNotFound(t, "GetWebhook", err)
+}
+
+func testCascadingDeletes(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+	config := newConfig(t, store, project.ID)
+	header, _ := store.CreateHeaderReplacement(ctx, config.ID, "name", "value")
+	alert, _ := store.CreateAlert(ctx, config.ID, models.AlertKindQuota, 90, 0, models.AlertChannelEmail, "a@example.com")
+	webhook, _ := store.CreateWebhook(ctx, project.ID, "https://example.com", "secret", models.WebhookEvents)
+
+	if err := store.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+
+	_, err := store.GetConfig(ctx, config.ID)
+	expectNotFound(t, "GetConfig", err)
+	_, err = store.GetHeaderReplacement(ctx, header.ID)
+	expectNotFound(t, "GetHeaderReplacement", err)
+	_, err = store.GetAlert(ctx, alert.ID)
+	expectNotFound(t, "GetAlert", err)
+	_, err = store.GetWebhook(ctx, webhook.ID)
+	expectNotFound(t, "GetWebhook", err)
+}
+
+func testResourceCounts(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	before, err := store.CountResources(ctx)
+	if err != nil {
+		t.Fatalf("CountResources: %v", err)
+	}
+
+	user := newUser(t, store)
+	config := newConfig(t, store, newProject(t, store, user.ID).ID)
+	if _, err := store.CreateHeaderReplacement(ctx, config.ID, "X-Api-Key", "value"); err != nil {
+		t.Fatalf("CreateHeaderReplacement: %v", err)
+	}
+
+	after, err := store.CountResources(ctx)
+	if err != nil {
+		t.Fatalf("CountResources: %v", err)
+	}
+	expected := models.ResourceCounts{
+		Users:              before.Users + 1,
+		Projects:           before.Projects + 1,
+		Configs:            before.Configs + 1,
+		HeaderReplacements: before.HeaderReplacements + 1,
+	}
+	if *after != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *after)
+	}
+}
+
+func testTransactionCommit(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+
+	var configID uuid.UUID
+	err := store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		config, err := tx.CreateConfig(ctx, project.ID, "config", 1, "second")
+		if err != nil {
+			return err
+		}
+		configID = config.ID
+		// Nested transactions join the outer one.
+		return tx.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+			_, err := tx.CreateHeaderReplacement(ctx, config.ID, "name", "value")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+
+	headers, err := store.ListHeaderReplacements(ctx, configID)
+	if err != nil || len(headers) != 1 {
+		t.Fatalf("expected the transaction to be committed, got %+v, %v", headers, err)
+	}
+}
+
+func testTransactionRollback(t *testing.T, store database.Store) {
+	ctx := context.Background()
+	user := newUser(t, store)
+	project := newProject(t, store, user.ID)
+
+	rollback := errors.New("rollback")
+	err := store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if _, err := tx.CreateConfig(ctx, project.ID, "config", 1, "second"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected the callback error to be returned, got %v", err)
+	}
+
+	configs, err := store.ListConfigs(ctx, project.ID)
+	if err != nil || len(configs) != 0 {
+		t.Fatalf("expected the transaction to be rolled back, got %+v, %v", configs, err)
+	}
+}
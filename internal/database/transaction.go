@@ -0,0 +1,124 @@
+package database
+
+import (
+	"configuration-management/internal/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrOutsideTransaction is returned when fn of Transaction uses another
+// store than the one it receives. The query would wait for the connection
+// held by the transaction, with SQLite and its single connection forever.
+var ErrOutsideTransaction = errors.New("store used outside of the open transaction")
+
+// transactionKey marks the context passed to fn of Transaction with the
+// database of the transaction.
+type transactionKey struct{}
+
+func inTransaction(ctx context.Context, db *sql.DB) bool {
+	open, _ := ctx.Value(transactionKey{}).(*sql.DB)
+	return open != nil && open == db
+}
+
+// queryer is implemented by txConn and dbConn.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) rowScanner
+}
+
+func (s *DatabaseHandler) conn() queryer {
+	if s.tx != nil {
+		return txConn{s.tx}
+	}
+	return dbConn{s.DB}
+}
+
+type txConn struct {
+	*sql.Tx
+}
+
+func (c txConn) QueryRowContext(ctx context.Context, query string, args ...any) rowScanner {
+	return c.Tx.QueryRowContext(ctx, query, args...)
+}
+
+// dbConn runs queries outside of a transaction, it refuses the context of
+// a transaction open on the same database.
+type dbConn struct {
+	*sql.DB
+}
+
+func (c dbConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if inTransaction(ctx, c.DB) {
+		return nil, ErrOutsideTransaction
+	}
+	return c.DB.ExecContext(ctx, query, args...)
+}
+
+func (c dbConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if inTransaction(ctx, c.DB) {
+		return nil, ErrOutsideTransaction
+	}
+	return c.DB.QueryContext(ctx, query, args...)
+}
+
+func (c dbConn) QueryRowContext(ctx context.Context, query string, args ...any) rowScanner {
+	if inTransaction(ctx, c.DB) {
+		return errRow{ErrOutsideTransaction}
+	}
+	return c.DB.QueryRowContext(ctx, query, args...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
+
+// Transaction runs fn in a database transaction. fn must use the store and
+// the context it receives for every operation, queries through another
+// store with that context fail with ErrOutsideTransaction. The transaction
+// is committed when fn returns nil and rolled back otherwise. Transactions
+// do not nest, calling Transaction on the store passed to fn runs fn in the
+// already open transaction.
+func (s *DatabaseHandler) Transaction(ctx context.Context, fn func(ctx context.Context, tx Store) error) (err error) {
+	if s.tx != nil {
+		return fn(ctx, s)
+	}
+	if inTransaction(ctx, s.DB) {
+		return ErrOutsideTransaction
+	}
+
+	ctx, span := tracing.Start(ctx, "store.Transaction")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txCtx := context.WithValue(ctx, transactionKey{}, s.DB)
+	if err := fn(txCtx, &DatabaseHandler{DB: s.DB, Driver: s.Driver, QueryTimeout: s.QueryTimeout, tx: tx}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
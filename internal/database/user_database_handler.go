@@ -2,76 +2,127 @@ package database
 
 import (
 	"configuration-management/internal/models"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-func (s *DatabaseHandler) CreateUser(oauth2ID int, name string, avatarUrl string) (*models.User, error) {
+func (s *DatabaseHandler) CreateUser(ctx context.Context, user models.User) (_ *models.User, err error) {
+	ctx, end := s.startQuery(ctx, "CreateUser")
+	defer func() { end(err) }()
+
 	query := `
-		INSERT INTO users (oauth2_id, name, avatarUrl)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (oauth2_id) DO NOTHING;
+		INSERT INTO users (provider, subject, name, email, avatarUrl)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, subject) DO NOTHING;
 	`
 
-	if _, err := s.DB.Exec(query, oauth2ID, name, avatarUrl); err != nil {
+	if _, err := s.conn().ExecContext(ctx, query, user.Provider, user.Subject, user.Name, user.Email, user.AvatarUrl); err != nil {
 		return nil, fmt.Errorf("failed to create user: %v", err)
 	}
 
-	return s.GetUserByOAuth2ID(oauth2ID)
+	return s.GetUserBySubject(ctx, user.Provider, user.Subject)
 }
 
-func (s *DatabaseHandler) GetUserByOAuth2ID(oauth2ID int) (*models.User, error) {
+func (s *DatabaseHandler) GetUserBySubject(ctx context.Context, provider string, subject string) (_ *models.User, err error) {
+	ctx, end := s.startQuery(ctx, "GetUserBySubject")
+	defer func() { end(err) }()
+
 	query := `
-		SELECT id, oauth2_id, name, avatarUrl FROM users WHERE oauth2_id=$1;
+		SELECT ` + userColumns + ` FROM users WHERE provider = $1 AND subject = $2;
 	`
 
 	var user models.User
-	if err := s.DB.QueryRow(query, oauth2ID).Scan(
-		&user.ID, &user.OAuth2ID, &user.Name, &user.AvatarUrl); err != nil {
-		return nil, fmt.Errorf("failed to get user: %v", err)
+	if err := scanUser(s.conn().QueryRowContext(ctx, query, provider, subject), &user); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", notFound(err))
 	}
 
 	return &user, nil
 }
 
-func (s *DatabaseHandler) GetUser(userID uuid.UUID) (*models.User, error) {
+func (s *DatabaseHandler) GetUser(ctx context.Context, userID uuid.UUID) (_ *models.User, err error) {
+	ctx, end := s.startQuery(ctx, "GetUser")
+	defer func() { end(err) }()
+
 	query := `
-		SELECT id, oauth2_id, name, avatarUrl FROM users WHERE id=$1;
+		SELECT ` + userColumns + ` FROM users WHERE id=$1;
 	`
 
 	var user models.User
-	if err := s.DB.QueryRow(query, userID).Scan(
-		&user.ID, &user.OAuth2ID, &user.Name, &user.AvatarUrl); err != nil {
-		return nil, fmt.Errorf("failed to get user: %v", err)
+	if err := scanUser(s.conn().QueryRowContext(ctx, query, userID), &user); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", notFound(err))
 	}
 
 	return &user, nil
 }
 
-func (s *DatabaseHandler) CreateUserSession(userID uuid.UUID) (*models.Session, error) {
+func (s *DatabaseHandler) ListUsers(ctx context.Context) (_ []models.User, err error) {
+	ctx, end := s.startQuery(ctx, "ListUsers")
+	defer func() { end(err) }()
+
 	query := `
-		INSERT INTO user_sessions (user_id) VALUES ($1)
-		RETURNING id, user_id, created_at
+		SELECT ` + userColumns + ` FROM users ORDER BY provider, subject
 	`
+	rows, err := s.conn().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %v", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query users: %v", err)
+	}
+
+	return users, nil
+}
+
+const userColumns = "id, provider, subject, name, email, avatarUrl"
+
+func scanUser(row rowScanner, user *models.User) error {
+	return row.Scan(&user.ID, &user.Provider, &user.Subject, &user.Name, &user.Email, &user.AvatarUrl)
+}
+
+func (s *DatabaseHandler) CreateUserSession(ctx context.Context, userID uuid.UUID, userAgent string,
+	ipAddress string, expiresAt time.Time) (_ *models.Session, err error) {
+	ctx, end := s.startQuery(ctx, "CreateUserSession")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO user_sessions (user_id, user_agent, ip_address, expires_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + sessionColumns
+	row := s.conn().QueryRowContext(ctx, query, userID, userAgent, ipAddress, expiresAt.UTC(), time.Now().UTC())
 
 	var session models.Session
-	if err := s.DB.QueryRow(query, userID).Scan(&session.ID, &session.UserID, &session.CreatedAt); err != nil {
+	if err := scanSession(row, &session); err != nil {
 		return nil, fmt.Errorf("failed to create user session: %v", err)
 	}
 
 	return &session, nil
 }
 
-func (s *DatabaseHandler) GetUserSession(sessionID uuid.UUID) (*models.Session, error) {
+func (s *DatabaseHandler) GetUserSession(ctx context.Context, sessionID uuid.UUID) (_ *models.Session, err error) {
+	ctx, end := s.startQuery(ctx, "GetUserSession")
+	defer func() { end(err) }()
+
 	query := `
-		SELECT id, user_id, created_at FROM user_sessions WHERE id = $1 
+		SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1
 	`
 
 	var session models.Session
-	if err := s.DB.QueryRow(query, sessionID).Scan(&session.ID, &session.UserID, &session.CreatedAt); err != nil {
+	if err := scanSession(s.conn().QueryRowContext(ctx, query, sessionID), &session); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -80,3 +131,104 @@ func (s *DatabaseHandler) GetUserSession(sessionID uuid.UUID) (*models.Session,
 
 	return &session, nil
 }
+
+func (s *DatabaseHandler) ListUserSessions(ctx context.Context, userID uuid.UUID) (_ []models.Session, err error) {
+	ctx, end := s.startQuery(ctx, "ListUserSessions")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM user_sessions
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := s.conn().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user sessions: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, fmt.Errorf("failed to scan user session row: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user sessions: %v", err)
+	}
+
+	return sessions, nil
+}
+
+func (s *DatabaseHandler) TouchUserSession(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) (err error) {
+	ctx, end := s.startQuery(ctx, "TouchUserSession")
+	defer func() { end(err) }()
+
+	query := `
+		UPDATE user_sessions SET last_seen_at = $2 WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, sessionID, lastSeenAt.UTC()); err != nil {
+		return fmt.Errorf("failed to update user session: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteUserSession(ctx context.Context, sessionID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteUserSession")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM user_sessions WHERE id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to delete user session: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteUserSessions(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteUserSessions")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM user_sessions WHERE user_id = $1
+	`
+	if _, err := s.conn().ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete user sessions: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time, idleSince time.Time) (_ int, err error) {
+	ctx, end := s.startQuery(ctx, "DeleteExpiredSessions")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM user_sessions WHERE expires_at <= $1 OR last_seen_at < $2
+	`
+	result, err := s.conn().ExecContext(ctx, query, expiredBefore.UTC(), idleSince.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired user sessions: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired user sessions: %v", err)
+	}
+
+	return int(deleted), nil
+}
+
+const sessionColumns = "id, user_id, created_at, expires_at, last_seen_at, user_agent, ip_address"
+
+func scanSession(row rowScanner, session *models.Session) error {
+	return row.Scan(
+		&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt,
+		&session.LastSeenAt, &session.UserAgent, &session.IPAddress,
+	)
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"configuration-management/internal/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func (s *DatabaseHandler) ListWebhooks(ctx context.Context, projectID uuid.UUID) (_ []models.Webhook, err error) {
+	ctx, end := s.startQuery(ctx, "ListWebhooks")
+	defer func() { end(err) }()
+
+	return s.listWebhooksWhere(ctx, "w.project_id = $1", projectID)
+}
+
+// listWebhooksWhere lists the webhooks matching the condition, the webhooks
+// table is aliased as w.
+func (s *DatabaseHandler) listWebhooksWhere(ctx context.Context, condition string, args ...any) ([]models.Webhook, error) {
+	query := `
+		SELECT w.id, w.project_id, w.url, w.secret, w.events, w.created_at
+		FROM webhooks w
+		WHERE ` + condition + `
+		ORDER BY w.created_at
+	`
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %v", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *DatabaseHandler) GetWebhook(ctx context.Context, webhookID uuid.UUID) (_ *models.Webhook, err error) {
+	ctx, end := s.startQuery(ctx, "GetWebhook")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT id, project_id, url, secret, events, created_at
+		FROM webhooks
+		WHERE id = $1
+	`
+	var webhook models.Webhook
+	if err := scanWebhook(s.conn().QueryRowContext(ctx, query, webhookID), &webhook); err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", notFound(err))
+	}
+
+	return &webhook, nil
+}
+
+func (s *DatabaseHandler) CreateWebhook(ctx context.Context, projectID uuid.UUID, url string, secret string, events []string) (_ *models.Webhook, err error) {
+	ctx, end := s.startQuery(ctx, "CreateWebhook")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO webhooks (project_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, project_id, url, secret, events, created_at
+	`
+	var webhook models.Webhook
+	row := s.conn().QueryRowContext(ctx, query, projectID, url, secret, strings.Join(events, ","))
+	if err := scanWebhook(row, &webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %v", err)
+	}
+
+	return &webhook, nil
+}
+
+func (s *DatabaseHandler) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) (err error) {
+	ctx, end := s.startQuery(ctx, "DeleteWebhook")
+	defer func() { end(err) }()
+
+	query := `
+		DELETE FROM webhooks WHERE id=$1
+	`
+	_, err = s.conn().ExecContext(ctx, query, webhookID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+
+	return nil
+}
+
+func (s *DatabaseHandler) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (_ *models.WebhookDelivery, err error) {
+	ctx, end := s.startQuery(ctx, "CreateWebhookDelivery")
+	defer func() { end(err) }()
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, attempt, status_code, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, webhook_id, event_id, event, attempt, status_code, error, created_at
+	`
+	row := s.conn().QueryRowContext(ctx, query, delivery.WebhookID, delivery.EventID, delivery.Event,
+		delivery.Attempt, delivery.StatusCode, delivery.Error)
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Event,
+		&delivery.Attempt, &delivery.StatusCode, &delivery.Error, &delivery.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %v", err)
+	}
+
+	return &delivery, nil
+}
+
+func (s *DatabaseHandler) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) (_ []models.WebhookDelivery, err error) {
+	ctx, end := s.startQuery(ctx, "ListWebhookDeliveries")
+	defer func() { end(err) }()
+
+	query := `
+		SELECT id, webhook_id, event_id, event, attempt, status_code, error, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := s.conn().QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Event,
+			&delivery.Attempt, &delivery.StatusCode, &delivery.Error, &delivery.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhook(row rowScanner, webhook *models.Webhook) error {
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.ProjectID, &webhook.URL,
+		&webhook.Secret, &events, &webhook.CreatedAt); err != nil {
+		return err
+	}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+
+	return nil
+}
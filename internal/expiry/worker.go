@@ -0,0 +1,111 @@
+// Package expiry disables the configs whose expiry passed. The proxy stops
+// serving them at expiry already, through the proxy_configs view; disabling
+// them records it and notifies the owner of the project once.
+package expiry
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Sender is implemented by mail.Mailer.
+type Sender interface {
+	Send(to string, subject string, body string) error
+}
+
+type Worker struct {
+	configs    database.ConfigStore
+	projects   database.ProjectStore
+	users      database.UserStore
+	dispatcher *webhooks.Dispatcher
+	interval   time.Duration
+	// mailer is nil when no SMTP server is configured.
+	mailer Sender
+	logger *slog.Logger
+}
+
+// NewWorker sends no emails when mailer is nil, the config.expired webhook
+// event is published either way.
+func NewWorker(configs database.ConfigStore, projects database.ProjectStore, users database.UserStore,
+	dispatcher *webhooks.Dispatcher, interval time.Duration, mailer *mail.Mailer, logger *slog.Logger) *Worker {
+	w := &Worker{configs: configs, projects: projects, users: users, dispatcher: dispatcher, interval: interval,
+		logger: logger.With("job", "config_expiry")}
+	if mailer != nil {
+		w.mailer = mailer
+	}
+	return w
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.DisableExpired(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) DisableExpired(ctx context.Context, now time.Time) {
+	configs, err := w.configs.ListExpiredConfigs(ctx, now)
+	if err != nil {
+		w.logger.Error("failed to list expired configs", "error", err)
+		return
+	}
+
+	for _, config := range configs {
+		disablement := models.Disablement{At: now, Reason: models.DisabledReasonExpired}
+		disabled, err := w.configs.DisableConfig(ctx, config.ID, disablement)
+		if err != nil {
+			w.logger.Error("failed to disable expired config", "config_id", config.ID, "error", err)
+			continue
+		}
+		if !disabled {
+			// Disabled by hand since it was listed, that reason is kept.
+			continue
+		}
+		config.Disabled = &disablement
+		w.dispatcher.Publish(ctx, config.ProjectID, models.EventConfigExpired, webhooks.NewConfigPayload(config))
+
+		if err := w.notifyOwner(ctx, config); err != nil {
+			w.logger.Error("failed to notify the owner of an expired config", "config_id", config.ID, "error", err)
+		}
+	}
+}
+
+// notifyOwner emails the owner of the project, when they have an email
+// address.
+func (w *Worker) notifyOwner(ctx context.Context, config models.Config) error {
+	if w.mailer == nil {
+		return nil
+	}
+
+	project, err := w.projects.GetProject(ctx, config.ProjectID)
+	if err != nil {
+		return err
+	}
+	owner, err := w.users.GetUser(ctx, project.UserID)
+	if err != nil {
+		return err
+	}
+	if owner.Email == "" {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Config %s of project %s expired", config.Name, project.Name)
+	body := fmt.Sprintf("The config %s of project %s expired at %s, the proxy no longer serves it.\n\n"+
+		"Set a new expiry in its availability to serve it again.",
+		config.Name, project.Name, config.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"))
+	return w.mailer.Send(owner.Email, subject, body)
+}
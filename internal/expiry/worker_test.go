@@ -0,0 +1,92 @@
+package expiry
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type sentMail struct {
+	to, subject, body string
+}
+
+type recordingSender struct {
+	sent []sentMail
+}
+
+func (r *recordingSender) Send(to string, subject string, body string) error {
+	r.sent = append(r.sent, sentMail{to, subject, body})
+	return nil
+}
+
+func TestDisableExpired(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice", Email: "alice@example.com"})
+	project, _ := store.CreateProject(ctx, "payments", "", "key", owner.ID)
+	expired, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	active, _ := store.CreateConfig(ctx, project.ID, "paypal", 100, "hour")
+
+	now := time.Now().UTC()
+	expiredAt, expiresAt := now.Add(-time.Minute), now.Add(time.Hour)
+	store.UpdateConfigAvailability(ctx, expired.ID, nil, &expiredAt)
+	store.UpdateConfigAvailability(ctx, active.ID, nil, &expiresAt)
+
+	sender := &recordingSender{}
+	worker := NewWorker(store, store, store, webhooks.NewDispatcher(store, nil), time.Minute, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	worker.mailer = sender
+	worker.DisableExpired(ctx, now)
+	worker.DisableExpired(ctx, now)
+
+	if config, _ := store.GetConfig(ctx, expired.ID); config.Disabled == nil || config.Disabled.Reason != models.DisabledReasonExpired {
+		t.Fatalf("expected the expired config to be disabled, got %+v", config)
+	}
+	if config, _ := store.GetConfig(ctx, active.ID); config.Disabled != nil {
+		t.Fatalf("expected the config expiring later to be left enabled, got %+v", config)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].to != "alice@example.com" || sender.sent[0].subject != "Config stripe of project payments expired" {
+		t.Fatalf("expected the owner to be emailed once, got %+v", sender.sent)
+	}
+}
+
+// listedBeforeDisable lists the configs as they were before someone
+// disabled one by hand.
+type listedBeforeDisable struct {
+	*memstore.Store
+	listed []models.Config
+}
+
+func (l listedBeforeDisable) ListExpiredConfigs(ctx context.Context, now time.Time) ([]models.Config, error) {
+	return l.listed, nil
+}
+
+func TestDisableExpiredKeepsDisabledByHand(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "local", Subject: "alice", Email: "alice@example.com"})
+	project, _ := store.CreateProject(ctx, "payments", "", "key", owner.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+
+	now := time.Now().UTC()
+	expiredAt := now.Add(-time.Minute)
+	store.UpdateConfigAvailability(ctx, config.ID, nil, &expiredAt)
+	listed, _ := store.ListExpiredConfigs(ctx, now)
+	store.DisableConfig(ctx, config.ID, models.Disablement{At: now, Reason: "leaked key", By: &owner.ID})
+
+	sender := &recordingSender{}
+	worker := NewWorker(listedBeforeDisable{store, listed}, store, store, webhooks.NewDispatcher(store, nil), time.Minute, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	worker.mailer = sender
+	worker.DisableExpired(ctx, now)
+
+	if got, _ := store.GetConfig(ctx, config.ID); got.Disabled == nil || got.Disabled.Reason != "leaked key" {
+		t.Fatalf("expected the config to keep the reason it was disabled for, got %+v", got)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected no expiry email, got %+v", sender.sent)
+	}
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"bytes"
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/web/account_components"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ChangePasswordForm struct {
+	CurrentPassword string `form:"current-password" validate:"required"`
+	Password        string `form:"password" validate:"required,min=12,max=72"`
+	ConfirmPassword string `form:"confirm-password" validate:"eqfield=Password"`
+}
+
+type InviteForm struct {
+	Email string `form:"email" validate:"omitempty,email,max=255"`
+}
+
+// AccountHandler lets local users manage their password, their second
+// factor and invite other users.
+type AccountHandler struct {
+	local     auth.LocalAuth
+	baseURL   string
+	store     database.Store
+	encrypter *utils.Encrypter
+	mailer    *mail.Mailer
+	decoder   *form.Decoder
+	validate  *validator.Validate
+}
+
+func NewAccountHandler(local auth.LocalAuth, baseURL string, store database.Store, encrypter *utils.Encrypter,
+	mailer *mail.Mailer) *AccountHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &AccountHandler{local, baseURL, store, encrypter, mailer, form.NewDecoder(), validate}
+}
+
+func accountFromContext(c echo.Context) (*models.User, *models.Credentials, error) {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return nil, nil, InternalError("missing user")
+	}
+	credentials, ok := c.Get("credentials").(*models.Credentials)
+	if !ok {
+		return nil, nil, InternalError("missing credentials")
+	}
+
+	return user, credentials, nil
+}
+
+func (a *AccountHandler) Account(c echo.Context) error {
+	user, credentials, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	return renderComponent(c, http.StatusOK, account_components.Account(user, *credentials))
+}
+
+// ChangePassword keeps the current session and signs the user out of the
+// other ones.
+func (a *AccountHandler) ChangePassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, credentials, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	passwordForm, formErrors, err := processForm[ChangePasswordForm](c, a.decoder, a.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors == nil && !auth.CheckPassword(credentials.PasswordHash, passwordForm.CurrentPassword) {
+		formErrors = forms.FormErrors{"CurrentPassword": "Wrong password"}
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, account_components.ChangePasswordForm(formErrors, false))
+	}
+
+	passwordHash, err := auth.HashPassword(passwordForm.Password)
+	if err != nil {
+		return InternalError("%w", err)
+	}
+	if err := a.store.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return InternalError("failed to change password: %w", err)
+	}
+
+	currentSession, ok := c.Get("currentSession").(*models.Session)
+	if !ok {
+		return InternalError("missing current session")
+	}
+	userSessions, err := a.store.ListUserSessions(ctx, user.ID)
+	if err != nil {
+		return InternalError("failed to list user sessions: %w", err)
+	}
+	for _, userSession := range userSessions {
+		if userSession.ID == currentSession.ID {
+			continue
+		}
+		if err := a.store.DeleteUserSession(ctx, userSession.ID); err != nil {
+			return InternalError("failed to delete user session: %w", err)
+		}
+	}
+
+	return renderComponent(c, http.StatusOK, account_components.ChangePasswordForm(nil, true))
+}
+
+// StartTOTP generates a new secret, the second factor is only enabled once
+// a code generated from it is confirmed.
+func (a *AccountHandler) StartTOTP(c echo.Context) error {
+	user, credentials, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+	if credentials.TOTPEnabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is already enabled")
+	}
+
+	key, err := auth.NewTOTPKey(credentials.Username)
+	if err != nil {
+		return InternalError("%w", err)
+	}
+	encryptedSecret, err := a.encrypter.Encrypt(c.Request().Context(), key.Secret())
+	if err != nil {
+		return InternalError("failed to encrypt totp secret: %w", err)
+	}
+	if err := a.store.UpdateTOTP(c.Request().Context(), user.ID, encryptedSecret, false); err != nil {
+		return InternalError("failed to start totp enrollment: %w", err)
+	}
+
+	var qrCode bytes.Buffer
+	image, err := key.Image(200, 200)
+	if err == nil {
+		err = png.Encode(&qrCode, image)
+	}
+	if err != nil {
+		return InternalError("failed to render totp qr code: %w", err)
+	}
+
+	enrollment := account_components.TOTPEnrollment{
+		Secret:    key.Secret(),
+		URL:       key.URL(),
+		QRCodeURI: "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	}
+	return renderComponent(c, http.StatusOK, account_components.TOTPSetup(enrollment, nil))
+}
+
+// checkTOTP validates the code against the stored secret, enrolled or not.
+func (a *AccountHandler) checkTOTP(c echo.Context, credentials *models.Credentials) (forms.FormErrors, error) {
+	totpForm, formErrors, err := processForm[TOTPForm](c, a.decoder, a.validate)
+	if err != nil || formErrors != nil {
+		return formErrors, err
+	}
+
+	if credentials.TOTPSecret == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is not set up")
+	}
+	valid, locked, err := verifyTOTP(c.Request().Context(), a.store, a.encrypter, credentials, totpForm.Code)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return forms.FormErrors{"Code": "Too many invalid codes, try again later"}, nil
+	}
+	if !valid {
+		return forms.FormErrors{"Code": "Invalid code"}, nil
+	}
+
+	return nil, nil
+}
+
+func (a *AccountHandler) ConfirmTOTP(c echo.Context) error {
+	user, credentials, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	formErrors, err := a.checkTOTP(c, credentials)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#totp-confirm-form")
+		return renderComponent(c, http.StatusBadRequest, account_components.ConfirmTOTPForm(formErrors))
+	}
+
+	if err := a.store.UpdateTOTP(c.Request().Context(), user.ID, credentials.TOTPSecret, true); err != nil {
+		return InternalError("failed to enable totp: %w", err)
+	}
+
+	credentials.TOTPEnabled = true
+	return renderComponent(c, http.StatusOK, account_components.TOTPSection(*credentials, nil))
+}
+
+func (a *AccountHandler) DisableTOTP(c echo.Context) error {
+	user, credentials, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+	if !credentials.TOTPEnabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	formErrors, err := a.checkTOTP(c, credentials)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, account_components.TOTPSection(*credentials, formErrors))
+	}
+
+	if err := a.store.UpdateTOTP(c.Request().Context(), user.ID, "", false); err != nil {
+		return InternalError("failed to disable totp: %w", err)
+	}
+
+	credentials.TOTPSecret = ""
+	credentials.TOTPEnabled = false
+	return renderComponent(c, http.StatusOK, account_components.TOTPSection(*credentials, nil))
+}
+
+// CreateInvite returns a signup link to share, it is also emailed when an
+// address is given and SMTP is configured.
+func (a *AccountHandler) CreateInvite(c echo.Context) error {
+	ctx := c.Request().Context()
+	user, _, err := accountFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	inviteForm, formErrors, err := processForm[InviteForm](c, a.decoder, a.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, account_components.InviteForm(formErrors, nil))
+	}
+
+	token, tokenHash, err := auth.NewAuthToken()
+	if err != nil {
+		return InternalError("%w", err)
+	}
+	expiresAt := time.Now().Add(a.local.InviteTTL)
+	if _, err := a.store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenInvite,
+		TokenHash: tokenHash,
+		Email:     inviteForm.Email,
+		CreatedBy: &user.ID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return InternalError("failed to create invite: %w", err)
+	}
+
+	invite := &account_components.Invite{
+		Link:      a.baseURL + "/signup?token=" + url.QueryEscape(token),
+		ExpiresAt: expiresAt,
+	}
+	if inviteForm.Email != "" && a.mailer != nil {
+		body := fmt.Sprintf("%s invited you to API Key Limiter.\n\nOpen %s within %s to create your account.",
+			user.Name, invite.Link, a.local.InviteTTL)
+		if err := a.mailer.Send(inviteForm.Email, "[API Key Limiter] You are invited", body); err != nil {
+			logging.FromContext(ctx).Error("failed to send invite", "error", err)
+		} else {
+			invite.Emailed = true
+		}
+	}
+
+	return renderComponent(c, http.StatusOK, account_components.InviteForm(nil, invite))
+}
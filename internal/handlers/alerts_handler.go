@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/models"
+	"configuration-management/web/projects_components"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type CreateAlertForm struct {
+	Kind          string `form:"kind" validate:"required,oneof=quota rejections"`
+	Threshold     int    `form:"threshold" validate:"required,min=1"`
+	WindowMinutes int    `form:"window-minutes" validate:"required_if=Kind rejections,min=0"`
+	Channel       string `form:"channel" validate:"required,oneof=webhook email"`
+	Target        string `form:"target" validate:"required"`
+}
+
+type AlertsHandler struct {
+	alerts   database.AlertStore
+	decoder  *form.Decoder
+	validate *validator.Validate
+}
+
+func NewAlertsHandler(alerts database.AlertStore) *AlertsHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &AlertsHandler{alerts, form.NewDecoder(), validate}
+}
+
+func (a *AlertsHandler) processForm(c echo.Context) (*CreateAlertForm, forms.FormErrors, error) {
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	var alertForm CreateAlertForm
+	if err := a.decoder.Decode(&alertForm, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateAlertForm: %w", err))
+	}
+
+	errors := make(forms.FormErrors)
+	if validationErr := a.validate.Struct(alertForm); validationErr != nil {
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+	}
+
+	if _, ok := errors["Target"]; !ok {
+		switch alertForm.Channel {
+		case models.AlertChannelEmail:
+			if err := a.validate.Var(alertForm.Target, "email"); err != nil {
+				errors["Target"] = "email"
+			}
+		default:
+			// as for webhooks, the payloads must not travel in clear
+			if u, err := url.Parse(alertForm.Target); err != nil || u.Scheme != "https" || u.Host == "" {
+				errors["Target"] = "https_url"
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return nil, errors, nil
+	}
+
+	return &alertForm, nil, nil
+}
+
+func (a *AlertsHandler) CreateAlert(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	alertForm, formErrs, processingErr := a.processForm(c)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetCreateAlertFormID(config.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.CreateAlertForm(project.ID, config.ID, formErrs))
+	}
+
+	windowMinutes := alertForm.WindowMinutes
+	if alertForm.Kind == models.AlertKindQuota {
+		windowMinutes = 0
+	}
+
+	alert, alertErr := a.alerts.CreateAlert(c.Request().Context(), config.ID, alertForm.Kind, alertForm.Threshold,
+		windowMinutes, alertForm.Channel, alertForm.Target)
+	if alertErr != nil {
+		return InternalError("failed to create alert: %w", alertErr)
+	}
+
+	return renderComponent(c, http.StatusOK, projects_components.Alert(project.ID, *config, *alert))
+}
+
+func (a *AlertsHandler) DeleteAlert(c echo.Context) error {
+	alert, ok := c.Get("alert").(*models.Alert)
+	if !ok {
+		return InternalError("missing alert instance in the context")
+	}
+
+	if deleteErr := a.alerts.DeleteAlert(c.Request().Context(), alert.ID); deleteErr != nil {
+		return InternalError("failed to delete alert: %w", deleteErr)
+	}
+
+	return nil
+}
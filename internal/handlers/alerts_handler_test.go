@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateAlertTargets(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 10, "hour")
+	handler := NewAlertsHandler(store)
+
+	tests := []struct {
+		channel string
+		target  string
+		status  int
+	}{
+		{models.AlertChannelWebhook, "http://example.com/alert", http.StatusBadRequest},
+		{models.AlertChannelWebhook, "ftp://example.com/alert", http.StatusBadRequest},
+		{models.AlertChannelWebhook, "https://", http.StatusBadRequest},
+		{models.AlertChannelWebhook, "https://example.com/alert", http.StatusOK},
+		{models.AlertChannelEmail, "https://example.com/alert", http.StatusBadRequest},
+		{models.AlertChannelEmail, "alice@example.com", http.StatusOK},
+	}
+	for _, tt := range tests {
+		target := "/projects/" + project.ID.String() + "/configs/" + config.ID.String() + "/alerts"
+		c, rec := newFormContext(http.MethodPost, target, url.Values{
+			"kind": {models.AlertKindQuota}, "threshold": {"80"}, "channel": {tt.channel}, "target": {tt.target},
+		})
+		c.Set("project", project)
+		c.Set("config", config)
+		if err := handler.CreateAlert(c); err != nil {
+			t.Fatalf("CreateAlert(%s, %q) returned error: %v", tt.channel, tt.target, err)
+		}
+		if rec.Code != tt.status {
+			t.Errorf("CreateAlert(%s, %q): expected status %d, got %d", tt.channel, tt.target, tt.status, rec.Code)
+		}
+	}
+
+	if alerts, _ := store.ListAlerts(ctx, config.ID); len(alerts) != 2 {
+		t.Fatalf("expected only the valid alerts to be created, got %+v", alerts)
+	}
+}
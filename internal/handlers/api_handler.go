@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"configuration-management/internal/api"
+	"configuration-management/internal/connection"
+	"configuration-management/internal/database"
+	"configuration-management/internal/metrics"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIHandler serves the JSON API of the command-line clients, with the same
+// effects as the pages: webhooks are notified and reveals are counted.
+type APIHandler struct {
+	store      database.Store
+	encrypter  *utils.Encrypter
+	dispatcher *webhooks.Dispatcher
+	// proxyHost is the address of the proxy shown in connection strings.
+	proxyHost string
+	metrics   *metrics.Metrics
+}
+
+func NewAPIHandler(store database.Store, encrypter *utils.Encrypter, dispatcher *webhooks.Dispatcher,
+	proxyHost string, metrics *metrics.Metrics) *APIHandler {
+	return &APIHandler{store, encrypter, dispatcher, proxyHost, metrics}
+}
+
+func bindJSON(c echo.Context, request any) error {
+	if err := c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid JSON body").SetInternal(err)
+	}
+	return nil
+}
+
+func (a *APIHandler) ListProjects(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user")
+	}
+
+	projects, err := a.store.ListProjects(c.Request().Context(), user.ID, database.ProjectListOptions{})
+	if err != nil {
+		return InternalError("failed to list projects: %w", err)
+	}
+
+	response := []api.Project{}
+	for _, project := range projects {
+		response = append(response, api.NewProject(project))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (a *APIHandler) GetProject(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	configs, err := a.store.ListConfigs(c.Request().Context(), project.ID)
+	if err != nil {
+		return InternalError("failed to list configs: %w", err)
+	}
+	response := *project
+	response.Configs = configs
+
+	return c.JSON(http.StatusOK, api.NewProject(response))
+}
+
+func (a *APIHandler) CreateProject(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user")
+	}
+
+	var request api.CreateProjectRequest
+	if err := bindJSON(c, &request); err != nil {
+		return err
+	}
+	if strings.TrimSpace(request.Name) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The project name is required")
+	}
+
+	project, err := a.store.CreateProject(c.Request().Context(), request.Name, request.Description, utils.GenerateToken(32), user.ID)
+	if err != nil {
+		return InternalError("failed to create project: %w", err)
+	}
+	a.dispatcher.Publish(c.Request().Context(), project.ID, models.EventProjectCreated, webhooks.NewProjectPayload(*project))
+
+	return c.JSON(http.StatusCreated, api.NewProject(*project))
+}
+
+func (a *APIHandler) DeleteProject(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	notifyDeleted := a.dispatcher.PublishProjectDeleted(c.Request().Context(), *project)
+	if err := a.store.DeleteProject(c.Request().Context(), project.ID); err != nil {
+		return InternalError("failed to delete project: %w", err)
+	}
+
+	notifyDeleted()
+	return c.NoContent(http.StatusNoContent)
+}
+
+func parseLimit(limit string) (int, string, error) {
+	numberOfRequests, per, err := models.ParseLimit(limit)
+	if err != nil {
+		return 0, "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return numberOfRequests, per, nil
+}
+
+func (a *APIHandler) CreateConfig(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	var request api.ConfigRequest
+	if err := bindJSON(c, &request); err != nil {
+		return err
+	}
+	if strings.TrimSpace(request.Name) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The config name is required")
+	}
+	numberOfRequests, per, err := parseLimit(request.Limit)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.store.CreateConfig(c.Request().Context(), project.ID, request.Name, numberOfRequests, per)
+	if err != nil {
+		return InternalError("failed to create config: %w", err)
+	}
+	a.dispatcher.Publish(c.Request().Context(), project.ID, models.EventConfigCreated, webhooks.NewConfigPayload(*config))
+
+	return c.JSON(http.StatusCreated, api.NewConfig(*config))
+}
+
+// UpdateConfig changes the limit of the config.
+func (a *APIHandler) UpdateConfig(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	var request api.ConfigRequest
+	if err := bindJSON(c, &request); err != nil {
+		return err
+	}
+	numberOfRequests, per, err := parseLimit(request.Limit)
+	if err != nil {
+		return err
+	}
+
+	if err := a.store.UpdateConfigLimit(c.Request().Context(), config.ID, numberOfRequests, per); err != nil {
+		return InternalError("failed to update config: %w", err)
+	}
+	config.LimitNumberOfRequests, config.LimitPer = numberOfRequests, per
+	a.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigUpdated, webhooks.NewConfigPayload(*config))
+
+	return a.renderConfig(c, config)
+}
+
+func (a *APIHandler) DeleteConfig(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	if err := a.store.DeleteConfig(c.Request().Context(), config.ID); err != nil {
+		return InternalError("failed to delete config: %w", err)
+	}
+	a.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigDeleted, webhooks.NewConfigPayload(*config))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// renderConfig responds with the config and the names of its headers.
+func (a *APIHandler) renderConfig(c echo.Context, config *models.Config) error {
+	headers, err := a.store.ListHeaderReplacements(c.Request().Context(), config.ID)
+	if err != nil {
+		return InternalError("failed to list header replacements: %w", err)
+	}
+	response := *config
+	response.HeaderReplacements = headers
+
+	return c.JSON(http.StatusOK, api.NewConfig(response))
+}
+
+// findHeader returns the header of the config named by the headerName
+// parameter, header names being case-insensitive, or nil when there is none.
+func (a *APIHandler) findHeader(c echo.Context, config *models.Config) (*models.HeaderReplacement, error) {
+	headers, err := a.store.ListHeaderReplacements(c.Request().Context(), config.ID)
+	if err != nil {
+		return nil, InternalError("failed to list header replacements: %w", err)
+	}
+	for _, header := range headers {
+		if strings.EqualFold(header.HeaderName, c.Param("headerName")) {
+			return &header, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetHeader creates the header replacement, or replaces its value when the
+// config already replaces the header.
+func (a *APIHandler) SetHeader(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	var request api.HeaderRequest
+	if err := bindJSON(c, &request); err != nil {
+		return err
+	}
+	if request.Value == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The header value is required")
+	}
+	encryptedValue, err := a.encrypter.Encrypt(c.Request().Context(), request.Value)
+	if err != nil {
+		return InternalError("failed to encrypt header value: %w", err)
+	}
+
+	header, err := a.findHeader(c, config)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		created, err := a.store.CreateHeaderReplacement(c.Request().Context(), config.ID, c.Param("headerName"), encryptedValue)
+		if err != nil {
+			return InternalError("failed to create header replacement: %w", err)
+		}
+		a.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventHeaderCreated, webhooks.NewHeaderPayload(*created))
+	} else {
+		if err := a.store.UpdateHeaderReplacementValue(c.Request().Context(), header.ID, encryptedValue); err != nil {
+			return InternalError("failed to update header replacement: %w", err)
+		}
+		a.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventHeaderUpdated, webhooks.NewHeaderPayload(*header))
+	}
+
+	return a.renderConfig(c, config)
+}
+
+func (a *APIHandler) UnsetHeader(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	header, err := a.findHeader(c, config)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("The config does not replace the %s header", c.Param("headerName")))
+	}
+	if err := a.store.DeleteHeaderReplacement(c.Request().Context(), header.ID); err != nil {
+		return InternalError("failed to delete header: %w", err)
+	}
+	a.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventHeaderDeleted, webhooks.NewHeaderPayload(*header))
+
+	return a.renderConfig(c, config)
+}
+
+func (a *APIHandler) GetConnection(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project")
+	}
+
+	a.metrics.Reveal(metrics.RevealConnectionInfo)
+	return c.JSON(http.StatusOK, api.NewConnection(connection.New(*config, *project, a.proxyHost)))
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"configuration-management/web/tokens_components"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type APITokensHandler struct {
+	tokens database.APITokenStore
+	// baseURL is shown in the login command of the created tokens.
+	baseURL string
+}
+
+func NewAPITokensHandler(tokens database.APITokenStore, baseURL string) *APITokensHandler {
+	return &APITokensHandler{tokens, baseURL}
+}
+
+func (a *APITokensHandler) ListAPITokens(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user instance in the context")
+	}
+
+	tokens, err := a.tokens.ListAPITokens(c.Request().Context(), user.ID)
+	if err != nil {
+		return InternalError("failed to list api tokens: %w", err)
+	}
+
+	return renderComponent(c, http.StatusOK, tokens_components.APITokens(user, tokens, a.baseURL))
+}
+
+// CreateAPIToken shows the token once, only its hash is stored.
+func (a *APITokensHandler) CreateAPIToken(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user instance in the context")
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The token name is required")
+	}
+	secret, tokenHash, err := auth.NewAuthToken()
+	if err != nil {
+		return InternalError("failed to create api token: %w", err)
+	}
+	if _, err := a.tokens.CreateAPIToken(c.Request().Context(), user.ID, name, tokenHash); err != nil {
+		return InternalError("failed to create api token: %w", err)
+	}
+
+	tokens, err := a.tokens.ListAPITokens(c.Request().Context(), user.ID)
+	if err != nil {
+		return InternalError("failed to list api tokens: %w", err)
+	}
+	created := &tokens_components.CreatedToken{Name: name, Secret: secret}
+	return renderComponent(c, http.StatusOK, tokens_components.TokenList(tokens, created, a.baseURL))
+}
+
+func (a *APITokensHandler) DeleteAPIToken(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user instance in the context")
+	}
+	tokenID, err := uuid.Parse(c.Param("tokenId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid token id")
+	}
+
+	if err := a.tokens.DeleteAPIToken(c.Request().Context(), user.ID, tokenID); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return InternalError("failed to revoke api token: %w", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
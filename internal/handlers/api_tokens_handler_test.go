@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCreateAndRevokeAPIToken(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	other, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "2", Name: "other"})
+	handler := NewAPITokensHandler(store, "https://limiter.example.com")
+
+	c, rec := newFormContext(http.MethodPost, "/tokens", url.Values{"name": {"laptop"}})
+	c.Set("user", user)
+	if err := handler.CreateAPIToken(c); err != nil {
+		t.Fatalf("CreateAPIToken returned error: %v", err)
+	}
+	match := regexp.MustCompile(`limiterctl login --url https://limiter.example.com --token (\S+)</code>`).FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatalf("expected the login command to be shown, got %s", rec.Body.String())
+	}
+	token, err := store.GetAPIToken(ctx, auth.HashAuthToken(match[1]))
+	if err != nil || token.UserID != user.ID || token.Name != "laptop" {
+		t.Fatalf("expected the hash of the shown token to be stored, got %+v, %v", token, err)
+	}
+
+	c, _ = newFormContext(http.MethodDelete, "/tokens/"+token.ID.String(), nil)
+	c.SetParamNames("tokenId")
+	c.SetParamValues(token.ID.String())
+	c.Set("user", other)
+	if err := handler.DeleteAPIToken(c); err == nil || err.(*echo.HTTPError).Code != http.StatusNotFound {
+		t.Fatalf("expected the token of another user not to be revoked, got %v", err)
+	}
+
+	c.Set("user", user)
+	if err := handler.DeleteAPIToken(c); err != nil {
+		t.Fatalf("DeleteAPIToken returned error: %v", err)
+	}
+	if tokens, _ := store.ListAPITokens(ctx, user.ID); len(tokens) != 0 {
+		t.Fatalf("expected the token to be revoked, got %+v", tokens)
+	}
+}
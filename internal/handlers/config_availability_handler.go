@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web/projects_components"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// ConfigAvailabilityForm sets the same hours on each of the days, the times
+// are in UTC.
+type ConfigAvailabilityForm struct {
+	ActiveFrom string `form:"active-from" validate:"omitempty,datetime=2006-01-02T15:04"`
+	ExpiresAt  string `form:"expires-at" validate:"omitempty,datetime=2006-01-02T15:04"`
+	Days       []int  `form:"days" validate:"dive,min=0,max=6"`
+	Start      string `form:"start" validate:"omitempty,datetime=15:04"`
+	End        string `form:"end" validate:"omitempty,datetime=15:04"`
+}
+
+type ConfigAvailabilityHandler struct {
+	transactor database.Transactor
+	dispatcher *webhooks.Dispatcher
+	decoder    *form.Decoder
+	validate   *validator.Validate
+}
+
+func NewConfigAvailabilityHandler(transactor database.Transactor, dispatcher *webhooks.Dispatcher) *ConfigAvailabilityHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &ConfigAvailabilityHandler{transactor, dispatcher, form.NewDecoder(), validate}
+}
+
+// processForm returns the config with its new availability. The expiry has
+// to be in the future and after the config becomes active, and the hours
+// are required once a day is checked.
+func (h *ConfigAvailabilityHandler) processForm(c echo.Context, config models.Config) (*models.Config, forms.FormErrors, error) {
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	var availabilityForm ConfigAvailabilityForm
+	if err := h.decoder.Decode(&availabilityForm, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode ConfigAvailabilityForm: %w", err))
+	}
+
+	errors := make(forms.FormErrors)
+	if validationErr := h.validate.Struct(availabilityForm); validationErr != nil {
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+	}
+
+	config.ActiveFrom, config.ExpiresAt, config.Schedule = nil, nil, nil
+	if _, ok := errors["ActiveFrom"]; !ok && availabilityForm.ActiveFrom != "" {
+		activeFrom, _ := time.Parse(projects_components.DateTimeInputLayout, availabilityForm.ActiveFrom)
+		config.ActiveFrom = &activeFrom
+	}
+	if _, ok := errors["ExpiresAt"]; !ok && availabilityForm.ExpiresAt != "" {
+		expiresAt, _ := time.Parse(projects_components.DateTimeInputLayout, availabilityForm.ExpiresAt)
+		if !expiresAt.After(time.Now()) {
+			errors["ExpiresAt"] = "future"
+		} else if config.ActiveFrom != nil && !expiresAt.After(*config.ActiveFrom) {
+			errors["ExpiresAt"] = "after active from"
+		}
+		config.ExpiresAt = &expiresAt
+	}
+
+	if len(availabilityForm.Days) > 0 {
+		for field, value := range map[string]string{"Start": availabilityForm.Start, "End": availabilityForm.End} {
+			if _, ok := errors[field]; !ok && value == "" {
+				errors[field] = "required"
+			}
+		}
+	}
+	if len(errors) == 0 && len(availabilityForm.Days) > 0 {
+		start, _ := models.ParseMinute(availabilityForm.Start)
+		end, _ := models.ParseMinute(availabilityForm.End)
+		if end == 0 {
+			end = 24 * 60
+		}
+		for _, day := range availabilityForm.Days {
+			window := models.ScheduleWindow{Weekday: time.Weekday(day), StartMinute: start, EndMinute: end}
+			if err := window.Validate(); err != nil {
+				errors["End"] = "after from"
+				break
+			}
+			config.Schedule = append(config.Schedule, window)
+		}
+	}
+
+	if len(errors) > 0 {
+		return nil, errors, nil
+	}
+
+	return &config, nil, nil
+}
+
+// UpdateConfigAvailability also enables the config again when it was
+// disabled on expiry, not when it was disabled by hand.
+func (h *ConfigAvailabilityHandler) UpdateConfigAvailability(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	updated, formErrs, processingErr := h.processForm(c, *config)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetConfigAvailabilityID(config.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.ConfigAvailability(*config, formErrs))
+	}
+
+	txErr := h.transactor.Transaction(c.Request().Context(), func(ctx context.Context, tx database.Store) error {
+		if err := tx.UpdateConfigAvailability(ctx, config.ID, updated.ActiveFrom, updated.ExpiresAt); err != nil {
+			return err
+		}
+		return tx.SetConfigSchedule(ctx, config.ID, updated.Schedule)
+	})
+	if txErr != nil {
+		return InternalError("failed to update config availability: %w", txErr)
+	}
+	if updated.Disabled != nil && updated.Disabled.Reason == models.DisabledReasonExpired {
+		updated.Disabled = nil
+	}
+	h.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigUpdated, webhooks.NewConfigPayload(*updated))
+
+	return renderComponent(c, http.StatusOK, projects_components.ConfigAvailability(*updated, nil))
+}
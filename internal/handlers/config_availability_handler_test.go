@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web/projects_components"
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateConfigAvailability(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	store.DisableConfig(ctx, config.ID, models.Disablement{At: time.Now(), Reason: models.DisabledReasonExpired})
+	config, _ = store.GetConfig(ctx, config.ID)
+	handler := NewConfigAvailabilityHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	expiresAt := time.Now().UTC().AddDate(0, 1, 0).Format(projects_components.DateTimeInputLayout)
+	form := url.Values{
+		"active-from": {"2024-01-01T00:00"},
+		"expires-at":  {expiresAt},
+		"days":        {"1", "2", "3", "4", "5"},
+		"start":       {"09:00"},
+		"end":         {"00:00"},
+	}
+	c, rec := newFormContext(http.MethodPost, "/availability", form)
+	c.Set("config", config)
+	if err := handler.UpdateConfigAvailability(c); err != nil {
+		t.Fatalf("UpdateConfigAvailability returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	updated, _ := store.GetConfig(ctx, config.ID)
+	if updated.Disabled != nil || updated.ActiveFrom == nil || !updated.ActiveFrom.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		updated.ExpiresAt == nil || updated.ExpiresAt.Format(projects_components.DateTimeInputLayout) != expiresAt {
+		t.Fatalf("unexpected config availability %+v", updated)
+	}
+	if schedule := models.FormatSchedule(updated.Schedule); schedule != "Mon-Fri 09:00-24:00 UTC" {
+		t.Fatalf("expected a schedule running until midnight, got %s", schedule)
+	}
+
+	c, _ = newFormContext(http.MethodPost, "/availability", url.Values{})
+	c.Set("config", updated)
+	if err := handler.UpdateConfigAvailability(c); err != nil {
+		t.Fatalf("UpdateConfigAvailability returned error: %v", err)
+	}
+	if cleared, _ := store.GetConfig(ctx, config.ID); cleared.ActiveFrom != nil || cleared.ExpiresAt != nil || len(cleared.Schedule) != 0 {
+		t.Fatalf("expected an empty form to serve the config at any time, got %+v", cleared)
+	}
+}
+
+func TestUpdateConfigAvailabilityValidation(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	handler := NewConfigAvailabilityHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	nextMonth := time.Now().UTC().AddDate(0, 1, 0).Format(projects_components.DateTimeInputLayout)
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"expired", url.Values{"expires-at": {"2024-01-01T00:00"}}},
+		{"expiring before it is active", url.Values{"active-from": {nextMonth}, "expires-at": {nextMonth}}},
+		{"invalid date", url.Values{"active-from": {"tomorrow"}}},
+		{"days without hours", url.Values{"days": {"1"}}},
+		{"invalid day", url.Values{"days": {"7"}, "start": {"09:00"}, "end": {"17:00"}}},
+		{"ending before it starts", url.Values{"days": {"1"}, "start": {"17:00"}, "end": {"09:00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newFormContext(http.MethodPost, "/availability", tt.form)
+			c.Set("config", config)
+			if err := handler.UpdateConfigAvailability(c); err != nil {
+				t.Fatalf("UpdateConfigAvailability returned error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+			if unchanged, _ := store.GetConfig(ctx, config.ID); unchanged.ExpiresAt != nil || unchanged.ActiveFrom != nil || len(unchanged.Schedule) != 0 {
+				t.Fatalf("expected the config to be left unchanged, got %+v", unchanged)
+			}
+		})
+	}
+}
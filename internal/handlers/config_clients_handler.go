@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/connection"
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/models"
+	"configuration-management/web/projects_components"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// expiryDateLayout is the layout of the date inputs, clients expire at the
+// start of the day in UTC.
+const expiryDateLayout = "2006-01-02"
+
+type CreateConfigClientForm struct {
+	Name      string `form:"name" validate:"required,max=255"`
+	Limit     int    `form:"limit" validate:"min=0"`
+	ExpiresAt string `form:"expires-at" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type ConfigClientsHandler struct {
+	clients   database.ConfigClientStore
+	proxyHost string
+	decoder   *form.Decoder
+	validate  *validator.Validate
+}
+
+func NewConfigClientsHandler(clients database.ConfigClientStore, proxyHost string) *ConfigClientsHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &ConfigClientsHandler{clients, proxyHost, form.NewDecoder(), validate}
+}
+
+// processForm checks the client limit is lower than the limit of the config,
+// over the same period, and the expiry is in the future.
+func (h *ConfigClientsHandler) processForm(c echo.Context, config models.Config) (*models.ConfigClient, forms.FormErrors, error) {
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	var clientForm CreateConfigClientForm
+	if err := h.decoder.Decode(&clientForm, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateConfigClientForm: %w", err))
+	}
+
+	errors := make(forms.FormErrors)
+	if validationErr := h.validate.Struct(clientForm); validationErr != nil {
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+	}
+
+	client := models.ConfigClient{ConfigID: config.ID, Name: clientForm.Name}
+	if _, ok := errors["Limit"]; !ok && clientForm.Limit > 0 {
+		if clientForm.Limit >= config.LimitNumberOfRequests {
+			errors["Limit"] = "lt"
+		}
+		client.LimitNumberOfRequests = &clientForm.Limit
+	}
+	if _, ok := errors["ExpiresAt"]; !ok && clientForm.ExpiresAt != "" {
+		expiresAt, _ := time.Parse(expiryDateLayout, clientForm.ExpiresAt)
+		if !expiresAt.After(time.Now()) {
+			errors["ExpiresAt"] = "future"
+		}
+		client.ExpiresAt = &expiresAt
+	}
+
+	if len(errors) > 0 {
+		return nil, errors, nil
+	}
+
+	return &client, nil, nil
+}
+
+// CreateConfigClient shows the proxy URL of the client once, only the hash of
+// its secret is stored.
+func (h *ConfigClientsHandler) CreateConfigClient(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	client, formErrs, processingErr := h.processForm(c, *config)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetCreateClientFormID(config.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.CreateConfigClientForm(*config, formErrs))
+	}
+
+	secret, secretHash, err := auth.NewAuthToken()
+	if err != nil {
+		return InternalError("failed to create config client: %w", err)
+	}
+	client.SecretHash = secretHash
+	created, err := h.clients.CreateConfigClient(c.Request().Context(), *client)
+	if err != nil {
+		return InternalError("failed to create config client: %w", err)
+	}
+
+	listed := *config
+	if listed.Clients, err = h.clients.ListConfigClients(c.Request().Context(), config.ID); err != nil {
+		return InternalError("failed to list config clients: %w", err)
+	}
+	conn := connection.NewClient(*config, *project, *created, secret, h.proxyHost)
+	component := projects_components.ListConfigClients(listed, &projects_components.CreatedClient{
+		Client:   *created,
+		Snippets: conn.Snippets(),
+	})
+	return renderComponent(c, http.StatusOK, component)
+}
+
+func (h *ConfigClientsHandler) DeleteConfigClient(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+	clientID, err := uuid.Parse(c.Param("clientId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid client id")
+	}
+
+	if err := h.clients.DeleteConfigClient(c.Request().Context(), config.ID, clientID); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return InternalError("failed to revoke config client: %w", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateConfigClient(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	handler := NewConfigClientsHandler(store, "proxy.example.com")
+
+	target := "/projects/" + project.ID.String() + "/configs/" + config.ID.String() + "/clients"
+	expiresAt := time.Now().AddDate(0, 0, 7).Format(expiryDateLayout)
+	c, rec := newFormContext(http.MethodPost, target, url.Values{"name": {"billing"}, "limit": {"10"}, "expires-at": {expiresAt}})
+	c.Set("project", project)
+	c.Set("config", config)
+	if err := handler.CreateConfigClient(c); err != nil {
+		t.Fatalf("CreateConfigClient returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	clients, _ := store.ListConfigClients(ctx, config.ID)
+	if len(clients) != 1 || clients[0].Name != "billing" || *clients[0].LimitNumberOfRequests != 10 ||
+		clients[0].ExpiresAt.Format(expiryDateLayout) != expiresAt {
+		t.Fatalf("unexpected clients %+v", clients)
+	}
+	client := clients[0]
+
+	// The proxy URL is shown once, the secret in it matches the stored hash.
+	match := regexp.MustCompile(`https://` + config.ID.String() + `:` + client.ID.String() + `:([\w-]+)@proxy\.example\.com`).
+		FindStringSubmatch(rec.Body.String())
+	if match == nil || auth.HashAuthToken(match[1]) != client.SecretHash {
+		t.Fatalf("expected the proxy URL of the client in the response, got %s", rec.Body.String())
+	}
+
+	c, _ = newFormContext(http.MethodPost, target, url.Values{"name": {"reports"}, "limit": {""}, "expires-at": {""}})
+	c.Set("project", project)
+	c.Set("config", config)
+	if err := handler.CreateConfigClient(c); err != nil {
+		t.Fatalf("CreateConfigClient without limit and expiry returned error: %v", err)
+	}
+	if clients, _ := store.ListConfigClients(ctx, config.ID); len(clients) != 2 || clients[1].LimitNumberOfRequests != nil || clients[1].ExpiresAt != nil {
+		t.Fatalf("expected a client sharing the config limit without expiry, got %+v", clients)
+	}
+}
+
+func TestCreateConfigClientValidation(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	handler := NewConfigClientsHandler(store, "proxy.example.com")
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		message string
+	}{
+		{"missing name", url.Values{"name": {""}}, "required"},
+		{"limit not lower than the config", url.Values{"name": {"billing"}, "limit": {"100"}}, "lower than 100"},
+		{"expired", url.Values{"name": {"billing"}, "expires-at": {time.Now().AddDate(0, 0, -1).Format(expiryDateLayout)}}, "future"},
+		{"invalid expiry", url.Values{"name": {"billing"}, "expires-at": {"tomorrow"}}, "datetime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newFormContext(http.MethodPost, "/clients", tt.form)
+			c.Set("project", project)
+			c.Set("config", config)
+			if err := handler.CreateConfigClient(c); err != nil {
+				t.Fatalf("CreateConfigClient returned error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest || rec.Header().Get("HX-Retarget") == "" {
+				t.Fatalf("expected the form to be rendered again, got %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.message) {
+				t.Fatalf("expected the form to show %q, got %s", tt.message, rec.Body.String())
+			}
+		})
+	}
+	if clients, _ := store.ListConfigClients(ctx, config.ID); len(clients) != 0 {
+		t.Fatalf("expected no client to be created, got %+v", clients)
+	}
+}
+
+func TestDeleteConfigClient(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	other, _ := store.CreateConfig(ctx, project.ID, "adyen", 100, "hour")
+	client, _ := store.CreateConfigClient(ctx, models.ConfigClient{ConfigID: config.ID, Name: "billing", SecretHash: "hash"})
+	handler := NewConfigClientsHandler(store, "proxy.example.com")
+
+	c, _ := newFormContext(http.MethodDelete, "/clients/"+client.ID.String(), nil)
+	c.SetParamNames("clientId")
+	c.SetParamValues(client.ID.String())
+	c.Set("config", other)
+	if err := handler.DeleteConfigClient(c); err == nil {
+		t.Fatalf("expected the client of another config not to be revoked")
+	}
+
+	c, rec := newFormContext(http.MethodDelete, "/clients/"+client.ID.String(), nil)
+	c.SetParamNames("clientId")
+	c.SetParamValues(client.ID.String())
+	c.Set("config", config)
+	if err := handler.DeleteConfigClient(c); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("DeleteConfigClient returned %d, %v", rec.Code, err)
+	}
+	if clients, _ := store.ListConfigClients(ctx, config.ID); len(clients) != 0 {
+		t.Fatalf("expected the client to be revoked, got %+v", clients)
+	}
+}
@@ -1,15 +1,18 @@
 package handlers
 
 import (
+	"configuration-management/internal/connection"
 	"configuration-management/internal/database"
 	"configuration-management/internal/forms"
+	"configuration-management/internal/metrics"
 	"configuration-management/internal/models"
 	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
 	"configuration-management/web/projects_components"
+	"context"
 	"fmt"
-	"log"
+	"mime"
 	"net/http"
-	"os"
 
 	"github.com/go-playground/form/v4"
 	"github.com/go-playground/validator/v10"
@@ -25,14 +28,21 @@ type CreateConfigForm struct {
 }
 
 type ConfigHandler struct {
-	db       *database.DatabaseHandler
-	decoder  *form.Decoder
-	validate *validator.Validate
+	configs    database.ConfigStore
+	transactor database.Transactor
+	encrypter  *utils.Encrypter
+	dispatcher *webhooks.Dispatcher
+	// proxyHost is the address of the proxy shown in connection strings.
+	proxyHost string
+	metrics   *metrics.Metrics
+	decoder   *form.Decoder
+	validate  *validator.Validate
 }
 
-func NewConfigHandler(db *database.DatabaseHandler) *ConfigHandler {
+func NewConfigHandler(configs database.ConfigStore, transactor database.Transactor, encrypter *utils.Encrypter,
+	dispatcher *webhooks.Dispatcher, proxyHost string, metrics *metrics.Metrics) *ConfigHandler {
 	validate := validator.New(validator.WithRequiredStructEnabled())
-	return &ConfigHandler{db, form.NewDecoder(), validate}
+	return &ConfigHandler{configs, transactor, encrypter, dispatcher, proxyHost, metrics, form.NewDecoder(), validate}
 }
 
 func (ch *ConfigHandler) processCreateConfigForm(c echo.Context) (*CreateConfigForm, forms.FormErrors, error) {
@@ -41,8 +51,7 @@ func (ch *ConfigHandler) processCreateConfigForm(c echo.Context) (*CreateConfigF
 	}
 	var createConfigForm CreateConfigForm
 	if err := ch.decoder.Decode(&createConfigForm, c.Request().Form); err != nil {
-		log.Fatalf("Error decoding CreateConfigForm: %e", err)
-		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateConfigForm: %w", err))
 	}
 
 	if validationErr := ch.validate.Struct(createConfigForm); validationErr != nil {
@@ -58,8 +67,7 @@ func (ch *ConfigHandler) processCreateConfigForm(c echo.Context) (*CreateConfigF
 func (ch *ConfigHandler) CreateConfig(c echo.Context) error {
 	project, ok := c.Get("project").(*models.Project)
 	if !ok {
-		log.Println("Missing project instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing project instance in the context")
 	}
 
 	createConfigForm, formErrs, processingErr := ch.processCreateConfigForm(c)
@@ -70,38 +78,62 @@ func (ch *ConfigHandler) CreateConfig(c echo.Context) error {
 	if formErrs != nil {
 		c.Response().Header().Set("HX-Reswap", "outerHTML")
 		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetCreateConfigFormID(project.ID))
+		c.Response().WriteHeader(http.StatusBadRequest)
 		component := projects_components.CreateConfigForm(project.ID, formErrs)
 		if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-			log.Fatalf("Error rendering created config: %e", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return InternalError("failed to render created config: %w", err)
 		}
-		c.Response().WriteHeader(http.StatusBadRequest)
 		return nil
 	}
 
-	config, configErr := ch.db.CreateConfig(project.ID, createConfigForm.Name, createConfigForm.NumberOfRequests, createConfigForm.Per)
-	if configErr != nil {
-		log.Fatalf("Failed to create config: %e", configErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
-	}
-
-	encryptedValue, encryptErr := utils.EncryptData(createConfigForm.HeaderValue)
+	encryptedValue, encryptErr := ch.encrypter.Encrypt(c.Request().Context(), createConfigForm.HeaderValue)
 	if encryptErr != nil {
-		log.Fatalf("Failed to encrypt header value: %e", encryptErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to encrypt header value: %w", encryptErr)
 	}
 
-	headeReplacement, headerErr := ch.db.CreateHeaderReplacement(config.ID, createConfigForm.HeaderName, encryptedValue)
-	if headerErr != nil {
-		log.Fatalf("Failed to create header replacement: %e", headerErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+	var config *models.Config
+	txErr := ch.transactor.Transaction(c.Request().Context(), func(ctx context.Context, tx database.Store) error {
+		createdConfig, configErr := tx.CreateConfig(ctx, project.ID, createConfigForm.Name, createConfigForm.NumberOfRequests, createConfigForm.Per)
+		if configErr != nil {
+			return configErr
+		}
+
+		headerReplacement, headerErr := tx.CreateHeaderReplacement(ctx, createdConfig.ID, createConfigForm.HeaderName, encryptedValue)
+		if headerErr != nil {
+			return headerErr
+		}
+
+		createdConfig.HeaderReplacements = append(createdConfig.HeaderReplacements, *headerReplacement)
+		config = createdConfig
+		return nil
+	})
+	if txErr != nil {
+		return InternalError("failed to create config: %w", txErr)
 	}
-	config.HeaderReplacements = append(config.HeaderReplacements, *headeReplacement)
+	ch.dispatcher.Publish(c.Request().Context(), project.ID, models.EventConfigCreated, webhooks.NewConfigPayload(*config))
 
 	component := projects_components.ConfigDetails(*config)
 	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-		log.Fatalf("Error rendering created config: %e", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render created config: %w", err)
+	}
+
+	return nil
+}
+
+func (ch *ConfigHandler) ListConfigs(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	configs, err := ch.configs.ListConfigs(c.Request().Context(), project.ID)
+	if err != nil {
+		return InternalError("failed to list configs: %w", err)
+	}
+
+	component := projects_components.LazyConfigTabs(project.ID, configs)
+	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+		return InternalError("failed to render configs: %w", err)
 	}
 
 	return nil
@@ -110,14 +142,13 @@ func (ch *ConfigHandler) CreateConfig(c echo.Context) error {
 func (ch *ConfigHandler) DeleteConfig(c echo.Context) error {
 	config, ok := c.Get("config").(*models.Config)
 	if !ok {
-		log.Println("Missing config instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing config instance in the context")
 	}
 
-	if deleteErr := ch.db.DeleteConfig(config.ID); deleteErr != nil {
-		log.Fatalf("Failed to delete config: %e", deleteErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+	if deleteErr := ch.configs.DeleteConfig(c.Request().Context(), config.ID); deleteErr != nil {
+		return InternalError("failed to delete config: %w", deleteErr)
 	}
+	ch.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigDeleted, webhooks.NewConfigPayload(*config))
 
 	return nil
 }
@@ -125,23 +156,43 @@ func (ch *ConfigHandler) DeleteConfig(c echo.Context) error {
 func (ch *ConfigHandler) GetConfigConnection(c echo.Context) error {
 	config, ok := c.Get("config").(*models.Config)
 	if !ok {
-		log.Println("Missing config instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing config instance in the context")
 	}
 
 	project, ok := c.Get("project").(*models.Project)
 	if !ok {
-		log.Println("Missing project")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing project")
 	}
 
-	host := os.Getenv("PROXY_HOST")
-	connectionString := fmt.Sprintf("https://%s:%s:%s@%s", config.ID, project.ID, project.AccessKey, host)
-	component := projects_components.ConfigConnectionString(connectionString)
+	conn := connection.New(*config, *project, ch.proxyHost)
+	component := projects_components.ConfigConnection(*config, conn.Snippets())
 	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-		log.Fatalf("Error rendering connection string: %e", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render connection string: %w", err)
 	}
+	ch.metrics.Reveal(metrics.RevealConnectionInfo)
 
 	return nil
 }
+
+// DownloadConfigConnection downloads the .env snippet of the config.
+func (ch *ConfigHandler) DownloadConfigConnection(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project")
+	}
+
+	dotenv, err := connection.New(*config, *project, ch.proxyHost).Snippet(connection.FormatDotenv)
+	if err != nil {
+		return InternalError("failed to write .env snippet: %w", err)
+	}
+	ch.metrics.Reveal(metrics.RevealConnectionInfo)
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": config.Name + ".env"}))
+	return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, []byte(dotenv))
+}
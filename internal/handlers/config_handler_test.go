@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCreateConfig(t *testing.T) {
+	ctx := context.Background()
+
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "project", "", "key", uuid.New())
+	handler := NewConfigHandler(store, store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter), "", nil)
+
+	c, rec := newFormContext(http.MethodPost, "/projects/"+project.ID.String()+"/configs", url.Values{
+		"name":            {"config"},
+		"header-name":     {"Authorization"},
+		"header-value":    {"Bearer secret"},
+		"num-of-requests": {"100"},
+		"requests-per":    {"hour"},
+	})
+	c.Set("project", project)
+	if err := handler.CreateConfig(c); err != nil {
+		t.Fatalf("CreateConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	configs, _ := store.ListConfigs(ctx, project.ID)
+	if len(configs) != 1 {
+		t.Fatalf("expected one config, got %d", len(configs))
+	}
+	config := configs[0]
+	if config.LimitNumberOfRequests != 100 || config.LimitPer != "hour" {
+		t.Fatalf("unexpected limit %d / %s", config.LimitNumberOfRequests, config.LimitPer)
+	}
+	if len(config.HeaderReplacements) != 1 {
+		t.Fatalf("expected one header replacement, got %d", len(config.HeaderReplacements))
+	}
+
+	header := config.HeaderReplacements[0]
+	if header.HeaderValue == "Bearer secret" {
+		t.Fatalf("expected the header value to be stored encrypted")
+	}
+	if value, _ := testEncrypter.Decrypt(ctx, header.HeaderValue); value != "Bearer secret" {
+		t.Fatalf("expected the header value to decrypt, got %q", value)
+	}
+}
+
+func TestCreateConfigValidation(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "project", "", "key", uuid.New())
+	handler := NewConfigHandler(store, store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter), "", nil)
+
+	c, rec := newFormContext(http.MethodPost, "/projects/"+project.ID.String()+"/configs", url.Values{
+		"name":         {"config"},
+		"requests-per": {"fortnight"},
+	})
+	c.Set("project", project)
+	if err := handler.CreateConfig(c); err != nil {
+		t.Fatalf("CreateConfig returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	if configs, _ := store.ListConfigs(ctx, project.ID); len(configs) != 0 {
+		t.Fatalf("expected no config to be created")
+	}
+}
+
+// failingHeaderStore fails every header replacement insert, inside and
+// outside of transactions.
+type failingHeaderStore struct {
+	database.Store
+}
+
+func (f failingHeaderStore) CreateHeaderReplacement(ctx context.Context, configID uuid.UUID, name string, value string) (*models.HeaderReplacement, error) {
+	return nil, errors.New("insert failed")
+}
+
+func (f failingHeaderStore) Transaction(ctx context.Context, fn func(ctx context.Context, tx database.Store) error) error {
+	return f.Store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		return fn(ctx, failingHeaderStore{tx})
+	})
+}
+
+func TestCreateConfigRollsBack(t *testing.T) {
+	ctx := context.Background()
+
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "project", "", "key", uuid.New())
+	failing := failingHeaderStore{store}
+	handler := NewConfigHandler(failing, failing, testEncrypter, webhooks.NewDispatcher(failing, testEncrypter), "", nil)
+
+	c, _ := newFormContext(http.MethodPost, "/projects/"+project.ID.String()+"/configs", url.Values{
+		"name":            {"config"},
+		"header-name":     {"Authorization"},
+		"header-value":    {"Bearer secret"},
+		"num-of-requests": {"100"},
+		"requests-per":    {"hour"},
+	})
+	c.Set("project", project)
+	err := handler.CreateConfig(c)
+
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected an internal server error, got %v", err)
+	}
+	if configs, _ := store.ListConfigs(ctx, project.ID); len(configs) != 0 {
+		t.Fatalf("expected the config to be rolled back, got %d configs", len(configs))
+	}
+}
+
+func TestDownloadConfigConnection(t *testing.T) {
+	store := memstore.New()
+	project := &models.Project{ID: uuid.New(), Name: "payments", AccessKey: "key"}
+	config := &models.Config{ID: uuid.New(), ProjectID: project.ID, Name: "stripe"}
+	handler := NewConfigHandler(store, store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter), "proxy.example.com", nil)
+
+	c, rec := newFormContext(http.MethodGet, "/projects/"+project.ID.String()+"/configs/"+config.ID.String()+"/connection/dotenv", nil)
+	c.Set("project", project)
+	c.Set("config", config)
+	if err := handler.DownloadConfigConnection(c); err != nil {
+		t.Fatalf("DownloadConfigConnection returned error: %v", err)
+	}
+	if disposition := rec.Header().Get(echo.HeaderContentDisposition); disposition != `attachment; filename=stripe.env` {
+		t.Fatalf("unexpected Content-Disposition %q", disposition)
+	}
+	want := "HTTPS_PROXY=https://" + config.ID.String() + ":" + project.ID.String() + ":key@proxy.example.com\n"
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("expected the .env snippet to contain %q, got %q", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InternalError hides the cause from the client, it is logged with the
+// request by the server error handler.
+func InternalError(format string, args ...any) *echo.HTTPError {
+	return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(fmt.Errorf(format, args...))
+}
@@ -3,10 +3,12 @@ package handlers
 import (
 	"configuration-management/internal/database"
 	"configuration-management/internal/forms"
+	"configuration-management/internal/metrics"
 	"configuration-management/internal/models"
 	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
 	"configuration-management/web/projects_components"
-	"log"
+	"fmt"
 	"net/http"
 
 	"github.com/go-playground/form/v4"
@@ -20,14 +22,18 @@ type CreateHeaderReplacementForm struct {
 }
 
 type HeaderReplacementsHandler struct {
-	db       *database.DatabaseHandler
-	decoder  *form.Decoder
-	validate *validator.Validate
+	headers    database.HeaderStore
+	encrypter  *utils.Encrypter
+	dispatcher *webhooks.Dispatcher
+	metrics    *metrics.Metrics
+	decoder    *form.Decoder
+	validate   *validator.Validate
 }
 
-func NewHeaderReplacementsHandler(db *database.DatabaseHandler) *HeaderReplacementsHandler {
+func NewHeaderReplacementsHandler(headers database.HeaderStore, encrypter *utils.Encrypter,
+	dispatcher *webhooks.Dispatcher, metrics *metrics.Metrics) *HeaderReplacementsHandler {
 	validate := validator.New(validator.WithRequiredStructEnabled())
-	return &HeaderReplacementsHandler{db, form.NewDecoder(), validate}
+	return &HeaderReplacementsHandler{headers, encrypter, dispatcher, metrics, form.NewDecoder(), validate}
 }
 
 func (h *HeaderReplacementsHandler) processForm(c echo.Context) (*CreateHeaderReplacementForm, forms.FormErrors, error) {
@@ -37,8 +43,7 @@ func (h *HeaderReplacementsHandler) processForm(c echo.Context) (*CreateHeaderRe
 
 	var headerForm CreateHeaderReplacementForm
 	if err := h.decoder.Decode(&headerForm, c.Request().Form); err != nil {
-		log.Fatalf("Error decoding CreateHeaderReplacementForm: %e", err)
-		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateHeaderReplacementForm: %w", err))
 	}
 
 	if validationErr := h.validate.Struct(headerForm); validationErr != nil {
@@ -56,14 +61,12 @@ func (h *HeaderReplacementsHandler) processForm(c echo.Context) (*CreateHeaderRe
 func (h *HeaderReplacementsHandler) CreateHeaderReplacement(c echo.Context) error {
 	project, ok := c.Get("project").(*models.Project)
 	if !ok {
-		log.Println("Missing project instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing project instance in the context")
 	}
 
 	config, ok := c.Get("config").(*models.Config)
 	if !ok {
-		log.Println("Missing config instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing config instance in the context")
 	}
 
 	headerForm, formErrs, processingErr := h.processForm(c)
@@ -74,47 +77,48 @@ func (h *HeaderReplacementsHandler) CreateHeaderReplacement(c echo.Context) erro
 	if formErrs != nil {
 		c.Response().Header().Set("HX-Reswap", "outerHTML")
 		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetCreateHeaderFormID(config.ID))
+		c.Response().WriteHeader(http.StatusBadRequest)
 		component := projects_components.CreateHeaderReplacement(project.ID, config.ID, formErrs)
 		if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-			log.Fatalf("Error rendering created header replacement: %e", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return InternalError("failed to render created header replacement: %w", err)
 		}
-		c.Response().WriteHeader(http.StatusBadRequest)
 		return nil
 	}
 
-	encryptedValue, encryptErr := utils.EncryptData(headerForm.HeaderValue)
+	encryptedValue, encryptErr := h.encrypter.Encrypt(c.Request().Context(), headerForm.HeaderValue)
 	if encryptErr != nil {
-		log.Fatalf("Failed to encrypt header value: %e", encryptErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to encrypt header value: %w", encryptErr)
 	}
 
-	replacement, replacementErr := h.db.CreateHeaderReplacement(config.ID, headerForm.HeaderName, encryptedValue)
+	replacement, replacementErr := h.headers.CreateHeaderReplacement(c.Request().Context(), config.ID, headerForm.HeaderName, encryptedValue)
 	if replacementErr != nil {
-		log.Fatalf("Failed to create headerReplacement: %e", replacementErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to create header replacement: %w", replacementErr)
 	}
+	h.dispatcher.Publish(c.Request().Context(), project.ID, models.EventHeaderCreated, webhooks.NewHeaderPayload(*replacement))
 
 	component := projects_components.HeaderReplacement(project.ID, *replacement)
 	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-		log.Fatalf("Error rendering created header replacement: %e", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render created header replacement: %w", err)
 	}
 
 	return nil
 }
 
 func (h *HeaderReplacementsHandler) DeleteHeaderReplacement(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
 	header, ok := c.Get("header").(*models.HeaderReplacement)
 	if !ok {
-		log.Println("Missing header replacement instance in the config")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing header replacement instance in the config")
 	}
 
-	if deleteErr := h.db.DeleteHeaderReplacement(header.ID); deleteErr != nil {
-		log.Fatalf("Failed to delete header: %e", deleteErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+	if deleteErr := h.headers.DeleteHeaderReplacement(c.Request().Context(), header.ID); deleteErr != nil {
+		return InternalError("failed to delete header: %w", deleteErr)
 	}
+	h.dispatcher.Publish(c.Request().Context(), project.ID, models.EventHeaderDeleted, webhooks.NewHeaderPayload(*header))
 
 	return nil
 }
@@ -122,15 +126,14 @@ func (h *HeaderReplacementsHandler) DeleteHeaderReplacement(c echo.Context) erro
 func (h *HeaderReplacementsHandler) GetHeaderReplacementValue(c echo.Context) error {
 	header, ok := c.Get("header").(*models.HeaderReplacement)
 	if !ok {
-		log.Println("Missing header replacement instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing header replacement instance in the context")
 	}
 
-	decryptedHeaderValue, err := utils.DecryptData(header.HeaderValue)
+	decryptedHeaderValue, err := h.encrypter.Decrypt(c.Request().Context(), header.HeaderValue)
 	if err != nil {
-		log.Fatalf("failed to decrypt header value: %e", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to decrypt header value: %w", err)
 	}
+	h.metrics.Reveal(metrics.RevealHeaderValue)
 
 	return c.String(http.StatusOK, decryptedHeaderValue)
 }
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web/projects_components"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type DisableForm struct {
+	Reason string `form:"reason" validate:"required,max=255"`
+}
+
+// KillSwitchHandler stops the proxy from serving a config, or a whole
+// project, without losing its setup.
+type KillSwitchHandler struct {
+	configs    database.ConfigStore
+	projects   database.ProjectStore
+	transactor database.Transactor
+	dispatcher *webhooks.Dispatcher
+	decoder    *form.Decoder
+	validate   *validator.Validate
+}
+
+func NewKillSwitchHandler(configs database.ConfigStore, projects database.ProjectStore, transactor database.Transactor,
+	dispatcher *webhooks.Dispatcher) *KillSwitchHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &KillSwitchHandler{configs, projects, transactor, dispatcher, form.NewDecoder(), validate}
+}
+
+// processForm records the logged in user as who disabled it.
+func (h *KillSwitchHandler) processForm(c echo.Context) (*models.Disablement, forms.FormErrors, error) {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return nil, nil, InternalError("missing user")
+	}
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	var disableForm DisableForm
+	if err := h.decoder.Decode(&disableForm, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode DisableForm: %w", err))
+	}
+
+	if validationErr := h.validate.Struct(disableForm); validationErr != nil {
+		errors := make(forms.FormErrors)
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+		return nil, errors, nil
+	}
+
+	return &models.Disablement{At: time.Now().UTC(), Reason: disableForm.Reason, By: &user.ID}, nil, nil
+}
+
+func (h *KillSwitchHandler) DisableConfig(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	disablement, formErrs, processingErr := h.processForm(c)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetConfigAvailabilityID(config.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.ConfigAvailability(*config, formErrs))
+	}
+
+	disabled, err := h.configs.DisableConfig(c.Request().Context(), config.ID, *disablement)
+	if err != nil {
+		return InternalError("failed to disable config: %w", err)
+	}
+	if !disabled {
+		// Disabled meanwhile, show who did and why.
+		if config, err = h.configs.GetConfig(c.Request().Context(), config.ID); err != nil {
+			return InternalError("failed to get config: %w", err)
+		}
+		return renderComponent(c, http.StatusOK, projects_components.ConfigAvailability(*config, nil))
+	}
+	config.Disabled = disablement
+	h.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigDisabled, webhooks.NewConfigPayload(*config))
+
+	return renderComponent(c, http.StatusOK, projects_components.ConfigAvailability(*config, nil))
+}
+
+// EnableConfig refuses an expired config, the expiry worker would disable it
+// again, a new expiry has to be set in its availability instead.
+func (h *KillSwitchHandler) EnableConfig(c echo.Context) error {
+	config, ok := c.Get("config").(*models.Config)
+	if !ok {
+		return InternalError("missing config instance in the context")
+	}
+
+	if config.ExpiresAt != nil && !config.ExpiresAt.After(time.Now()) {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetConfigAvailabilityID(config.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.ConfigAvailability(*config, forms.FormErrors{"ExpiresAt": "future"}))
+	}
+
+	if err := h.configs.EnableConfig(c.Request().Context(), config.ID); err != nil {
+		return InternalError("failed to enable config: %w", err)
+	}
+	config.Disabled = nil
+	h.dispatcher.Publish(c.Request().Context(), config.ProjectID, models.EventConfigEnabled, webhooks.NewConfigPayload(*config))
+
+	return renderComponent(c, http.StatusOK, projects_components.ConfigAvailability(*config, nil))
+}
+
+// DisableProject keeps the state of each config, so that enabling the
+// project again serves the same configs as before.
+func (h *KillSwitchHandler) DisableProject(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	disablement, formErrs, processingErr := h.processForm(c)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetProjectKillSwitchID(project.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.ProjectKillSwitch(*project, formErrs, ""))
+	}
+
+	disabled, err := h.projects.DisableProject(c.Request().Context(), project.ID, *disablement)
+	if err != nil {
+		return InternalError("failed to disable project: %w", err)
+	}
+	if !disabled {
+		// Disabled meanwhile, show who did and why.
+		if project, err = h.projects.GetProject(c.Request().Context(), project.ID); err != nil {
+			return InternalError("failed to get project: %w", err)
+		}
+		return renderComponent(c, http.StatusOK, projects_components.ProjectKillSwitch(*project, nil, ""))
+	}
+	project.Disabled = disablement
+	h.dispatcher.Publish(c.Request().Context(), project.ID, models.EventProjectDisabled, webhooks.NewProjectPayload(*project))
+
+	return renderComponent(c, http.StatusOK, projects_components.ProjectKillSwitch(*project, nil, ""))
+}
+
+func (h *KillSwitchHandler) EnableProject(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	if err := h.projects.EnableProject(c.Request().Context(), project.ID); err != nil {
+		return InternalError("failed to enable project: %w", err)
+	}
+	project.Disabled = nil
+	h.dispatcher.Publish(c.Request().Context(), project.ID, models.EventProjectEnabled, webhooks.NewProjectPayload(*project))
+
+	return renderComponent(c, http.StatusOK, projects_components.ProjectKillSwitch(*project, nil, ""))
+}
+
+// DisableProjectConfigs disables every config of the project that is not
+// disabled yet, all of them or none.
+func (h *KillSwitchHandler) DisableProjectConfigs(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	disablement, formErrs, processingErr := h.processForm(c)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetProjectKillSwitchID(project.ID))
+		return renderComponent(c, http.StatusBadRequest, projects_components.ProjectKillSwitch(*project, formErrs, ""))
+	}
+
+	var disabled []models.Config
+	txErr := h.transactor.Transaction(c.Request().Context(), func(ctx context.Context, tx database.Store) error {
+		configs, err := tx.ListConfigs(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		for _, config := range configs {
+			if config.Disabled != nil {
+				continue
+			}
+			updated, err := tx.DisableConfig(ctx, config.ID, *disablement)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				continue
+			}
+			config.Disabled = disablement
+			disabled = append(disabled, config)
+		}
+		return nil
+	})
+	if txErr != nil {
+		return InternalError("failed to disable the configs of the project: %w", txErr)
+	}
+	for _, config := range disabled {
+		h.dispatcher.Publish(c.Request().Context(), project.ID, models.EventConfigDisabled, webhooks.NewConfigPayload(config))
+	}
+
+	return renderComponent(c, http.StatusOK, projects_components.DisabledProjectConfigs(*project, disabled))
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDisableConfig(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user := &models.User{ID: uuid.New(), Provider: "local", Subject: "alice", Name: "Alice"}
+	project, _ := store.CreateProject(ctx, "payments", "", "key", user.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	handler := NewKillSwitchHandler(store, store, store, webhooks.NewDispatcher(store, testEncrypter))
+
+	userNames := func(ctx context.Context, userID uuid.UUID) string {
+		if userID == user.ID {
+			return user.Name
+		}
+		return ""
+	}
+
+	c, rec := newFormContext(http.MethodPost, "/disable", url.Values{"reason": {"leaked key"}})
+	c.SetRequest(c.Request().WithContext(web.WithUserNames(ctx, userNames)))
+	c.Set("user", user)
+	c.Set("config", config)
+	if err := handler.DisableConfig(c); err != nil {
+		t.Fatalf("DisableConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "by Alice: leaked key") {
+		t.Fatalf("expected the disabled state in the response, got %d %s", rec.Code, rec.Body.String())
+	}
+	if served, _ := store.IsConfigServed(ctx, config.ID); served {
+		t.Fatal("expected the disabled config not to be served")
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/disable", url.Values{"reason": {"other"}})
+	c.SetRequest(c.Request().WithContext(web.WithUserNames(ctx, userNames)))
+	c.Set("user", user)
+	c.Set("config", config)
+	if err := handler.DisableConfig(c); err != nil {
+		t.Fatalf("DisableConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "by Alice: leaked key") {
+		t.Fatalf("expected the first disable to be kept, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	config, _ = store.GetConfig(ctx, config.ID)
+	c, _ = newFormContext(http.MethodPost, "/enable", url.Values{})
+	c.Set("user", user)
+	c.Set("config", config)
+	if err := handler.EnableConfig(c); err != nil {
+		t.Fatalf("EnableConfig returned error: %v", err)
+	}
+	if served, _ := store.IsConfigServed(ctx, config.ID); !served {
+		t.Fatal("expected the enabled config to be served")
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/disable", url.Values{"reason": {""}})
+	c.Set("user", user)
+	c.Set("config", config)
+	if err := handler.DisableConfig(c); err != nil {
+		t.Fatalf("DisableConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a reason to be required, got status %d", rec.Code)
+	}
+}
+
+func TestEnableExpiredConfig(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user := &models.User{ID: uuid.New(), Provider: "local", Subject: "alice"}
+	project, _ := store.CreateProject(ctx, "payments", "", "key", user.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	expiresAt := time.Now().UTC().Add(-time.Hour)
+	store.UpdateConfigAvailability(ctx, config.ID, nil, &expiresAt)
+	store.DisableConfig(ctx, config.ID, models.Disablement{At: time.Now().UTC(), Reason: models.DisabledReasonExpired})
+	handler := NewKillSwitchHandler(store, store, store, webhooks.NewDispatcher(store, testEncrypter))
+
+	config, _ = store.GetConfig(ctx, config.ID)
+	c, rec := newFormContext(http.MethodPost, "/enable", url.Values{})
+	c.Set("user", user)
+	c.Set("config", config)
+	if err := handler.EnableConfig(c); err != nil {
+		t.Fatalf("EnableConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "future") {
+		t.Fatalf("expected a new expiry to be asked for, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got, _ := store.GetConfig(ctx, config.ID); got.Disabled == nil {
+		t.Fatal("expected the expired config to stay disabled")
+	}
+}
+
+func TestDisableProject(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user := &models.User{ID: uuid.New(), Provider: "local", Subject: "alice"}
+	project, _ := store.CreateProject(ctx, "payments", "", "key", user.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	handler := NewKillSwitchHandler(store, store, store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, rec := newFormContext(http.MethodPost, "/disable", url.Values{"reason": {"incident"}})
+	c.Set("user", user)
+	c.Set("project", project)
+	if err := handler.DisableProject(c); err != nil {
+		t.Fatalf("DisableProject returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	disabled, _ := store.GetProject(ctx, project.ID)
+	if disabled.Disabled == nil || disabled.Disabled.Reason != "incident" || disabled.Disabled.By == nil || *disabled.Disabled.By != user.ID {
+		t.Fatalf("unexpected disabled project %+v", disabled)
+	}
+	if served, _ := store.IsConfigServed(ctx, config.ID); served {
+		t.Fatal("expected the configs of a disabled project not to be served")
+	}
+
+	c, _ = newFormContext(http.MethodPost, "/enable", url.Values{})
+	c.Set("user", user)
+	c.Set("project", disabled)
+	if err := handler.EnableProject(c); err != nil {
+		t.Fatalf("EnableProject returned error: %v", err)
+	}
+	if served, _ := store.IsConfigServed(ctx, config.ID); !served {
+		t.Fatal("expected the configs of an enabled project to be served")
+	}
+}
+
+func TestDisableProjectConfigs(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user := &models.User{ID: uuid.New(), Provider: "local", Subject: "alice", Name: "Alice"}
+	project, _ := store.CreateProject(ctx, "payments", "", "key", user.ID)
+	stripe, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	paypal, _ := store.CreateConfig(ctx, project.ID, "paypal", 100, "hour")
+	expired := models.Disablement{Reason: models.DisabledReasonExpired}
+	store.DisableConfig(ctx, paypal.ID, expired)
+	handler := NewKillSwitchHandler(store, store, store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, rec := newFormContext(http.MethodPost, "/configs/disable", url.Values{"reason": {"incident"}})
+	c.Set("user", user)
+	c.Set("project", project)
+	if err := handler.DisableProjectConfigs(c); err != nil {
+		t.Fatalf("DisableProjectConfigs returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Disabled 1 config") {
+		t.Fatalf("expected the configs not disabled yet to be disabled, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if config, _ := store.GetConfig(ctx, stripe.ID); config.Disabled == nil || config.Disabled.Reason != "incident" {
+		t.Fatalf("expected the config to be disabled, got %+v", config)
+	}
+	if config, _ := store.GetConfig(ctx, paypal.ID); config.Disabled == nil || config.Disabled.Reason != models.DisabledReasonExpired {
+		t.Fatalf("expected the config disabled already to keep its reason, got %+v", config)
+	}
+}
@@ -0,0 +1,575 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/metrics"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/web/login_components"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// totpLoginTimeout bounds the time between a correct password and the
+	// second factor code.
+	totpLoginTimeout = 5 * time.Minute
+	// maxTOTPFailures invalid codes in a row lock the second factor of an
+	// account for totpLockout, the count is kept with the credentials since
+	// a session cookie can be replayed.
+	maxTOTPFailures = 5
+	totpLockout     = 15 * time.Minute
+)
+
+type LocalLoginForm struct {
+	Username string `form:"username" validate:"required"`
+	Password string `form:"password" validate:"required"`
+}
+
+type TOTPForm struct {
+	Code string `form:"code" validate:"required,numeric,len=6"`
+}
+
+type SignupForm struct {
+	Token           string `form:"token"`
+	Username        string `form:"username" validate:"required,alphanum,min=3,max=64"`
+	Name            string `form:"name" validate:"max=255"`
+	Email           string `form:"email" validate:"omitempty,email,max=255"`
+	Password        string `form:"password" validate:"required,min=12,max=72"`
+	ConfirmPassword string `form:"confirm-password" validate:"eqfield=Password"`
+}
+
+type ForgotPasswordForm struct {
+	Username string `form:"username" validate:"required"`
+}
+
+type ResetPasswordForm struct {
+	Token           string `form:"token" validate:"required"`
+	Password        string `form:"password" validate:"required,min=12,max=72"`
+	ConfirmPassword string `form:"confirm-password" validate:"eqfield=Password"`
+}
+
+// LocalAuthHandler signs users in with a username and a password, followed
+// by a TOTP code when they enabled the second factor.
+type LocalAuthHandler struct {
+	local auth.LocalAuth
+	// baseURL is the public address used in the emailed links.
+	baseURL       string
+	store         database.Store
+	encrypter     *utils.Encrypter
+	sessionPolicy auth.SessionPolicy
+	// mailer is nil when SMTP is not configured, password reset links are
+	// then not delivered.
+	mailer   *mail.Mailer
+	metrics  *metrics.Metrics
+	decoder  *form.Decoder
+	validate *validator.Validate
+}
+
+func NewLocalAuthHandler(local auth.LocalAuth, baseURL string, store database.Store, encrypter *utils.Encrypter,
+	sessionPolicy auth.SessionPolicy, mailer *mail.Mailer, metrics *metrics.Metrics) *LocalAuthHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &LocalAuthHandler{local, baseURL, store, encrypter, sessionPolicy, mailer, metrics, form.NewDecoder(), validate}
+}
+
+// processForm decodes and validates the request form, validation errors
+// are returned by field.
+func processForm[T any](c echo.Context, decoder *form.Decoder, validate *validator.Validate) (*T, forms.FormErrors, error) {
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+	var values T
+	if err := decoder.Decode(&values, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode %T: %w", values, err))
+	}
+
+	if validationErr := validate.Struct(values); validationErr != nil {
+		errors := make(forms.FormErrors)
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+
+		return &values, errors, nil
+	}
+	return &values, nil, nil
+}
+
+func renderComponent(c echo.Context, status int, component templ.Component) error {
+	if status != http.StatusOK {
+		c.Response().WriteHeader(status)
+	}
+	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+		return InternalError("failed to render %T: %w", component, err)
+	}
+
+	return nil
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func (l *LocalAuthHandler) Login(c echo.Context) error {
+	loginForm, formErrors, err := processForm[LocalLoginForm](c, l.decoder, l.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, login_components.LocalLoginForm(formErrors))
+	}
+
+	credentials, err := l.store.GetCredentials(c.Request().Context(), normalizeUsername(loginForm.Username))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return InternalError("failed to get credentials: %w", err)
+	}
+	passwordHash := ""
+	if credentials != nil {
+		passwordHash = credentials.PasswordHash
+	}
+	if !auth.CheckPassword(passwordHash, loginForm.Password) {
+		l.metrics.Login(models.ProviderLocal, metrics.LoginFailed)
+		formErrors := forms.FormErrors{"Password": "Invalid username or password"}
+		return renderComponent(c, http.StatusBadRequest, login_components.LocalLoginForm(formErrors))
+	}
+
+	if credentials.TOTPEnabled {
+		sess, err := session.Get("session", c)
+		if err != nil {
+			return InternalError("failed to get session cookie: %w", err)
+		}
+		sess.Values["pending_user_id"] = credentials.UserID.String()
+		sess.Values["pending_since"] = time.Now().Unix()
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return InternalError("failed to update session in request: %w", err)
+		}
+
+		c.Response().Header().Set("HX-Redirect", "/login/totp")
+		return c.NoContent(http.StatusOK)
+	}
+
+	if err := startUserSession(c, l.store, l.sessionPolicy, credentials.UserID); err != nil {
+		return err
+	}
+	l.metrics.Login(models.ProviderLocal, metrics.LoginSucceeded)
+
+	c.Response().Header().Set("HX-Redirect", "/projects")
+	return c.NoContent(http.StatusOK)
+}
+
+// pendingUser returns the user who entered a correct password and still
+// has to enter a TOTP code.
+func (l *LocalAuthHandler) pendingUser(c echo.Context) (uuid.UUID, bool) {
+	sess, err := session.Get("session", c)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	since, ok := sess.Values["pending_since"].(int64)
+	if !ok || time.Since(time.Unix(since, 0)) > totpLoginTimeout {
+		return uuid.Nil, false
+	}
+	userIDString, ok := sess.Values["pending_user_id"].(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+func (l *LocalAuthHandler) TOTPLogin(c echo.Context) error {
+	if _, ok := l.pendingUser(c); !ok {
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+
+	return renderComponent(c, http.StatusOK, login_components.TOTPLogin())
+}
+
+func (l *LocalAuthHandler) VerifyTOTPLogin(c echo.Context) error {
+	userID, ok := l.pendingUser(c)
+	if !ok {
+		c.Response().Header().Set("HX-Redirect", "/login")
+		return c.NoContent(http.StatusOK)
+	}
+
+	totpForm, formErrors, err := processForm[TOTPForm](c, l.decoder, l.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, login_components.TOTPLoginForm(formErrors))
+	}
+
+	credentials, err := l.store.GetUserCredentials(c.Request().Context(), userID)
+	if err != nil {
+		return InternalError("failed to get credentials: %w", err)
+	}
+	valid, locked, err := verifyTOTP(c.Request().Context(), l.store, l.encrypter, credentials, totpForm.Code)
+	if err != nil {
+		return err
+	}
+	if locked {
+		// the password has to be entered again once the lockout is over
+		l.metrics.Login(models.ProviderLocal, metrics.LoginFailed)
+		sess, err := session.Get("session", c)
+		if err != nil {
+			return InternalError("failed to get session cookie: %w", err)
+		}
+		delete(sess.Values, "pending_user_id")
+		delete(sess.Values, "pending_since")
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return InternalError("failed to update session in request: %w", err)
+		}
+		formErrors := forms.FormErrors{"Code": "Too many invalid codes, sign in again later"}
+		return renderComponent(c, http.StatusBadRequest, login_components.TOTPLoginForm(formErrors))
+	}
+	if !valid {
+		l.metrics.Login(models.ProviderLocal, metrics.LoginFailed)
+		formErrors := forms.FormErrors{"Code": "Invalid code"}
+		return renderComponent(c, http.StatusBadRequest, login_components.TOTPLoginForm(formErrors))
+	}
+
+	sess, err := session.Get("session", c)
+	if err != nil {
+		return InternalError("failed to get session cookie: %w", err)
+	}
+	delete(sess.Values, "pending_user_id")
+	delete(sess.Values, "pending_since")
+	if err := startUserSession(c, l.store, l.sessionPolicy, userID); err != nil {
+		return err
+	}
+	l.metrics.Login(models.ProviderLocal, metrics.LoginSucceeded)
+
+	c.Response().Header().Set("HX-Redirect", "/projects")
+	return c.NoContent(http.StatusOK)
+}
+
+// verifyTOTP checks a second factor code of the account. A code is accepted
+// once, and after maxTOTPFailures invalid codes in a row the account is
+// locked for totpLockout, locked accounts do not get to try a code.
+func verifyTOTP(ctx context.Context, store database.CredentialStore, encrypter *utils.Encrypter,
+	credentials *models.Credentials, code string) (bool, bool, error) {
+	now := time.Now()
+	if credentials.TOTPFailures >= maxTOTPFailures && credentials.TOTPFailedAt != nil &&
+		now.Sub(*credentials.TOTPFailedAt) < totpLockout {
+		return false, true, nil
+	}
+
+	secret, err := encrypter.Decrypt(ctx, credentials.TOTPSecret)
+	if err != nil {
+		return false, false, InternalError("failed to decrypt totp secret: %w", err)
+	}
+	step, valid := auth.ValidateTOTP(code, secret, now)
+	if valid {
+		if valid, err = store.UseTOTPStep(ctx, credentials.UserID, step); err != nil {
+			return false, false, InternalError("failed to use totp code: %w", err)
+		}
+	}
+	if valid {
+		return true, false, nil
+	}
+
+	failures, err := store.RecordTOTPFailure(ctx, credentials.UserID, now)
+	if err != nil {
+		return false, false, InternalError("failed to record totp failure: %w", err)
+	}
+	return false, failures >= maxTOTPFailures, nil
+}
+
+// validToken returns the unused and unexpired token of the kind, or nil
+// when there is none.
+func (l *LocalAuthHandler) validToken(ctx context.Context, kind string, token string) (*models.AuthToken, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	authToken, err := l.store.GetAuthToken(ctx, kind, auth.HashAuthToken(token))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if authToken.UsedAt != nil || !authToken.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+
+	return authToken, nil
+}
+
+var errSignupClosed = errors.New("an invite is required to sign up")
+
+// signupAllowed reports whether an account can be created without an
+// invite.
+func (l *LocalAuthHandler) signupAllowed(ctx context.Context) (bool, error) {
+	if l.local.Signup == auth.SignupOpen {
+		return true, nil
+	}
+
+	count, err := l.store.CountCredentials(ctx)
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+func (l *LocalAuthHandler) Signup(c echo.Context) error {
+	ctx := c.Request().Context()
+	token := c.QueryParam("token")
+	invite, err := l.validToken(ctx, models.AuthTokenInvite, token)
+	if err != nil {
+		return InternalError("failed to get invite: %w", err)
+	}
+	allowed, err := l.signupAllowed(ctx)
+	if err != nil {
+		return InternalError("failed to count local accounts: %w", err)
+	}
+
+	signup := login_components.SignupPage{Allowed: allowed || invite != nil}
+	if invite != nil {
+		signup.Token = token
+		signup.Email = invite.Email
+	}
+	return renderComponent(c, http.StatusOK, login_components.Signup(signup))
+}
+
+func (l *LocalAuthHandler) CreateAccount(c echo.Context) error {
+	ctx := c.Request().Context()
+	signupForm, formErrors, err := processForm[SignupForm](c, l.decoder, l.validate)
+	if err != nil {
+		return err
+	}
+	renderErrors := func(formErrors forms.FormErrors) error {
+		page := login_components.SignupPage{Allowed: true, Token: signupForm.Token, Email: signupForm.Email}
+		return renderComponent(c, http.StatusBadRequest, login_components.SignupForm(page, formErrors))
+	}
+	if formErrors != nil {
+		return renderErrors(formErrors)
+	}
+
+	invite, err := l.validToken(ctx, models.AuthTokenInvite, signupForm.Token)
+	if err != nil {
+		return InternalError("failed to get invite: %w", err)
+	}
+	if invite == nil {
+		allowed, err := l.signupAllowed(ctx)
+		if err != nil {
+			return InternalError("failed to count local accounts: %w", err)
+		}
+		if !allowed {
+			return echo.NewHTTPError(http.StatusForbidden, "An invite is required to sign up")
+		}
+	}
+
+	username := normalizeUsername(signupForm.Username)
+	if _, err := l.store.GetCredentials(ctx, username); err == nil {
+		return renderErrors(forms.FormErrors{"Username": "This username is taken"})
+	} else if !errors.Is(err, database.ErrNotFound) {
+		return InternalError("failed to get credentials: %w", err)
+	}
+
+	passwordHash, err := auth.HashPassword(signupForm.Password)
+	if err != nil {
+		return InternalError("%w", err)
+	}
+	name := signupForm.Name
+	if name == "" {
+		name = username
+	}
+
+	// Without an invite only the first account can be created, unless the
+	// sign-up is open. Concurrent sign-ups are checked again in the
+	// transaction, and the unique first account makes one of them fail.
+	firstAccount := invite == nil && l.local.Signup != auth.SignupOpen
+	var user *models.User
+	txErr := l.store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if firstAccount {
+			count, err := tx.CountCredentials(ctx)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return errSignupClosed
+			}
+		}
+
+		var err error
+		user, err = tx.CreateUser(ctx, models.User{
+			Provider: models.ProviderLocal,
+			Subject:  username,
+			Name:     name,
+			Email:    signupForm.Email,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateCredentials(ctx, models.Credentials{
+			UserID:       user.ID,
+			Username:     username,
+			PasswordHash: passwordHash,
+			FirstAccount: firstAccount,
+		}); err != nil {
+			return err
+		}
+		if invite != nil {
+			used, err := tx.UseAuthToken(ctx, invite.ID, time.Now())
+			if err != nil {
+				return err
+			}
+			if !used {
+				return fmt.Errorf("invite %s was already used", invite.ID)
+			}
+		}
+		return nil
+	})
+	if errors.Is(txErr, errSignupClosed) {
+		return echo.NewHTTPError(http.StatusForbidden, "An invite is required to sign up")
+	}
+	if txErr != nil {
+		return InternalError("failed to create local account: %w", txErr)
+	}
+
+	if err := startUserSession(c, l.store, l.sessionPolicy, user.ID); err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/projects")
+	return c.NoContent(http.StatusOK)
+}
+
+func (l *LocalAuthHandler) ForgotPassword(c echo.Context) error {
+	return renderComponent(c, http.StatusOK, login_components.ForgotPassword(l.mailer != nil))
+}
+
+// RequestPasswordReset emails a reset link when the account has an email
+// address. The response is the same whether the account exists or not.
+func (l *LocalAuthHandler) RequestPasswordReset(c echo.Context) error {
+	ctx := c.Request().Context()
+	forgotForm, formErrors, err := processForm[ForgotPasswordForm](c, l.decoder, l.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, login_components.ForgotPasswordForm(formErrors))
+	}
+
+	if err := l.sendPasswordReset(ctx, normalizeUsername(forgotForm.Username)); err != nil {
+		logging.FromContext(ctx).Error("failed to send password reset", "error", err)
+	}
+
+	return renderComponent(c, http.StatusOK, login_components.PasswordResetRequested())
+}
+
+func (l *LocalAuthHandler) sendPasswordReset(ctx context.Context, username string) error {
+	credentials, err := l.store.GetCredentials(ctx, username)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user, err := l.store.GetUser(ctx, credentials.UserID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" || l.mailer == nil {
+		logging.FromContext(ctx).Info("password reset requested for an account which cannot receive email", "username", username)
+		return nil
+	}
+
+	token, tokenHash, err := auth.NewAuthToken()
+	if err != nil {
+		return err
+	}
+	if _, err := l.store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenPasswordReset,
+		TokenHash: tokenHash,
+		UserID:    &user.ID,
+		ExpiresAt: time.Now().Add(l.local.PasswordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	link := l.baseURL + "/password/reset?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("A password reset was requested for %s.\n\nOpen %s within %s to choose a new password. "+
+		"You can ignore this email if you did not request it.", username, link, l.local.PasswordResetTTL)
+	return l.mailer.Send(user.Email, "[API Key Limiter] Reset your password", body)
+}
+
+func (l *LocalAuthHandler) ResetPassword(c echo.Context) error {
+	token := c.QueryParam("token")
+	reset, err := l.validToken(c.Request().Context(), models.AuthTokenPasswordReset, token)
+	if err != nil {
+		return InternalError("failed to get password reset: %w", err)
+	}
+	if reset == nil {
+		token = ""
+	}
+
+	return renderComponent(c, http.StatusOK, login_components.ResetPassword(token))
+}
+
+// ConfirmPasswordReset sets the new password and signs the user out of
+// every session.
+func (l *LocalAuthHandler) ConfirmPasswordReset(c echo.Context) error {
+	ctx := c.Request().Context()
+	resetForm, formErrors, err := processForm[ResetPasswordForm](c, l.decoder, l.validate)
+	if err != nil {
+		return err
+	}
+	if formErrors != nil {
+		return renderComponent(c, http.StatusBadRequest, login_components.ResetPasswordForm(resetForm.Token, formErrors))
+	}
+
+	reset, err := l.validToken(ctx, models.AuthTokenPasswordReset, resetForm.Token)
+	if err != nil {
+		return InternalError("failed to get password reset: %w", err)
+	}
+	if reset == nil || reset.UserID == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The password reset link is invalid or expired")
+	}
+
+	passwordHash, err := auth.HashPassword(resetForm.Password)
+	if err != nil {
+		return InternalError("%w", err)
+	}
+
+	txErr := l.store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+		used, err := tx.UseAuthToken(ctx, reset.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return fmt.Errorf("password reset %s was already used", reset.ID)
+		}
+		if err := tx.UpdatePassword(ctx, *reset.UserID, passwordHash); err != nil {
+			return err
+		}
+		return tx.DeleteUserSessions(ctx, *reset.UserID)
+	})
+	if txErr != nil {
+		return InternalError("failed to reset password: %w", txErr)
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/login")
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/pquerna/otp/totp"
+)
+
+var testSessionStore = sessions.NewCookieStore([]byte("test session secret"))
+
+// callWithSession runs the handler behind the session middleware, sending
+// the cookies of a previous response.
+func callWithSession(handler echo.HandlerFunc, c echo.Context, cookies []*http.Cookie) error {
+	for _, cookie := range cookies {
+		c.Request().AddCookie(cookie)
+	}
+	return session.Middleware(testSessionStore)(handler)(c)
+}
+
+func newLocalAuthHandler(store *memstore.Store) *LocalAuthHandler {
+	local := auth.LocalAuth{
+		Enabled:          true,
+		Signup:           auth.SignupInvite,
+		InviteTTL:        time.Hour,
+		PasswordResetTTL: time.Hour,
+	}
+	return NewLocalAuthHandler(local, "http://localhost:8080", store, testEncrypter,
+		auth.SessionPolicy{MaxAge: time.Hour, IdleTimeout: time.Hour}, nil, nil)
+}
+
+func signupForm(username string, password string) url.Values {
+	return url.Values{
+		"username":         {username},
+		"password":         {password},
+		"confirm-password": {password},
+	}
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := newLocalAuthHandler(store)
+
+	c, rec := newFormContext(http.MethodPost, "/signup", signupForm("Alice", "correct horse battery"))
+	if err := callWithSession(handler.CreateAccount, c, nil); err != nil {
+		t.Fatalf("CreateAccount returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/projects" {
+		t.Fatalf("expected the first account to be created without an invite, got %d %s", rec.Code, rec.Body.String())
+	}
+	user, err := store.GetUserBySubject(ctx, models.ProviderLocal, "alice")
+	if err != nil {
+		t.Fatalf("expected a local user: %v", err)
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 1 {
+		t.Fatalf("expected the signup to start a session, got %d", len(userSessions))
+	}
+
+	c, _ = newFormContext(http.MethodPost, "/signup", signupForm("bob", "correct horse battery"))
+	err = callWithSession(handler.CreateAccount, c, nil)
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusForbidden {
+		t.Fatalf("expected an invite to be required for the second account, got %v", err)
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/login/local", url.Values{"username": {"alice"}, "password": {"wrong password"}})
+	if err := callWithSession(handler.Login, c, nil); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Invalid username or password") {
+		t.Fatalf("expected a wrong password to be rejected, got %d", rec.Code)
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/login/local", url.Values{"username": {"ALICE"}, "password": {"correct horse battery"}})
+	if err := callWithSession(handler.Login, c, nil); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/projects" {
+		t.Fatalf("expected the login to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 2 {
+		t.Fatalf("expected the login to start a session, got %d", len(userSessions))
+	}
+}
+
+func TestSignupWithInvite(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := newLocalAuthHandler(store)
+	inviter, _ := store.CreateUser(ctx, models.User{Provider: models.ProviderLocal, Subject: "alice", Name: "alice"})
+	store.CreateCredentials(ctx, models.Credentials{UserID: inviter.ID, Username: "alice", PasswordHash: "hash"})
+
+	token, tokenHash, _ := auth.NewAuthToken()
+	store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenInvite,
+		TokenHash: tokenHash,
+		CreatedBy: &inviter.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	form := signupForm("bob", "correct horse battery")
+	form.Set("token", token)
+	c, rec := newFormContext(http.MethodPost, "/signup", form)
+	if err := callWithSession(handler.CreateAccount, c, nil); err != nil {
+		t.Fatalf("CreateAccount returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/projects" {
+		t.Fatalf("expected the invite to allow the signup, got %d %s", rec.Code, rec.Body.String())
+	}
+	if invite, _ := store.GetAuthToken(ctx, models.AuthTokenInvite, tokenHash); invite.UsedAt == nil {
+		t.Fatalf("expected the invite to be used")
+	}
+
+	form = signupForm("carol", "correct horse battery")
+	form.Set("token", token)
+	c, _ = newFormContext(http.MethodPost, "/signup", form)
+	if err := callWithSession(handler.CreateAccount, c, nil); err == nil {
+		t.Fatalf("expected a used invite to be rejected")
+	}
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := newLocalAuthHandler(store)
+
+	user, _ := store.CreateUser(ctx, models.User{Provider: models.ProviderLocal, Subject: "alice", Name: "alice"})
+	passwordHash, _ := auth.HashPassword("correct horse battery")
+	key, _ := auth.NewTOTPKey("alice")
+	encryptedSecret, _ := testEncrypter.Encrypt(ctx, key.Secret())
+	store.CreateCredentials(ctx, models.Credentials{
+		UserID:       user.ID,
+		Username:     "alice",
+		PasswordHash: passwordHash,
+		TOTPSecret:   encryptedSecret,
+		TOTPEnabled:  true,
+	})
+
+	c, rec := newFormContext(http.MethodPost, "/login/local", url.Values{"username": {"alice"}, "password": {"correct horse battery"}})
+	if err := callWithSession(handler.Login, c, nil); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/login/totp" {
+		t.Fatalf("expected the login to ask for a code, got %d", rec.Code)
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 0 {
+		t.Fatalf("expected no session before the code is verified")
+	}
+	cookies := rec.Result().Cookies()
+
+	c, _ = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {"123456"}})
+	if err := callWithSession(handler.VerifyTOTPLogin, c, nil); err != nil {
+		t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 0 {
+		t.Fatalf("expected a code without a pending login to be ignored")
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {"000000"}})
+	if err := callWithSession(handler.VerifyTOTPLogin, c, cookies); err != nil {
+		t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+	}
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	if code != "000000" && rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a wrong code to be rejected, got %d", rec.Code)
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {code}})
+	if err := callWithSession(handler.VerifyTOTPLogin, c, cookies); err != nil {
+		t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/projects" {
+		t.Fatalf("expected the code to complete the login, got %d %s", rec.Code, rec.Body.String())
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 1 {
+		t.Fatalf("expected the login to start a session, got %d", len(userSessions))
+	}
+
+	// the same code does not complete a second login
+	c, rec = newFormContext(http.MethodPost, "/login/local", url.Values{"username": {"alice"}, "password": {"correct horse battery"}})
+	if err := callWithSession(handler.Login, c, nil); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	cookies = rec.Result().Cookies()
+	c, rec = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {code}})
+	if err := callWithSession(handler.VerifyTOTPLogin, c, cookies); err != nil {
+		t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a used code to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestVerifyTOTPLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := newLocalAuthHandler(store)
+
+	user, _ := store.CreateUser(ctx, models.User{Provider: models.ProviderLocal, Subject: "alice", Name: "alice"})
+	passwordHash, _ := auth.HashPassword("correct horse battery")
+	key, _ := auth.NewTOTPKey("alice")
+	encryptedSecret, _ := testEncrypter.Encrypt(ctx, key.Secret())
+	store.CreateCredentials(ctx, models.Credentials{
+		UserID:       user.ID,
+		Username:     "alice",
+		PasswordHash: passwordHash,
+		TOTPSecret:   encryptedSecret,
+		TOTPEnabled:  true,
+	})
+
+	c, rec := newFormContext(http.MethodPost, "/login/local", url.Values{"username": {"alice"}, "password": {"correct horse battery"}})
+	if err := callWithSession(handler.Login, c, nil); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	cookies := rec.Result().Cookies()
+
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	// the cookie of the pending login is replayed for every attempt
+	for attempt := 1; attempt <= maxTOTPFailures; attempt++ {
+		c, rec = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {wrong}})
+		if err := callWithSession(handler.VerifyTOTPLogin, c, cookies); err != nil {
+			t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("attempt %d: expected a wrong code to be rejected, got %d", attempt, rec.Code)
+		}
+	}
+	if !strings.Contains(rec.Body.String(), "Too many invalid codes") {
+		t.Fatalf("expected the last attempt to lock the account, got %s", rec.Body.String())
+	}
+
+	c, rec = newFormContext(http.MethodPost, "/login/totp", url.Values{"code": {code}})
+	if err := callWithSession(handler.VerifyTOTPLogin, c, cookies); err != nil {
+		t.Fatalf("VerifyTOTPLogin returned error: %v", err)
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); rec.Code != http.StatusBadRequest || len(userSessions) != 0 {
+		t.Fatalf("expected the correct code to be refused while locked, got %d", rec.Code)
+	}
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := newLocalAuthHandler(store)
+
+	user, _ := store.CreateUser(ctx, models.User{Provider: models.ProviderLocal, Subject: "alice", Name: "alice"})
+	store.CreateCredentials(ctx, models.Credentials{UserID: user.ID, Username: "alice", PasswordHash: "old hash"})
+	store.CreateUserSession(ctx, user.ID, "", "", time.Now().Add(time.Hour))
+	token, tokenHash, _ := auth.NewAuthToken()
+	store.CreateAuthToken(ctx, models.AuthToken{
+		Kind:      models.AuthTokenPasswordReset,
+		TokenHash: tokenHash,
+		UserID:    &user.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	form := url.Values{"token": {token}, "password": {"a new password!"}, "confirm-password": {"a new password!"}}
+	c, rec := newFormContext(http.MethodPost, "/password/reset", form)
+	if err := handler.ConfirmPasswordReset(c); err != nil {
+		t.Fatalf("ConfirmPasswordReset returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/login" {
+		t.Fatalf("expected a redirect to the login page, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	credentials, _ := store.GetUserCredentials(ctx, user.ID)
+	if !auth.CheckPassword(credentials.PasswordHash, "a new password!") {
+		t.Fatalf("expected the password to be changed")
+	}
+	if userSessions, _ := store.ListUserSessions(ctx, user.ID); len(userSessions) != 0 {
+		t.Fatalf("expected the reset to sign out every session")
+	}
+
+	c, _ = newFormContext(http.MethodPost, "/password/reset", form)
+	if err := handler.ConfirmPasswordReset(c); err == nil {
+		t.Fatalf("expected the reset link to be single use")
+	}
+}
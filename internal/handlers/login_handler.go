@@ -1,46 +1,58 @@
 package handlers
 
 import (
+	"configuration-management/internal/auth"
 	"configuration-management/internal/database"
+	"configuration-management/internal/metrics"
 	"configuration-management/internal/models"
+	"configuration-management/internal/tracing"
 	"configuration-management/web/login_components"
-	"context"
-	"crypto/rand"
-	"encoding/base64"
-	"encoding/json"
-	"log"
+	"fmt"
+	"net"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/github"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const loginFlowCookie = "login_flow"
+
 type LoginHandler struct {
-	conf *oauth2.Config
-	db   *database.DatabaseHandler
+	providers     []auth.Provider
+	local         auth.LocalAuth
+	users         database.UserStore
+	sessions      database.SessionStore
+	sessionPolicy auth.SessionPolicy
+	metrics       *metrics.Metrics
+	// secureCookies is set when the application is served over https.
+	secureCookies bool
+}
+
+func NewLoginHandler(providers []auth.Provider, local auth.LocalAuth, users database.UserStore,
+	sessions database.SessionStore, sessionPolicy auth.SessionPolicy, metrics *metrics.Metrics,
+	secureCookies bool) *LoginHandler {
+	return &LoginHandler{providers, local, users, sessions, sessionPolicy, metrics, secureCookies}
 }
 
-func NewLoginHandler(db *database.DatabaseHandler) *LoginHandler {
-	conf := &oauth2.Config{
-		ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
-		ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-		Scopes:       []string{},
-		Endpoint:     github.Endpoint,
+func (l *LoginHandler) provider(c echo.Context) (auth.Provider, error) {
+	for _, provider := range l.providers {
+		if provider.ID() == c.Param("provider") {
+			return provider, nil
+		}
 	}
-	return &LoginHandler{conf, db}
+
+	return nil, echo.NewHTTPError(http.StatusNotFound, "Unknown identity provider")
 }
 
 func (l *LoginHandler) Login(c echo.Context) error {
-	component := login_components.Login()
+	component := login_components.Login(l.providers, l.local)
 	renderErr := component.Render(c.Request().Context(), c.Response().Writer)
 	if renderErr != nil {
-		log.Fatalf("Error rendering in ListProjects: %e", renderErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render login page: %w", renderErr)
 	}
 
 	return nil
@@ -49,108 +61,160 @@ func (l *LoginHandler) Login(c echo.Context) error {
 func (l *LoginHandler) Logout(c echo.Context) error {
 	sess, err := session.Get("session", c)
 	if err != nil {
-		log.Printf("failed to get session cookie: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to get session cookie: %w", err)
 	}
 
-	sess.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   1,
-		HttpOnly: true,
+	if sessionID, ok := SessionIDFromCookie(sess); ok {
+		if err := l.sessions.DeleteUserSession(c.Request().Context(), sessionID); err != nil {
+			return InternalError("failed to delete user session: %w", err)
+		}
 	}
 
+	// the other options are the defaults of the session store
+	sess.Options.MaxAge = -1
+
 	delete(sess.Values, "session_id")
 	if err := sess.Save(c.Request(), c.Response()); err != nil {
-		log.Printf("failed to update session in request: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to update session in request: %w", err)
 	}
 
-	return c.Redirect(http.StatusPermanentRedirect, "/login")
+	c.Response().Header().Set("HX-Redirect", "/login")
+	return c.NoContent(http.StatusOK)
 }
 
-func (l *LoginHandler) LoginWithGitlab(c echo.Context) error {
-	// Generate a random state for CSRF protection and set it in a cookie.
-	state, err := randString(16)
+func (l *LoginHandler) LoginWithProvider(c echo.Context) error {
+	provider, err := l.provider(c)
+	if err != nil {
+		return err
+	}
+
+	// The state protects the callback against CSRF, the PKCE verifier and
+	// the nonce bind the issued tokens to this login attempt.
+	flow, err := auth.NewLoginFlow()
 	if err != nil {
-		panic(err)
+		return InternalError("failed to start login flow: %w", err)
+	}
+
+	redirectURL, err := provider.AuthCodeURL(c.Request().Context(), flow)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "The identity provider is unavailable").
+			SetInternal(fmt.Errorf("failed to build %s authorization url: %w", provider.ID(), err))
 	}
 
 	cookie := &http.Cookie{
-		Name:     "state",
-		Value:    state,
-		Path:     "/",
-		MaxAge:   int(time.Hour.Seconds()),
-		Secure:   c.Request().TLS != nil,
+		Name:     loginFlowCookie,
+		Value:    flow.Encode(),
+		Path:     "/auth/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		Secure:   l.secureCookies,
 		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	}
-	redirectURL := l.conf.AuthCodeURL(state)
 	http.SetCookie(c.Response().Writer, cookie)
 	return c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
-func (l *LoginHandler) GithubCallback(c echo.Context) error {
-	state, err := c.Request().Cookie("state")
+func (l *LoginHandler) Callback(c echo.Context) error {
+	provider, err := l.provider(c)
+	if err != nil {
+		return err
+	}
+
+	cookie, err := c.Request().Cookie(loginFlowCookie)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "State not found")
+	}
+	flow, err := auth.DecodeLoginFlow(cookie.Value)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "State not found")
 	}
-	if c.Request().URL.Query().Get("state") != state.Value {
+	if c.QueryParam("state") != flow.State {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid state")
 	}
+	http.SetCookie(c.Response().Writer, &http.Cookie{
+		Name:     loginFlowCookie,
+		Path:     "/auth/",
+		MaxAge:   -1,
+		Secure:   l.secureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
 
-	code := c.Request().URL.Query().Get("code")
-	tok, err := l.conf.Exchange(context.Background(), code)
-	if err != nil {
-		log.Printf("error exchanging token: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+	if errorCode := c.QueryParam("error"); errorCode != "" {
+		l.metrics.Login(provider.ID(), metrics.LoginFailed)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Login was cancelled or denied").
+			SetInternal(fmt.Errorf("%s login failed: %s %s", provider.ID(), errorCode, c.QueryParam("error_description")))
 	}
 
-	client := l.conf.Client(context.Background(), tok)
-	resp, err := client.Get("https://api.github.com/user")
+	ctx, span := tracing.Start(c.Request().Context(), "auth.Identify", attribute.String("auth.provider", provider.ID()))
+	identity, err := provider.Identify(ctx, c.QueryParam("code"), flow)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("error fetching user data from github: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		l.metrics.Login(provider.ID(), metrics.LoginFailed)
+		return echo.NewHTTPError(http.StatusUnauthorized).SetInternal(fmt.Errorf("failed to identify %s user: %w", provider.ID(), err))
 	}
 
-	var githubUser models.GithubUser
-	json.NewDecoder(resp.Body).Decode(&githubUser)
-	user, userErr := l.db.CreateUser(githubUser.Id, githubUser.Name, githubUser.AvatarUrl)
+	user, userErr := l.users.CreateUser(c.Request().Context(), models.User{
+		Provider:  provider.ID(),
+		Subject:   identity.Subject,
+		Name:      identity.Name,
+		Email:     identity.Email,
+		AvatarUrl: identity.AvatarURL,
+	})
 	if userErr != nil {
-		log.Printf("failed to create a user: %v\n", userErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to create a user: %w", userErr)
+	}
+
+	if err := startUserSession(c, l.sessions, l.sessionPolicy, user.ID); err != nil {
+		return err
 	}
+	l.metrics.Login(provider.ID(), metrics.LoginSucceeded)
 
-	userSession, sessionErr := l.db.CreateUserSession(user.ID)
+	return c.Redirect(http.StatusPermanentRedirect, "/projects")
+}
+
+// startUserSession records a session for the user and stores it in the
+// session cookie.
+func startUserSession(c echo.Context, store database.SessionStore, policy auth.SessionPolicy, userID uuid.UUID) error {
+	// the address is only informative, one that does not parse is not kept
+	ipAddress := c.RealIP()
+	if net.ParseIP(ipAddress) == nil {
+		ipAddress = ""
+	}
+	userSession, sessionErr := store.CreateUserSession(c.Request().Context(), userID,
+		c.Request().UserAgent(), ipAddress, policy.ExpiresAt(time.Now().UTC()))
 	if sessionErr != nil {
-		log.Printf("failed to create user session: %v\n", sessionErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to create user session: %w", sessionErr)
 	}
 
-	// create session
 	sess, err := session.Get("session", c)
 	if err != nil {
-		log.Printf("failed to create session cookie: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to create session cookie: %w", err)
 	}
 
-	sess.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400 * 7,
-		HttpOnly: true,
-	}
+	// the other options are the defaults of the session store
+	sess.Options.MaxAge = int(policy.MaxAge.Seconds())
 
 	sess.Values["session_id"] = userSession.ID.String()
 	if err := sess.Save(c.Request(), c.Response()); err != nil {
-		log.Printf("failed to update session in request: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to update session in request: %w", err)
 	}
 
-	return c.Redirect(http.StatusPermanentRedirect, "/projects")
+	return nil
 }
 
-func randString(n int) (string, error) {
-	buf := make([]byte, n)
-	if _, err := rand.Read(buf); err != nil {
-		return "", err
+// SessionIDFromCookie returns the user session referenced by the session
+// cookie.
+func SessionIDFromCookie(sess *sessions.Session) (uuid.UUID, bool) {
+	sessionIDString, ok := sess.Values["session_id"].(string)
+	if !ok {
+		return uuid.Nil, false
 	}
-	return base64.RawURLEncoding.EncodeToString(buf), nil
+
+	sessionID, err := uuid.Parse(sessionIDString)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return sessionID, true
 }
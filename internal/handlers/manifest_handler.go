@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"configuration-management/internal/api"
+	"configuration-management/internal/database"
+	"configuration-management/internal/manifest"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web/projects_components"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxManifestSize bounds the imported documents.
+const maxManifestSize = 1 << 20
+
+type ManifestHandler struct {
+	store      database.Store
+	encrypter  *utils.Encrypter
+	dispatcher *webhooks.Dispatcher
+}
+
+func NewManifestHandler(store database.Store, encrypter *utils.Encrypter, dispatcher *webhooks.Dispatcher) *ManifestHandler {
+	return &ManifestHandler{store, encrypter, dispatcher}
+}
+
+type ImportResponse struct {
+	DryRun    bool              `json:"dry_run"`
+	ProjectID *uuid.UUID        `json:"project_id,omitempty"`
+	Changes   []manifest.Change `json:"changes"`
+}
+
+// ExportProject downloads the project as YAML, or as JSON with format=json.
+// The header values are exported encrypted with values=true.
+func (m *ManifestHandler) ExportProject(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = manifest.FormatYAML
+	}
+	contentType := "application/yaml"
+	switch format {
+	case manifest.FormatYAML:
+	case manifest.FormatJSON:
+		contentType = echo.MIMEApplicationJSONCharsetUTF8
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Format must be yaml or json")
+	}
+
+	configs, err := m.store.ListConfigs(c.Request().Context(), project.ID)
+	if err != nil {
+		return InternalError("failed to list configs: %w", err)
+	}
+	exported := *project
+	exported.Configs = configs
+	document := manifest.Export(exported, c.QueryParam("values") == "true")
+
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": project.Name + "." + format}))
+	if err := manifest.Encode(c.Response(), document, format); err != nil {
+		return InternalError("failed to export project: %w", err)
+	}
+
+	return nil
+}
+
+// ImportProject imports the document into the user's project of the same
+// name, which is created when there is none.
+func (m *ManifestHandler) ImportProject(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user")
+	}
+
+	document, err := readManifest(c)
+	if err != nil {
+		return err
+	}
+	project, err := manifest.FindProject(c.Request().Context(), m.store, user.ID, document.Project.Name)
+	if errors.Is(err, manifest.ErrAmbiguousProject) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error()).SetInternal(err)
+	}
+	if err != nil {
+		return InternalError("failed to find project: %w", err)
+	}
+
+	return m.importDocument(c, user, project, document)
+}
+
+// ImportIntoProject imports the document into the project of the request,
+// whatever the project name of the document.
+func (m *ManifestHandler) ImportIntoProject(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user")
+	}
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	document, err := readManifest(c)
+	if err != nil {
+		return err
+	}
+	configs, err := m.store.ListConfigs(c.Request().Context(), project.ID)
+	if err != nil {
+		return InternalError("failed to list configs: %w", err)
+	}
+	current := *project
+	current.Configs = configs
+
+	return m.importDocument(c, user, &current, document)
+}
+
+// importDocument only shows the changes with dry_run=true, and deletes the
+// configs missing from the document with prune=true. The changes are
+// answered as JSON on the API.
+func (m *ManifestHandler) importDocument(c echo.Context, user *models.User, project *models.Project, document *manifest.Document) error {
+	ctx := c.Request().Context()
+	dryRun := formFlag(c, "dry_run")
+	plan, err := manifest.NewPlan(ctx, m.encrypter, project, *document, manifest.Options{Prune: formFlag(c, "prune")})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	response := ImportResponse{DryRun: dryRun, Changes: plan.Changes}
+	if project != nil {
+		response.ProjectID = &project.ID
+	}
+	if !dryRun && !plan.Empty() {
+		var result *manifest.Result
+		txErr := m.store.Transaction(ctx, func(ctx context.Context, tx database.Store) error {
+			result, err = plan.Apply(ctx, tx, user.ID)
+			return err
+		})
+		if txErr != nil {
+			return InternalError("failed to import project: %w", txErr)
+		}
+		result.Publish(ctx, m.dispatcher)
+		response.ProjectID = &result.Project.ID
+	}
+	if response.Changes == nil {
+		response.Changes = []manifest.Change{}
+	}
+
+	if strings.HasPrefix(c.Path(), api.Prefix+"/") || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON) {
+		return c.JSON(http.StatusOK, response)
+	}
+	return renderComponent(c, http.StatusOK, projects_components.ImportPlan(plan, dryRun))
+}
+
+// readManifest reads the document from the document field or the uploaded
+// file of a form, or from the request body.
+func readManifest(c echo.Context) (*manifest.Document, error) {
+	var reader io.Reader = c.Request().Body
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEApplicationForm) || strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		reader = strings.NewReader(c.FormValue("document"))
+		if file, err := c.FormFile("file"); err == nil {
+			uploaded, err := file.Open()
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to read the uploaded file").SetInternal(err)
+			}
+			defer uploaded.Close()
+			reader = uploaded
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxManifestSize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to read document: %w", err))
+	}
+	if len(data) > maxManifestSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "The document is larger than 1MB")
+	}
+
+	document, err := manifest.Decode(data)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return document, nil
+}
+
+// formFlag reads a checkbox or a true query parameter.
+func formFlag(c echo.Context, name string) bool {
+	value := c.FormValue(name)
+	return value == "true" || value == "on"
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/manifest"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestExportAndImportProject(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := NewManifestHandler(store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter))
+	owner, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "owner"})
+	other, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "2", Name: "other"})
+
+	project, _ := store.CreateProject(ctx, "payments", "Payment providers", "key", owner.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+	value, _ := testEncrypter.Encrypt(ctx, "Bearer secret")
+	store.CreateHeaderReplacement(ctx, config.ID, "Authorization", value)
+
+	c, rec := newFormContext(http.MethodGet, "/projects/"+project.ID.String()+"/export?values=true", nil)
+	c.Set("project", project)
+	if err := handler.ExportProject(c); err != nil {
+		t.Fatalf("ExportProject returned error: %v", err)
+	}
+	exported := rec.Body.String()
+	if !strings.Contains(exported, "limit: 100/hour") || !strings.Contains(exported, "encrypted_value: "+value) {
+		t.Fatalf("unexpected export %s", exported)
+	}
+	if disposition := rec.Header().Get(echo.HeaderContentDisposition); disposition != `attachment; filename=payments.yaml` {
+		t.Errorf("unexpected content disposition %q", disposition)
+	}
+
+	importAs := func(dryRun string) ImportResponse {
+		t.Helper()
+		c, rec := newFormContext(http.MethodPost, "/projects/import", url.Values{"document": {exported}, "dry_run": {dryRun}})
+		c.Request().Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+		c.Set("user", other)
+		if err := handler.ImportProject(c); err != nil {
+			t.Fatalf("ImportProject returned error: %v", err)
+		}
+		var response ImportResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	if response := importAs("true"); !response.DryRun || len(response.Changes) != 3 || response.ProjectID != nil {
+		t.Fatalf("expected the dry run to list the project, config and header, got %+v", response)
+	}
+	if imported, _ := manifest.FindProject(ctx, store, other.ID, "payments"); imported != nil {
+		t.Fatalf("expected the dry run not to create the project")
+	}
+
+	response := importAs("false")
+	if response.ProjectID == nil || len(response.Changes) != 3 {
+		t.Fatalf("expected the project to be imported, got %+v", response)
+	}
+	imported, _ := manifest.FindProject(ctx, store, other.ID, "payments")
+	if imported == nil || imported.ID != *response.ProjectID || len(imported.Configs) != 1 ||
+		imported.Configs[0].HeaderReplacements[0].HeaderName != "Authorization" {
+		t.Fatalf("unexpected imported project %+v", imported)
+	}
+	if response := importAs("false"); len(response.Changes) != 0 {
+		t.Fatalf("expected a second import to change nothing, got %+v", response.Changes)
+	}
+}
+
+func TestImportRejectsInvalidDocuments(t *testing.T) {
+	store := memstore.New()
+	handler := NewManifestHandler(store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, _ := newFormContext(http.MethodPost, "/projects/import", url.Values{"document": {"version: 1\nproject: {}"}})
+	c.Set("user", &models.User{})
+	err := handler.ImportProject(c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest ||
+		!strings.Contains(httpErr.Message.(string), "project.name is required") {
+		t.Fatalf("expected a 400 naming the invalid field, got %v", err)
+	}
+}
+
+func TestImportRejectsDuplicateProjectNames(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	handler := NewManifestHandler(store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter))
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "owner"})
+	store.CreateProject(ctx, "payments", "", "key", user.ID)
+	store.CreateProject(ctx, "payments", "", "key", user.ID)
+
+	c, _ := newFormContext(http.MethodPost, "/projects/import", url.Values{"document": {"version: 1\nproject:\n  name: payments"}})
+	c.Set("user", user)
+	err := handler.ImportProject(c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusConflict {
+		t.Fatalf("expected a 409 for duplicate project names, got %v", err)
+	}
+}
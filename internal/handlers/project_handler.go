@@ -5,49 +5,80 @@ import (
 	"configuration-management/internal/forms"
 	"configuration-management/internal/models"
 	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
 	"configuration-management/web/projects_components"
-	"log"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-playground/form/v4"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	projectsPageSize = 20
+	// Accounts with more configs than this get the config tabs of each
+	// project loaded on demand instead of with the page.
+	lazyConfigsThreshold = 200
+)
+
 type CreateProjectForm struct {
 	Name        string `form:"name" validate:"required"`
 	Description string `form:"name"`
 }
 
 type ProjectHandler struct {
-	db       *database.DatabaseHandler
-	decoder  *form.Decoder
-	validate *validator.Validate
+	projects   database.ProjectStore
+	dispatcher *webhooks.Dispatcher
+	decoder    *form.Decoder
+	validate   *validator.Validate
 }
 
-func NewProjectHandler(db *database.DatabaseHandler) *ProjectHandler {
+func NewProjectHandler(projects database.ProjectStore, dispatcher *webhooks.Dispatcher) *ProjectHandler {
 	validate := validator.New(validator.WithRequiredStructEnabled())
-	return &ProjectHandler{db, form.NewDecoder(), validate}
+	return &ProjectHandler{projects, dispatcher, form.NewDecoder(), validate}
 }
 
 func (p *ProjectHandler) ListProjects(c echo.Context) error {
 	user, ok := c.Get("user").(*models.User)
 	if !ok {
-		log.Println("Missing user")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing user")
+	}
+
+	page := 1
+	if requestedPage, err := strconv.Atoi(c.QueryParam("page")); err == nil && requestedPage > 1 {
+		page = requestedPage
 	}
 
-	projects, err := p.db.ListProjects(user.ID)
+	projectsCount, err := p.projects.CountProjects(c.Request().Context(), user.ID)
+	if err != nil {
+		return InternalError("failed to count projects: %w", err)
+	}
+
+	configsCount, err := p.projects.CountUserConfigs(c.Request().Context(), user.ID)
+	if err != nil {
+		return InternalError("failed to count configs: %w", err)
+	}
+
+	listing := projects_components.ProjectsListing{
+		Page:        page,
+		TotalPages:  (projectsCount + projectsPageSize - 1) / projectsPageSize,
+		LazyConfigs: configsCount > lazyConfigsThreshold,
+	}
+	projects, err := p.projects.ListProjects(c.Request().Context(), user.ID, database.ProjectListOptions{
+		Limit:       projectsPageSize,
+		Offset:      (page - 1) * projectsPageSize,
+		WithConfigs: !listing.LazyConfigs,
+	})
 	if err != nil {
-		log.Fatalf("Error fetching projects: %v\n", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to list projects: %w", err)
 	}
 
-	component := projects_components.Projects(user, projects)
+	component := projects_components.Projects(user, projects, listing)
 	renderErr := component.Render(c.Request().Context(), c.Response().Writer)
 	if renderErr != nil {
-		log.Fatalf("Error rendering in ListProjects: %e", renderErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render projects: %w", renderErr)
 	}
 
 	return nil
@@ -59,8 +90,7 @@ func (p *ProjectHandler) processCreateForm(c echo.Context) (*CreateProjectForm,
 	}
 	var createProjectForm CreateProjectForm
 	if err := p.decoder.Decode(&createProjectForm, c.Request().Form); err != nil {
-		log.Fatalf("Error decoding CreateProjectForm: %e", err)
-		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateProjectForm: %w", err))
 	}
 
 	if validationErr := p.validate.Struct(createProjectForm); validationErr != nil {
@@ -77,8 +107,7 @@ func (p *ProjectHandler) processCreateForm(c echo.Context) (*CreateProjectForm,
 func (p *ProjectHandler) CreateProject(c echo.Context) error {
 	user, ok := c.Get("user").(*models.User)
 	if !ok {
-		log.Println("Missing user")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing user")
 	}
 
 	createProjectForm, formErrors, processingErr := p.processCreateForm(c)
@@ -88,28 +117,26 @@ func (p *ProjectHandler) CreateProject(c echo.Context) error {
 	if formErrors != nil {
 		c.Response().Header().Set("HX-Reswap", "outerHTML")
 		c.Response().Header().Set("HX-Retarget", "#create-project-form")
+		c.Response().WriteHeader(http.StatusBadRequest)
 		component := projects_components.CreateProject(formErrors)
 		if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-			log.Fatalf("Error rendering created project: %e", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return InternalError("failed to render created project: %w", err)
 		}
-		c.Response().WriteHeader(http.StatusBadRequest)
 		return nil
 
 	}
 
 	accessKey := utils.GenerateToken(32)
 
-	project, projectErr := p.db.CreateProject(createProjectForm.Name, createProjectForm.Description, accessKey, user.ID)
+	project, projectErr := p.projects.CreateProject(c.Request().Context(), createProjectForm.Name, createProjectForm.Description, accessKey, user.ID)
 	if projectErr != nil {
-		log.Fatalf("Error creating project: %e", projectErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to create project: %w", projectErr)
 	}
+	p.dispatcher.Publish(c.Request().Context(), project.ID, models.EventProjectCreated, webhooks.NewProjectPayload(*project))
 
-	component := projects_components.ProjectDetails(*project, true)
+	component := projects_components.ProjectDetails(*project, true, false)
 	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
-		log.Fatalf("Error rendering created project: %e", err)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("failed to render created project: %w", err)
 	}
 
 	return nil
@@ -118,14 +145,29 @@ func (p *ProjectHandler) CreateProject(c echo.Context) error {
 func (p *ProjectHandler) DeleteProject(c echo.Context) error {
 	project, ok := c.Get("project").(*models.Project)
 	if !ok {
-		log.Println("Missing project instance in the context")
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return InternalError("missing project instance in the context")
 	}
 
-	if deleteErr := p.db.DeleteProject(project.ID); deleteErr != nil {
-		log.Fatalf("Failed to delete project: %e", deleteErr)
-		return echo.NewHTTPError(http.StatusInternalServerError)
+	notifyDeleted := p.dispatcher.PublishProjectDeleted(c.Request().Context(), *project)
+	if deleteErr := p.projects.DeleteProject(c.Request().Context(), project.ID); deleteErr != nil {
+		return InternalError("failed to delete project: %w", deleteErr)
 	}
 
+	notifyDeleted()
 	return nil
 }
+
+func (p *ProjectHandler) RotateAccessKey(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	accessKey := utils.GenerateToken(32)
+	if err := p.projects.UpdateProjectAccessKey(c.Request().Context(), project.ID, accessKey); err != nil {
+		return InternalError("failed to rotate access key: %w", err)
+	}
+	p.dispatcher.Publish(c.Request().Context(), project.ID, models.EventAccessKeyRotated, webhooks.NewProjectPayload(*project))
+
+	return c.String(http.StatusOK, "Access key rotated, existing proxy URLs stopped working")
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// testEncrypter stands in for the key configured with SECRET_KEY.
+var testEncrypter, _ = utils.NewEncrypter([]byte("0123456789abcdef0123456789abcdef"))
+
+func newFormContext(method string, target string, form url.Values) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func TestListProjects(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	if _, err := store.CreateProject(ctx, "my project", "description", "key", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateProject(ctx, "someone else's project", "", "key", uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+	c, rec := newFormContext(http.MethodGet, "/projects", nil)
+	c.Set("user", user)
+
+	if err := handler.ListProjects(c); err != nil {
+		t.Fatalf("ListProjects returned error: %v", err)
+	}
+	if !strings.Contains(rec.Body.String(), "my project") {
+		t.Fatalf("expected the user's project to be listed")
+	}
+	if strings.Contains(rec.Body.String(), "someone else") {
+		t.Fatalf("expected other users' projects not to be listed")
+	}
+}
+
+func TestCreateProject(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, rec := newFormContext(http.MethodPost, "/projects", url.Values{"name": {"new project"}})
+	c.Set("user", user)
+	if err := handler.CreateProject(c); err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	projects, _ := store.ListProjects(ctx, user.ID, database.ProjectListOptions{})
+	if len(projects) != 1 || projects[0].Name != "new project" {
+		t.Fatalf("expected the project to be created, got %+v", projects)
+	}
+	if projects[0].AccessKey == "" {
+		t.Fatalf("expected an access key to be generated")
+	}
+	if !strings.Contains(rec.Body.String(), "new project") {
+		t.Fatalf("expected the created project to be rendered")
+	}
+}
+
+func TestCreateProjectValidation(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, rec := newFormContext(http.MethodPost, "/projects", url.Values{})
+	c.Set("user", user)
+	if err := handler.CreateProject(c); err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	if rec.Header().Get("HX-Retarget") != "#create-project-form" {
+		t.Fatalf("expected the form to be re-rendered")
+	}
+	if projects, _ := store.ListProjects(ctx, user.ID, database.ProjectListOptions{}); len(projects) != 0 {
+		t.Fatalf("expected no project to be created")
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	project, _ := store.CreateProject(ctx, "project", "", "key", user.ID)
+	config, _ := store.CreateConfig(ctx, project.ID, "config", 10, "day")
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, _ := newFormContext(http.MethodDelete, "/projects/"+project.ID.String(), nil)
+	c.Set("project", project)
+	if err := handler.DeleteProject(c); err != nil {
+		t.Fatalf("DeleteProject returned error: %v", err)
+	}
+
+	if _, err := store.GetProject(ctx, project.ID); err == nil {
+		t.Fatalf("expected the project to be deleted")
+	}
+	if _, err := store.GetConfig(ctx, config.ID); err == nil {
+		t.Fatalf("expected the project configs to be deleted")
+	}
+}
+
+func TestRotateAccessKey(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "project", "", "key", uuid.New())
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+
+	c, _ := newFormContext(http.MethodPost, "/projects/"+project.ID.String()+"/access-key/rotate", nil)
+	c.Set("project", project)
+	if err := handler.RotateAccessKey(c); err != nil {
+		t.Fatalf("RotateAccessKey returned error: %v", err)
+	}
+
+	rotated, _ := store.GetProject(ctx, project.ID)
+	if rotated.AccessKey == project.AccessKey {
+		t.Fatalf("expected the access key to change")
+	}
+}
+
+func TestListProjectsPagination(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	for i := 0; i < projectsPageSize+5; i++ {
+		if _, err := store.CreateProject(ctx, fmt.Sprintf("project-%02d", i), "", "key", user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewProjectHandler(store, webhooks.NewDispatcher(store, testEncrypter))
+	c, rec := newFormContext(http.MethodGet, "/projects?page=2", nil)
+	c.Set("user", user)
+	if err := handler.ListProjects(c); err != nil {
+		t.Fatalf("ListProjects returned error: %v", err)
+	}
+
+	body := rec.Body.String()
+	if strings.Count(body, "<details") != 5 {
+		t.Fatalf("expected 5 projects on the second page, got %d", strings.Count(body, "<details"))
+	}
+	if !strings.Contains(body, "Page 2 of 2") {
+		t.Fatalf("expected the pagination to be rendered")
+	}
+}
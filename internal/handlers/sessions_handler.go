@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"configuration-management/web/sessions_components"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SessionsHandler struct {
+	sessions database.SessionStore
+	policy   auth.SessionPolicy
+}
+
+func NewSessionsHandler(sessions database.SessionStore, policy auth.SessionPolicy) *SessionsHandler {
+	return &SessionsHandler{sessions, policy}
+}
+
+func (s *SessionsHandler) ListSessions(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user instance in the context")
+	}
+	current, ok := c.Get("currentSession").(*models.Session)
+	if !ok {
+		return InternalError("missing current session instance in the context")
+	}
+
+	userSessions, err := s.sessions.ListUserSessions(c.Request().Context(), user.ID)
+	if err != nil {
+		return InternalError("failed to list user sessions: %w", err)
+	}
+
+	// Expired sessions are only deleted by the cleanup job, hide them
+	// until then.
+	now := time.Now().UTC()
+	var active []models.Session
+	for _, userSession := range userSessions {
+		if s.policy.Active(userSession, now) {
+			active = append(active, userSession)
+		}
+	}
+
+	component := sessions_components.Sessions(user, active, current.ID)
+	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+		return InternalError("failed to render sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SessionsHandler) RevokeSession(c echo.Context) error {
+	userSession, ok := c.Get("userSession").(*models.Session)
+	if !ok {
+		return InternalError("missing user session instance in the context")
+	}
+	current, ok := c.Get("currentSession").(*models.Session)
+	if !ok {
+		return InternalError("missing current session instance in the context")
+	}
+
+	if err := s.sessions.DeleteUserSession(c.Request().Context(), userSession.ID); err != nil {
+		return InternalError("failed to revoke user session: %w", err)
+	}
+
+	if userSession.ID == current.ID {
+		c.Response().Header().Set("HX-Redirect", "/login")
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *SessionsHandler) RevokeAllSessions(c echo.Context) error {
+	user, ok := c.Get("user").(*models.User)
+	if !ok {
+		return InternalError("missing user instance in the context")
+	}
+
+	if err := s.sessions.DeleteUserSessions(c.Request().Context(), user.ID); err != nil {
+		return InternalError("failed to revoke user sessions: %w", err)
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/login")
+	return c.NoContent(http.StatusOK)
+}
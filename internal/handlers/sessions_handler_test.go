@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListSessions(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	policy := auth.SessionPolicy{MaxAge: time.Hour, IdleTimeout: time.Hour}
+	current, _ := store.CreateUserSession(ctx, user.ID, "Mozilla/5.0 (X11; Linux x86_64) Firefox/131.0", "203.0.113.7", time.Now().Add(time.Hour))
+	store.CreateUserSession(ctx, user.ID, "curl/8.5.0", "198.51.100.1", time.Now().Add(-time.Minute))
+
+	handler := NewSessionsHandler(store, policy)
+	c, rec := newFormContext(http.MethodGet, "/sessions", nil)
+	c.Set("user", user)
+	c.Set("currentSession", current)
+	if err := handler.ListSessions(c); err != nil {
+		t.Fatalf("ListSessions returned error: %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Firefox on Linux") || !strings.Contains(body, "203.0.113.7") {
+		t.Fatalf("expected the active session to be listed")
+	}
+	if strings.Contains(body, "198.51.100.1") {
+		t.Fatalf("expected the expired session not to be listed")
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	current, _ := store.CreateUserSession(ctx, user.ID, "", "", time.Now().Add(time.Hour))
+	other, _ := store.CreateUserSession(ctx, user.ID, "", "", time.Now().Add(time.Hour))
+	handler := NewSessionsHandler(store, auth.DefaultSessionPolicy())
+
+	c, rec := newFormContext(http.MethodDelete, "/sessions/"+other.ID.String(), nil)
+	c.Set("currentSession", current)
+	c.Set("userSession", other)
+	if err := handler.RevokeSession(c); err != nil {
+		t.Fatalf("RevokeSession returned error: %v", err)
+	}
+	if found, _ := store.GetUserSession(ctx, other.ID); found != nil {
+		t.Fatalf("expected the session to be revoked")
+	}
+	if rec.Header().Get("HX-Redirect") != "" {
+		t.Fatalf("expected revoking another session not to sign out")
+	}
+
+	c, rec = newFormContext(http.MethodDelete, "/sessions/"+current.ID.String(), nil)
+	c.Set("currentSession", current)
+	c.Set("userSession", current)
+	if err := handler.RevokeSession(c); err != nil {
+		t.Fatalf("RevokeSession returned error: %v", err)
+	}
+	if rec.Header().Get("HX-Redirect") != "/login" {
+		t.Fatalf("expected revoking the current session to redirect to the login page")
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	user, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	other, _ := store.CreateUser(ctx, models.User{Provider: "github", Subject: "2", Name: "other"})
+	for i := 0; i < 3; i++ {
+		store.CreateUserSession(ctx, user.ID, "", "", time.Now().Add(time.Hour))
+	}
+	store.CreateUserSession(ctx, other.ID, "", "", time.Now().Add(time.Hour))
+	handler := NewSessionsHandler(store, auth.DefaultSessionPolicy())
+
+	c, _ := newFormContext(http.MethodPost, "/sessions/revoke-all", nil)
+	c.Set("user", user)
+	if err := handler.RevokeAllSessions(c); err != nil {
+		t.Fatalf("RevokeAllSessions returned error: %v", err)
+	}
+
+	if sessions, _ := store.ListUserSessions(ctx, user.ID); len(sessions) != 0 {
+		t.Fatalf("expected every session of the user to be revoked, got %d", len(sessions))
+	}
+	if sessions, _ := store.ListUserSessions(ctx, other.ID); len(sessions) != 1 {
+		t.Fatalf("expected other users' sessions to be kept")
+	}
+}
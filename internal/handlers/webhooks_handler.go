@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/forms"
+	"configuration-management/internal/metrics"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+	"configuration-management/web/projects_components"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const webhookDeliveriesLimit = 20
+
+type CreateWebhookForm struct {
+	URL    string   `form:"url" validate:"required,http_url"`
+	Events []string `form:"events" validate:"required,min=1"`
+}
+
+type WebhooksHandler struct {
+	webhooks   database.WebhookStore
+	encrypter  *utils.Encrypter
+	dispatcher *webhooks.Dispatcher
+	metrics    *metrics.Metrics
+	decoder    *form.Decoder
+	validate   *validator.Validate
+}
+
+func NewWebhooksHandler(webhookStore database.WebhookStore, encrypter *utils.Encrypter,
+	dispatcher *webhooks.Dispatcher, metrics *metrics.Metrics) *WebhooksHandler {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return &WebhooksHandler{webhookStore, encrypter, dispatcher, metrics, form.NewDecoder(), validate}
+}
+
+func (w *WebhooksHandler) processForm(c echo.Context) (*CreateWebhookForm, forms.FormErrors, error) {
+	if c.Request().ParseForm() != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	var webhookForm CreateWebhookForm
+	if err := w.decoder.Decode(&webhookForm, c.Request().Form); err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest).SetInternal(fmt.Errorf("failed to decode CreateWebhookForm: %w", err))
+	}
+
+	errors := make(forms.FormErrors)
+	if validationErr := w.validate.Struct(webhookForm); validationErr != nil {
+		for _, err := range validationErr.(validator.ValidationErrors) {
+			errors[err.Field()] = err.Tag()
+		}
+	}
+
+	for _, event := range webhookForm.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			errors["Events"] = "oneof"
+		}
+	}
+
+	if _, ok := errors["URL"]; !ok {
+		// payloads are signed with the webhook secret, they must not travel in clear
+		if u, err := url.Parse(webhookForm.URL); err != nil || u.Scheme != "https" {
+			errors["URL"] = "https_url"
+		}
+	}
+
+	if len(errors) > 0 {
+		return nil, errors, nil
+	}
+
+	return &webhookForm, nil, nil
+}
+
+func (w *WebhooksHandler) CreateWebhook(c echo.Context) error {
+	project, ok := c.Get("project").(*models.Project)
+	if !ok {
+		return InternalError("missing project instance in the context")
+	}
+
+	webhookForm, formErrs, processingErr := w.processForm(c)
+	if processingErr != nil {
+		return processingErr
+	}
+
+	if formErrs != nil {
+		c.Response().Header().Set("HX-Reswap", "outerHTML")
+		c.Response().Header().Set("HX-Retarget", "#"+projects_components.GetCreateWebhookFormID(project.ID))
+		c.Response().WriteHeader(http.StatusBadRequest)
+		component := projects_components.CreateWebhookForm(project.ID, formErrs)
+		if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+			return InternalError("failed to render webhook form: %w", err)
+		}
+		return nil
+	}
+
+	encryptedSecret, encryptErr := w.encrypter.Encrypt(c.Request().Context(), utils.GenerateToken(32))
+	if encryptErr != nil {
+		return InternalError("failed to encrypt webhook secret: %w", encryptErr)
+	}
+
+	webhook, webhookErr := w.webhooks.CreateWebhook(c.Request().Context(), project.ID, webhookForm.URL, encryptedSecret, webhookForm.Events)
+	if webhookErr != nil {
+		return InternalError("failed to create webhook: %w", webhookErr)
+	}
+
+	component := projects_components.Webhook(*webhook)
+	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+		return InternalError("failed to render created webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (w *WebhooksHandler) DeleteWebhook(c echo.Context) error {
+	webhook, ok := c.Get("webhook").(*models.Webhook)
+	if !ok {
+		return InternalError("missing webhook instance in the context")
+	}
+
+	if deleteErr := w.webhooks.DeleteWebhook(c.Request().Context(), webhook.ID); deleteErr != nil {
+		return InternalError("failed to delete webhook: %w", deleteErr)
+	}
+
+	return nil
+}
+
+func (w *WebhooksHandler) GetWebhookSecret(c echo.Context) error {
+	webhook, ok := c.Get("webhook").(*models.Webhook)
+	if !ok {
+		return InternalError("missing webhook instance in the context")
+	}
+
+	secret, err := w.encrypter.Decrypt(c.Request().Context(), webhook.Secret)
+	if err != nil {
+		return InternalError("failed to decrypt webhook secret: %w", err)
+	}
+	w.metrics.Reveal(metrics.RevealWebhookSecret)
+
+	return c.String(http.StatusOK, secret)
+}
+
+func (w *WebhooksHandler) SendTestEvent(c echo.Context) error {
+	webhook, ok := c.Get("webhook").(*models.Webhook)
+	if !ok {
+		return InternalError("missing webhook instance in the context")
+	}
+
+	if _, err := w.dispatcher.SendTest(c.Request().Context(), *webhook); err != nil {
+		return InternalError("failed to send test event: %w", err)
+	}
+
+	return w.ListDeliveries(c)
+}
+
+func (w *WebhooksHandler) ListDeliveries(c echo.Context) error {
+	webhook, ok := c.Get("webhook").(*models.Webhook)
+	if !ok {
+		return InternalError("missing webhook instance in the context")
+	}
+
+	deliveries, err := w.webhooks.ListWebhookDeliveries(c.Request().Context(), webhook.ID, webhookDeliveriesLimit)
+	if err != nil {
+		return InternalError("failed to list webhook deliveries: %w", err)
+	}
+
+	component := projects_components.WebhookDeliveries(deliveries)
+	if err := component.Render(c.Request().Context(), c.Response().Writer); err != nil {
+		return InternalError("failed to render webhook deliveries: %w", err)
+	}
+
+	return nil
+}
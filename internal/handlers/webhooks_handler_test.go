@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/webhooks"
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateWebhookRequiresHTTPS(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	handler := NewWebhooksHandler(store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter), nil)
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"http://169.254.169.254/latest/meta-data", http.StatusBadRequest},
+		{"ftp://example.com/webhook", http.StatusBadRequest},
+		{"https://example.com/webhook", http.StatusOK},
+	}
+	for _, tt := range tests {
+		target := "/projects/" + project.ID.String() + "/webhooks"
+		c, rec := newFormContext(http.MethodPost, target, url.Values{"url": {tt.url}, "events": {models.EventConfigCreated}})
+		c.Set("project", project)
+		if err := handler.CreateWebhook(c); err != nil {
+			t.Fatalf("CreateWebhook(%q) returned error: %v", tt.url, err)
+		}
+		if rec.Code != tt.status {
+			t.Errorf("CreateWebhook(%q): expected status %d, got %d", tt.url, tt.status, rec.Code)
+		}
+	}
+
+	if webhooks, _ := store.ListWebhooks(ctx, project.ID); len(webhooks) != 1 || webhooks[0].URL != "https://example.com/webhook" {
+		t.Fatalf("expected only the https webhook to be created, got %+v", webhooks)
+	}
+}
+
+func TestCreateWebhookEvents(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	handler := NewWebhooksHandler(store, testEncrypter, webhooks.NewDispatcher(store, testEncrypter), nil)
+
+	tests := []struct {
+		events []string
+		status int
+	}{
+		{[]string{models.EventConfigCreated, "config.renamed"}, http.StatusBadRequest},
+		{nil, http.StatusBadRequest},
+		{models.WebhookEvents, http.StatusOK},
+	}
+	for _, tt := range tests {
+		target := "/projects/" + project.ID.String() + "/webhooks"
+		c, rec := newFormContext(http.MethodPost, target, url.Values{"url": {"https://example.com/webhook"}, "events": tt.events})
+		c.Set("project", project)
+		if err := handler.CreateWebhook(c); err != nil {
+			t.Fatalf("CreateWebhook(%v) returned error: %v", tt.events, err)
+		}
+		if rec.Code != tt.status {
+			t.Errorf("CreateWebhook(%v): expected status %d, got %d", tt.events, tt.status, rec.Code)
+		}
+	}
+}
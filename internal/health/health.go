@@ -0,0 +1,116 @@
+// Package health runs the readiness checks of the application, the
+// dependencies that must work for it to serve requests.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"configuration-management/internal/database"
+	"configuration-management/internal/database/migrations"
+	"configuration-management/internal/utils"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `json:"duration_ms"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Run runs the checks concurrently, each bounded by timeout. The report is
+// unavailable when any check fails.
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := CheckResult{Status: StatusOK}
+			if err := check.Run(checkCtx); err != nil {
+				result = CheckResult{Status: StatusUnavailable, Error: err.Error()}
+			}
+			result.Duration = time.Since(start).Milliseconds()
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func DatabaseCheck(store database.Store) Check {
+	return Check{Name: "database", Run: store.Ping}
+}
+
+// EncryptionCheck verifies that the secret key encrypts and decrypts, and
+// that it decrypts the stored header values, which fails once SECRET_KEY is
+// changed without re-encrypting them.
+func EncryptionCheck(encrypter *utils.Encrypter, headers database.HeaderStore) Check {
+	return Check{Name: "encryption", Run: func(ctx context.Context) error {
+		encrypted, err := encrypter.Encrypt(ctx, "readiness")
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %v", err)
+		}
+		if decrypted, err := encrypter.Decrypt(ctx, encrypted); err != nil || decrypted != "readiness" {
+			return fmt.Errorf("failed to decrypt a value encrypted with the same key")
+		}
+
+		stored, err := headers.SampleHeaderValue(ctx)
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := encrypter.Decrypt(ctx, stored); err != nil {
+			return fmt.Errorf("the secret key does not decrypt the stored header values")
+		}
+		return nil
+	}}
+}
+
+// MigrationsCheck verifies that the schema is at the newest migration of
+// the binary.
+func MigrationsCheck(db *database.DatabaseHandler) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		return migrations.Check(ctx, db)
+	}}
+}
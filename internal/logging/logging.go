@@ -0,0 +1,75 @@
+// Package logging sets up the structured logger and carries the logger of
+// a request, with its request id, route and user, through its context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Config struct {
+	// Format is FormatText or FormatJSON.
+	Format string `yaml:"format"`
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+func DefaultConfig() Config {
+	return Config{Format: FormatText, Level: "info"}
+}
+
+func (c Config) level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return level, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Level)
+	}
+	return level, nil
+}
+
+func (c Config) Validate() error {
+	if c.Format != FormatText && c.Format != FormatJSON {
+		return fmt.Errorf("LOG_FORMAT must be %q or %q, got %q", FormatText, FormatJSON, c.Format)
+	}
+	_, err := c.level()
+	return err
+}
+
+func New(w io.Writer, config Config) (*slog.Logger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	level, _ := config.level()
+
+	options := &slog.HandlerOptions{Level: level}
+	if config.Format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, options)), nil
+}
+
+type loggerKey struct{}
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request, or the default logger
+// outside of requests.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the logger of the context.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
@@ -0,0 +1,60 @@
+// Package mail sends plain text emails through the configured SMTP server.
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type Mailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+type Config struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// NewMailer returns nil when no SMTP host is configured.
+func NewMailer(config Config) *Mailer {
+	if config.Host == "" {
+		return nil
+	}
+
+	port := config.Port
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	return &Mailer{
+		addr: config.Host + ":" + port,
+		auth: auth,
+		from: config.From,
+	}
+}
+
+func (m *Mailer) Send(to string, subject string, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(body + "\r\n")
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package manifest
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a document read from a file of a directory.
+type Source struct {
+	Path     string
+	Document Document
+}
+
+// ReadDir reads the .yaml, .yml and .json documents of the directory and its
+// subdirectories, skipping the hidden ones such as .git, and resolves the
+// value_from references of their headers with lookupEnv, usually
+// os.LookupEnv. Every project must be described by a single document, and
+// header values must not be set in plain text.
+func ReadDir(dir string, lookupEnv func(string) (string, bool)) ([]Source, error) {
+	var sources []Source
+	projects := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		document, err := Decode(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if err := document.resolveValues(filepath.Dir(path), lookupEnv); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if other, found := projects[document.Project.Name]; found {
+			return fmt.Errorf("%s: project %q is already described by %s", path, document.Project.Name, other)
+		}
+		projects[document.Project.Name] = path
+
+		sources = append(sources, Source{Path: path, Document: *document})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// resolveValues replaces the value_from references by the value of the
+// environment variable or the encrypted value read from the file. Plain text
+// values are rejected, the documents of a directory are meant to be
+// committed.
+func (d *Document) resolveValues(dir string, lookupEnv func(string) (string, bool)) error {
+	for i, config := range d.Project.Configs {
+		for j, header := range config.Headers {
+			if header.Value != "" {
+				return fmt.Errorf("header %s/%s: value is in plain text, use encrypted_value or value_from", config.Name, header.Name)
+			}
+			from := header.ValueFrom
+			if from == nil {
+				continue
+			}
+
+			resolved := &d.Project.Configs[i].Headers[j]
+			resolved.ValueFrom = nil
+			if from.Env != "" {
+				value, found := lookupEnv(from.Env)
+				if !found || value == "" {
+					return fmt.Errorf("header %s/%s: environment variable %s is not set", config.Name, header.Name, from.Env)
+				}
+				resolved.Value = value
+				continue
+			}
+
+			path := from.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("header %s/%s: %v", config.Name, header.Name, err)
+			}
+			resolved.EncryptedValue = strings.TrimSpace(string(data))
+			if resolved.EncryptedValue == "" {
+				return fmt.Errorf("header %s/%s: %s is empty", config.Name, header.Name, path)
+			}
+		}
+	}
+
+	return nil
+}
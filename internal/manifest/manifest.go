@@ -0,0 +1,185 @@
+// Package manifest describes a project, its configs and their header
+// replacements as a declarative YAML or JSON document, which can be exported
+// from one account or environment and imported into another. Configs are
+// matched by name within their project and headers by name within their
+// config.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"configuration-management/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the document format.
+const Version = 1
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+type Document struct {
+	Version int     `yaml:"version" json:"version"`
+	Project Project `yaml:"project" json:"project"`
+}
+
+type Project struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Configs     []Config `yaml:"configs" json:"configs"`
+}
+
+type Config struct {
+	Name string `yaml:"name" json:"name"`
+	// Limit is written as requests/period, such as 100/hour.
+	Limit   string   `yaml:"limit" json:"limit"`
+	Headers []Header `yaml:"headers,omitempty" json:"headers,omitempty"`
+}
+
+// Header sets its value with at most one of Value, EncryptedValue and
+// ValueFrom. A header without a value keeps the value of the existing header,
+// and cannot be created.
+type Header struct {
+	Name string `yaml:"name" json:"name"`
+	// Value is in plain text, it is encrypted on import.
+	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+	// EncryptedValue is encrypted with the SECRET_KEY of the environment,
+	// as exported. It can only be imported where SECRET_KEY is the same.
+	EncryptedValue string `yaml:"encrypted_value,omitempty" json:"encrypted_value,omitempty"`
+	// ValueFrom references the value kept outside of the document, it is
+	// only resolved when reading a directory with ReadDir.
+	ValueFrom *ValueFrom `yaml:"value_from,omitempty" json:"value_from,omitempty"`
+}
+
+// ValueFrom names either an environment variable holding the plain text
+// value, or a file holding the value encrypted with SECRET_KEY, relative to
+// the document.
+type ValueFrom struct {
+	Env  string `yaml:"env,omitempty" json:"env,omitempty"`
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// Export describes the project with its configs and header replacements
+// loaded. The header values are left out unless withValues is set, in which
+// case they are exported encrypted.
+func Export(project models.Project, withValues bool) Document {
+	document := Document{
+		Version: Version,
+		Project: Project{Name: project.Name, Description: project.Description, Configs: []Config{}},
+	}
+	for _, config := range project.Configs {
+		exported := Config{Name: config.Name, Limit: models.FormatLimit(config.LimitNumberOfRequests, config.LimitPer)}
+		for _, header := range config.HeaderReplacements {
+			exportedHeader := Header{Name: header.HeaderName}
+			if withValues {
+				exportedHeader.EncryptedValue = header.HeaderValue
+			}
+			exported.Headers = append(exported.Headers, exportedHeader)
+		}
+		document.Project.Configs = append(document.Project.Configs, exported)
+	}
+
+	return document
+}
+
+func Encode(w io.Writer, document Document, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("format must be %q or %q, got %q", FormatYAML, FormatJSON, format)
+	}
+}
+
+// Decode reads a YAML or JSON document, JSON being valid YAML, and
+// validates it.
+func Decode(data []byte) (*Document, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var document Document
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("the document is empty")
+		}
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Validate reports every invalid field at once.
+func (d Document) Validate() error {
+	var errs []error
+	if d.Version != Version {
+		errs = append(errs, fmt.Errorf("version must be %d, got %d", Version, d.Version))
+	}
+	if strings.TrimSpace(d.Project.Name) == "" {
+		errs = append(errs, fmt.Errorf("project.name is required"))
+	}
+
+	configs := make(map[string]bool)
+	for i, config := range d.Project.Configs {
+		path := fmt.Sprintf("project.configs[%d]", i)
+		switch {
+		case strings.TrimSpace(config.Name) == "":
+			errs = append(errs, fmt.Errorf("%s.name is required", path))
+		case configs[config.Name]:
+			errs = append(errs, fmt.Errorf("%s: config %q is declared twice", path, config.Name))
+		}
+		configs[config.Name] = true
+		if _, _, err := models.ParseLimit(config.Limit); err != nil {
+			errs = append(errs, fmt.Errorf("%s.%v", path, err))
+		}
+
+		headers := make(map[string]bool)
+		for j, header := range config.Headers {
+			headerPath := fmt.Sprintf("%s.headers[%d]", path, j)
+			switch {
+			case strings.TrimSpace(header.Name) == "":
+				errs = append(errs, fmt.Errorf("%s.name is required", headerPath))
+			case headers[strings.ToLower(header.Name)]:
+				errs = append(errs, fmt.Errorf("%s: header %q is declared twice", headerPath, header.Name))
+			}
+			headers[strings.ToLower(header.Name)] = true
+			if countSet(header.Value != "", header.EncryptedValue != "", header.ValueFrom != nil) > 1 {
+				errs = append(errs, fmt.Errorf("%s: set one of value, encrypted_value and value_from", headerPath))
+			}
+			if from := header.ValueFrom; from != nil && countSet(from.Env != "", from.File != "") != 1 {
+				errs = append(errs, fmt.Errorf("%s.value_from: set either env or file", headerPath))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func countSet(values ...bool) int {
+	count := 0
+	for _, set := range values {
+		if set {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,271 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+var testEncrypter, _ = utils.NewEncrypter([]byte("0123456789abcdef0123456789abcdef"))
+
+const document = `
+version: 1
+project:
+  name: payments
+  description: Payment providers
+  configs:
+    - name: stripe
+      limit: 100/hour
+      headers:
+        - name: Authorization
+          value: Bearer stripe
+    - name: adyen
+      limit: 10/second
+      headers:
+        - name: X-Api-Key
+          value: adyen
+`
+
+func TestDecodeValidates(t *testing.T) {
+	_, err := Decode([]byte(`
+version: 2
+project:
+  configs:
+    - name: stripe
+      limit: 100/fortnight
+      headers:
+        - name: Authorization
+        - name: authorization
+`))
+	if err == nil {
+		t.Fatalf("expected the document to be rejected")
+	}
+	for _, message := range []string{"version must be 1", "project.name is required", `got "fortnight"`, "declared twice"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected the error to contain %q, got %v", message, err)
+		}
+	}
+
+	if _, err := Decode([]byte(`{"version": 1, "project": {"name": "payments", "owner": "me"}}`)); err == nil {
+		t.Errorf("expected unknown fields to be rejected")
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	userID := uuid.New()
+
+	desired, err := Decode([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan(ctx, testEncrypter, nil, *desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts := plan.Count(); counts[ActionCreate] != 5 || len(plan.Changes) != 5 {
+		t.Fatalf("expected the project, two configs and two headers to be created, got %+v", plan.Changes)
+	}
+	if _, err := plan.Apply(ctx, store, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := FindProject(ctx, store, userID, "payments")
+	if err != nil || project == nil || len(project.Configs) != 2 {
+		t.Fatalf("expected the project to be created with its configs, got %+v, %v", project, err)
+	}
+	plan, _ = NewPlan(ctx, testEncrypter, project, *desired, Options{})
+	if !plan.Empty() {
+		t.Fatalf("expected importing the document again to change nothing, got %+v", plan.Changes)
+	}
+
+	// The exported document, with encrypted values, round trips.
+	var exported bytes.Buffer
+	if err := Encode(&exported, Export(*project, true), FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := Decode(exported.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan, _ := NewPlan(ctx, testEncrypter, project, *roundTrip, Options{}); !plan.Empty() {
+		t.Fatalf("expected the export to match the project, got %+v", plan.Changes)
+	}
+
+	desired.Project.Configs = desired.Project.Configs[:1]
+	desired.Project.Configs[0].Limit = "200/hour"
+	desired.Project.Configs[0].Headers = []Header{{Name: "Authorization", Value: "Bearer rotated"}, {Name: "X-Account", Value: "acct"}}
+	plan, err = NewPlan(ctx, testEncrypter, project, *desired, Options{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"update config stripe limit 100/hour → 200/hour", "update header stripe/Authorization value",
+		"create header stripe/X-Account", "delete config adyen"}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), plan.Changes)
+	}
+	for i, change := range plan.Changes {
+		if got := strings.TrimSpace(strings.Join([]string{change.Action, change.Resource, change.Name, change.Detail}, " ")); got != want[i] {
+			t.Errorf("change %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+	if _, err := plan.Apply(ctx, store, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	project, _ = FindProject(ctx, store, userID, "payments")
+	if len(project.Configs) != 1 || project.Configs[0].LimitNumberOfRequests != 200 || len(project.Configs[0].HeaderReplacements) != 2 {
+		t.Fatalf("unexpected project after the update %+v", project)
+	}
+	for _, header := range project.Configs[0].HeaderReplacements {
+		if header.HeaderName == "Authorization" {
+			if value, _ := testEncrypter.Decrypt(ctx, header.HeaderValue); value != "Bearer rotated" {
+				t.Errorf("expected the header value to be rotated, got %q", value)
+			}
+		}
+	}
+}
+
+func TestFindProjectRejectsDuplicateNames(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	userID := uuid.New()
+	store.CreateProject(ctx, "payments", "", "key", userID)
+	store.CreateProject(ctx, "refunds", "", "key", userID)
+
+	if project, err := FindProject(ctx, store, userID, "payments"); err != nil || project == nil || project.Name != "payments" {
+		t.Fatalf("expected the project to be found, got %+v, %v", project, err)
+	}
+
+	store.CreateProject(ctx, "payments", "", "key", userID)
+	if project, err := FindProject(ctx, store, userID, "payments"); !errors.Is(err, ErrAmbiguousProject) || project != nil {
+		t.Fatalf("expected duplicate names to be rejected, got %+v, %v", project, err)
+	}
+	// The projects of other users do not count.
+	if project, err := FindProject(ctx, store, uuid.New(), "payments"); err != nil || project != nil {
+		t.Fatalf("expected no project for another user, got %+v, %v", project, err)
+	}
+}
+
+func TestPlanRejectsValuesOfAnotherKey(t *testing.T) {
+	ctx := context.Background()
+	otherKey, _ := utils.NewEncrypter([]byte(strings.Repeat("k", 32)))
+	encrypted, _ := otherKey.Encrypt(ctx, "secret")
+
+	document := Document{Version: Version, Project: Project{Name: "payments", Configs: []Config{{
+		Name: "stripe", Limit: "1/second", Headers: []Header{{Name: "Authorization", EncryptedValue: encrypted}},
+	}}}}
+	if _, err := NewPlan(ctx, testEncrypter, nil, document, Options{}); err == nil || !strings.Contains(err.Error(), "SECRET_KEY") {
+		t.Fatalf("expected the foreign encrypted value to be rejected, got %v", err)
+	}
+
+	document.Project.Configs[0].Headers[0] = Header{Name: "Authorization"}
+	if _, err := NewPlan(ctx, testEncrypter, nil, document, Options{}); err == nil || !strings.Contains(err.Error(), "value is required") {
+		t.Fatalf("expected a header without a value not to be created, got %v", err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	encrypted, _ := testEncrypter.Encrypt(ctx, "Bearer from file")
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("payments/payments.yaml", `
+version: 1
+project:
+  name: payments
+  configs:
+    - name: stripe
+      limit: 100/hour
+      headers:
+        - name: Authorization
+          value_from:
+            file: secrets/stripe.enc
+        - name: X-Account
+          value_from:
+            env: STRIPE_ACCOUNT
+`)
+	writeFile("payments/secrets/stripe.enc", encrypted+"\n")
+	writeFile("maps.json", `{"version": 1, "project": {"name": "maps", "configs": []}}`)
+	writeFile(".git/config.yaml", "not a document")
+	writeFile("README.md", "# Projects")
+
+	env := map[string]string{"STRIPE_ACCOUNT": "acct_1"}
+	lookupEnv := func(name string) (string, bool) {
+		value, found := env[name]
+		return value, found
+	}
+	sources, err := ReadDir(dir, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[0].Document.Project.Name != "maps" || sources[1].Document.Project.Name != "payments" {
+		t.Fatalf("expected the maps and payments documents, got %+v", sources)
+	}
+	headers := sources[1].Document.Project.Configs[0].Headers
+	if headers[0].EncryptedValue != encrypted || headers[1].Value != "acct_1" || headers[0].ValueFrom != nil {
+		t.Fatalf("expected the values to be resolved, got %+v", headers)
+	}
+	if _, err := NewPlan(ctx, testEncrypter, nil, sources[1].Document, Options{}); err != nil {
+		t.Fatalf("expected the resolved document to be planned, got %v", err)
+	}
+
+	delete(env, "STRIPE_ACCOUNT")
+	if _, err := ReadDir(dir, lookupEnv); err == nil || !strings.Contains(err.Error(), "STRIPE_ACCOUNT is not set") {
+		t.Fatalf("expected the missing environment variable to be reported, got %v", err)
+	}
+
+	env["STRIPE_ACCOUNT"] = "acct_1"
+	writeFile("copy.yml", "version: 1\nproject:\n  name: maps\n  configs: []\n")
+	if _, err := ReadDir(dir, lookupEnv); err == nil || !strings.Contains(err.Error(), "already described") {
+		t.Fatalf("expected a project described twice to be rejected, got %v", err)
+	}
+
+	writeFile("copy.yml", `
+version: 1
+project:
+  name: search
+  configs:
+    - name: algolia
+      limit: 10/second
+      headers:
+        - name: X-Api-Key
+          value: secret
+`)
+	if _, err := ReadDir(dir, lookupEnv); err == nil || !strings.Contains(err.Error(), "header algolia/X-Api-Key: value is in plain text") {
+		t.Fatalf("expected a plain text header value to be rejected, got %v", err)
+	}
+}
+
+func TestValueFromIsOnlyResolvedFromDirectories(t *testing.T) {
+	document := Document{Version: Version, Project: Project{Name: "payments", Configs: []Config{{
+		Name: "stripe", Limit: "1/second", Headers: []Header{{Name: "Authorization", ValueFrom: &ValueFrom{Env: "TOKEN"}}},
+	}}}}
+	if _, err := NewPlan(context.Background(), testEncrypter, nil, document, Options{}); err == nil || !strings.Contains(err.Error(), "value_from") {
+		t.Fatalf("expected an unresolved value_from to be rejected, got %v", err)
+	}
+
+	document.Project.Configs[0].Headers[0].ValueFrom = &ValueFrom{Env: "TOKEN", File: "token.enc"}
+	if err := document.Validate(); err == nil || !strings.Contains(err.Error(), "set either env or file") {
+		t.Fatalf("expected value_from to name a single source, got %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"configuration-management/internal/webhooks"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	ResourceProject = "project"
+	ResourceConfig  = "config"
+	ResourceHeader  = "header"
+)
+
+// Change is a single difference between the document and the database.
+// Details never contain header values.
+type Change struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+	// Name is the project or config name, or config/header for headers.
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+
+	apply func(ctx context.Context, tx database.Store, state *applyState) error
+}
+
+type Options struct {
+	// Prune deletes the configs of the project missing from the document,
+	// which are left untouched otherwise. The headers of a config in the
+	// document are always replaced by the ones of the document.
+	Prune bool
+}
+
+type Plan struct {
+	Changes []Change
+	project *models.Project
+	configs map[string]uuid.UUID
+}
+
+type applyState struct {
+	project *models.Project
+	// configs maps the config names to their ids, including the configs
+	// created while applying the plan.
+	configs map[string]uuid.UUID
+	events  []event
+}
+
+type event struct {
+	eventType string
+	data      any
+}
+
+// Result is the outcome of an applied plan.
+type Result struct {
+	Project *models.Project
+	events  []event
+}
+
+// NewPlan diffs the document against the project, which is nil when it does
+// not exist yet and must otherwise have its configs and headers loaded. The
+// stored header values are decrypted to be compared with the document, and
+// the values of the document are encrypted while planning, so that applying
+// the plan only writes to the database.
+func NewPlan(ctx context.Context, encrypter *utils.Encrypter, project *models.Project, document Document, options Options) (*Plan, error) {
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{project: project, configs: make(map[string]uuid.UUID)}
+	desired := document.Project
+	existingConfigs := make(map[string]models.Config)
+	if project == nil {
+		plan.add(Change{Action: ActionCreate, Resource: ResourceProject, Name: desired.Name,
+			apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+				return state.createProject(ctx, tx, desired)
+			}})
+	} else {
+		if project.Description != desired.Description {
+			plan.add(Change{Action: ActionUpdate, Resource: ResourceProject, Name: project.Name, Detail: "description",
+				apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+					if err := tx.UpdateProjectDescription(ctx, state.project.ID, desired.Description); err != nil {
+						return err
+					}
+					state.project.Description = desired.Description
+					state.publish(models.EventProjectUpdated, webhooks.NewProjectPayload(*state.project))
+					return nil
+				}})
+		}
+		for _, config := range project.Configs {
+			existingConfigs[config.Name] = config
+			plan.configs[config.Name] = config.ID
+		}
+	}
+
+	for _, config := range desired.Configs {
+		existing, found := existingConfigs[config.Name]
+		if err := plan.diffConfig(ctx, encrypter, config, existing, found); err != nil {
+			return nil, err
+		}
+		delete(existingConfigs, config.Name)
+	}
+
+	if options.Prune && project != nil {
+		for _, config := range project.Configs {
+			if _, unmanaged := existingConfigs[config.Name]; !unmanaged {
+				continue
+			}
+			plan.add(Change{Action: ActionDelete, Resource: ResourceConfig, Name: config.Name,
+				apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+					if err := tx.DeleteConfig(ctx, config.ID); err != nil {
+						return err
+					}
+					state.publish(models.EventConfigDeleted, webhooks.NewConfigPayload(config))
+					return nil
+				}})
+		}
+	}
+
+	return plan, nil
+}
+
+func (p *Plan) add(change Change) {
+	p.Changes = append(p.Changes, change)
+}
+
+// Empty reports whether the database already matches the document.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of changes of each action.
+func (p *Plan) Count() map[string]int {
+	counts := map[string]int{ActionCreate: 0, ActionUpdate: 0, ActionDelete: 0}
+	for _, change := range p.Changes {
+		counts[change.Action]++
+	}
+	return counts
+}
+
+func (p *Plan) diffConfig(ctx context.Context, encrypter *utils.Encrypter, config Config, existing models.Config, found bool) error {
+	numberOfRequests, per, err := models.ParseLimit(config.Limit)
+	if err != nil {
+		return fmt.Errorf("config %q: %v", config.Name, err)
+	}
+	limit := models.FormatLimit(numberOfRequests, per)
+
+	switch {
+	case !found:
+		p.add(Change{Action: ActionCreate, Resource: ResourceConfig, Name: config.Name, Detail: limit,
+			apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+				created, err := tx.CreateConfig(ctx, state.project.ID, config.Name, numberOfRequests, per)
+				if err != nil {
+					return err
+				}
+				state.configs[config.Name] = created.ID
+				state.publish(models.EventConfigCreated, webhooks.NewConfigPayload(*created))
+				return nil
+			}})
+	case existing.LimitNumberOfRequests != numberOfRequests || existing.LimitPer != per:
+		detail := fmt.Sprintf("limit %s → %s", models.FormatLimit(existing.LimitNumberOfRequests, existing.LimitPer), limit)
+		p.add(Change{Action: ActionUpdate, Resource: ResourceConfig, Name: config.Name, Detail: detail,
+			apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+				if err := tx.UpdateConfigLimit(ctx, existing.ID, numberOfRequests, per); err != nil {
+					return err
+				}
+				updated := existing
+				updated.LimitNumberOfRequests, updated.LimitPer = numberOfRequests, per
+				state.publish(models.EventConfigUpdated, webhooks.NewConfigPayload(updated))
+				return nil
+			}})
+	}
+
+	existingHeaders := make(map[string]models.HeaderReplacement)
+	for _, header := range existing.HeaderReplacements {
+		existingHeaders[strings.ToLower(header.HeaderName)] = header
+	}
+
+	for _, header := range config.Headers {
+		name := config.Name + "/" + header.Name
+		value, err := headerValue(ctx, encrypter, header)
+		if err != nil {
+			return fmt.Errorf("header %s: %v", name, err)
+		}
+
+		current, found := existingHeaders[strings.ToLower(header.Name)]
+		delete(existingHeaders, strings.ToLower(header.Name))
+		if !found {
+			if value == "" {
+				return fmt.Errorf("header %s: a value is required to create the header", name)
+			}
+			encrypted, err := encrypter.Encrypt(ctx, value)
+			if err != nil {
+				return fmt.Errorf("header %s: failed to encrypt the value: %v", name, err)
+			}
+			p.add(Change{Action: ActionCreate, Resource: ResourceHeader, Name: name,
+				apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+					created, err := tx.CreateHeaderReplacement(ctx, state.configs[config.Name], header.Name, encrypted)
+					if err != nil {
+						return err
+					}
+					state.publish(models.EventHeaderCreated, webhooks.NewHeaderPayload(*created))
+					return nil
+				}})
+			continue
+		}
+
+		if value == "" {
+			continue
+		}
+		if currentValue, err := encrypter.Decrypt(ctx, current.HeaderValue); err == nil && currentValue == value {
+			continue
+		}
+		encrypted, err := encrypter.Encrypt(ctx, value)
+		if err != nil {
+			return fmt.Errorf("header %s: failed to encrypt the value: %v", name, err)
+		}
+		p.add(Change{Action: ActionUpdate, Resource: ResourceHeader, Name: name, Detail: "value",
+			apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+				if err := tx.UpdateHeaderReplacementValue(ctx, current.ID, encrypted); err != nil {
+					return err
+				}
+				state.publish(models.EventHeaderUpdated, webhooks.NewHeaderPayload(current))
+				return nil
+			}})
+	}
+
+	for _, header := range existing.HeaderReplacements {
+		if _, removed := existingHeaders[strings.ToLower(header.HeaderName)]; !removed {
+			continue
+		}
+		p.add(Change{Action: ActionDelete, Resource: ResourceHeader, Name: config.Name + "/" + header.HeaderName,
+			apply: func(ctx context.Context, tx database.Store, state *applyState) error {
+				if err := tx.DeleteHeaderReplacement(ctx, header.ID); err != nil {
+					return err
+				}
+				state.publish(models.EventHeaderDeleted, webhooks.NewHeaderPayload(header))
+				return nil
+			}})
+	}
+
+	return nil
+}
+
+// headerValue returns the plain text value of the header, empty when the
+// document does not set it.
+func headerValue(ctx context.Context, encrypter *utils.Encrypter, header Header) (string, error) {
+	if header.ValueFrom != nil {
+		return "", fmt.Errorf("value_from is only supported when reconciling a directory")
+	}
+	if header.EncryptedValue == "" {
+		return header.Value, nil
+	}
+
+	value, err := encrypter.Decrypt(ctx, header.EncryptedValue)
+	if err != nil || value == "" {
+		return "", fmt.Errorf("the encrypted value cannot be decrypted, it was exported with another SECRET_KEY")
+	}
+	return value, nil
+}
+
+// Apply makes the changes with tx, which should be a transaction so that a
+// failed change leaves the database untouched. userID owns the project when
+// the plan creates it.
+func (p *Plan) Apply(ctx context.Context, tx database.Store, userID uuid.UUID) (*Result, error) {
+	state := &applyState{project: p.project, configs: make(map[string]uuid.UUID, len(p.configs))}
+	if state.project == nil {
+		state.project = &models.Project{UserID: userID}
+	} else {
+		project := *p.project
+		state.project = &project
+	}
+	for name, id := range p.configs {
+		state.configs[name] = id
+	}
+
+	for _, change := range p.Changes {
+		if err := change.apply(ctx, tx, state); err != nil {
+			return nil, fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Resource, change.Name, err)
+		}
+	}
+
+	return &Result{Project: state.project, events: state.events}, nil
+}
+
+func (s *applyState) createProject(ctx context.Context, tx database.Store, desired Project) error {
+	project, err := tx.CreateProject(ctx, desired.Name, desired.Description, utils.GenerateToken(32), s.project.UserID)
+	if err != nil {
+		return err
+	}
+	s.project = project
+	s.publish(models.EventProjectCreated, webhooks.NewProjectPayload(*project))
+	return nil
+}
+
+func (s *applyState) publish(eventType string, data any) {
+	s.events = append(s.events, event{eventType, data})
+}
+
+// Publish delivers the webhook events of the applied changes, once the
+// transaction they were applied in is committed.
+func (r *Result) Publish(ctx context.Context, dispatcher *webhooks.Dispatcher) {
+	for _, event := range r.events {
+		dispatcher.Publish(ctx, r.Project.ID, event.eventType, event.data)
+	}
+}
+
+// ErrAmbiguousProject is returned by FindProject when several projects of
+// the user have the name, the document could apply to any of them.
+var ErrAmbiguousProject = errors.New("several projects have this name")
+
+// FindProject returns the project of the user with the given name, with its
+// configs and headers loaded, or nil when there is none.
+func FindProject(ctx context.Context, projects database.ProjectStore, userID uuid.UUID, name string) (*models.Project, error) {
+	list, err := projects.ListProjects(ctx, userID, database.ProjectListOptions{WithConfigs: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.Project
+	for _, project := range list {
+		if project.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %q, rename them or import into one of them by id", ErrAmbiguousProject, name)
+		}
+		found = &project
+	}
+	return found, nil
+}
@@ -0,0 +1,168 @@
+// Package metrics exposes Prometheus metrics about the HTTP requests, the
+// database connection pool, the stored resources and the security relevant
+// events of the management plane.
+package metrics
+
+import (
+	"configuration-management/internal/database"
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "limiter"
+
+	LoginSucceeded = "success"
+	LoginFailed    = "failure"
+
+	// RevealHeaderValue and the other reveal kinds name the secrets shown
+	// to users in plain text.
+	RevealHeaderValue    = "header_value"
+	RevealWebhookSecret  = "webhook_secret"
+	RevealConnectionInfo = "connection"
+)
+
+// Metrics is safe to use as a nil pointer, which records nothing.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	logins          *prometheus.CounterVec
+	reveals         *prometheus.CounterVec
+}
+
+// New registers the pool statistics of db unless it is nil, and the
+// resource counts of store, queried on every scrape.
+func New(store database.StatsStore, db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latencies by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by provider and result.",
+		}, []string{"provider", "result"}),
+		reveals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "secret_reveals_total",
+			Help:      "Secrets shown in plain text by kind.",
+		}, []string{"kind"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.logins,
+		m.reveals,
+		newResourceCollector(store),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "limiter"))
+	}
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records the requests by route rather than by path, so that
+// ids do not create a series each.
+func (m *Metrics) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request().Method
+		m.requests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+		m.requestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+func (m *Metrics) Login(provider string, result string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(provider, result).Inc()
+}
+
+func (m *Metrics) Reveal(kind string) {
+	if m == nil {
+		return
+	}
+	m.reveals.WithLabelValues(kind).Inc()
+}
+
+// resourceCollector counts the stored resources when scraped.
+type resourceCollector struct {
+	store              database.StatsStore
+	users              *prometheus.Desc
+	projects           *prometheus.Desc
+	configs            *prometheus.Desc
+	headerReplacements *prometheus.Desc
+}
+
+func newResourceCollector(store database.StatsStore) *resourceCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+	}
+	return &resourceCollector{
+		store:              store,
+		users:              desc("users", "Number of users."),
+		projects:           desc("projects", "Number of projects."),
+		configs:            desc("configs", "Number of configurations."),
+		headerReplacements: desc("header_replacements", "Number of header replacements."),
+	}
+}
+
+func (r *resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.users
+	ch <- r.projects
+	ch <- r.configs
+	ch <- r.headerReplacements
+}
+
+func (r *resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts, err := r.store.CountResources(ctx)
+	if err != nil {
+		slog.Error("failed to collect resource metrics", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(r.users, prometheus.GaugeValue, float64(counts.Users))
+	ch <- prometheus.MustNewConstMetric(r.projects, prometheus.GaugeValue, float64(counts.Projects))
+	ch <- prometheus.MustNewConstMetric(r.configs, prometheus.GaugeValue, float64(counts.Configs))
+	ch <- prometheus.MustNewConstMetric(r.headerReplacements, prometheus.GaugeValue, float64(counts.HeaderReplacements))
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AlertKindQuota      = "quota"
+	AlertKindRejections = "rejections"
+
+	AlertChannelWebhook = "webhook"
+	AlertChannelEmail   = "email"
+
+	AlertStateOK     = "ok"
+	AlertStateFiring = "firing"
+)
+
+type Alert struct {
+	ID              uuid.UUID
+	ConfigID        uuid.UUID
+	Kind            string
+	Threshold       int
+	WindowMinutes   int
+	Channel         string
+	Target          string
+	State           string
+	LastValue       int
+	LastEvaluatedAt *time.Time
+	LastTriggeredAt *time.Time
+}
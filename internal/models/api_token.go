@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIToken authenticates the HTTP API requests of command-line clients as
+// its user, only the hash of the token is stored.
+type APIToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
@@ -1,6 +1,12 @@
 package models
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -11,4 +17,63 @@ type Config struct {
 	LimitNumberOfRequests int
 	LimitPer              string
 	HeaderReplacements    []HeaderReplacement
+	Alerts                []Alert
+	Clients               []ConfigClient
+	// ActiveFrom and ExpiresAt are nil when the config is not limited to a
+	// period.
+	ActiveFrom *time.Time
+	ExpiresAt  *time.Time
+	// Schedule lists the weekly windows the config is served within, every
+	// time when it is empty.
+	Schedule []ScheduleWindow
+	// Disabled is set once the config expired or was switched off by hand,
+	// whether its project is disabled is left to the project.
+	Disabled *Disablement
+}
+
+const (
+	ConfigStatusActive      = "active"
+	ConfigStatusPending     = "pending"
+	ConfigStatusOffSchedule = "off schedule"
+	ConfigStatusExpired     = "expired"
+	ConfigStatusDisabled    = "disabled"
+)
+
+// Status tells whether the proxy serves the config at now, following the
+// rules of the proxy_configs view.
+func (c Config) Status(now time.Time) string {
+	switch {
+	case c.Disabled != nil:
+		return ConfigStatusDisabled
+	case c.ExpiresAt != nil && !now.Before(*c.ExpiresAt):
+		return ConfigStatusExpired
+	case c.ActiveFrom != nil && now.Before(*c.ActiveFrom):
+		return ConfigStatusPending
+	case len(c.Schedule) > 0 && !slices.ContainsFunc(c.Schedule, func(w ScheduleWindow) bool { return w.Contains(now) }):
+		return ConfigStatusOffSchedule
+	default:
+		return ConfigStatusActive
+	}
+}
+
+// LimitPeriods are the periods over which the requests of a config are
+// counted.
+var LimitPeriods = []string{"second", "minute", "hour", "day", "week", "month", "year", "forever"}
+
+// FormatLimit writes a limit as requests/period, such as 100/hour.
+func FormatLimit(numberOfRequests int, per string) string {
+	return strconv.Itoa(numberOfRequests) + "/" + per
+}
+
+// ParseLimit reads a limit written by FormatLimit.
+func ParseLimit(limit string) (int, string, error) {
+	count, per, ok := strings.Cut(strings.TrimSpace(limit), "/")
+	numberOfRequests, err := strconv.Atoi(count)
+	if !ok || err != nil || numberOfRequests <= 0 {
+		return 0, "", fmt.Errorf("limit must be a positive number of requests per period such as 100/hour, got %q", limit)
+	}
+	if !slices.Contains(LimitPeriods, per) {
+		return 0, "", fmt.Errorf("limit period must be one of %s, got %q", strings.Join(LimitPeriods, ", "), per)
+	}
+	return numberOfRequests, per, nil
 }
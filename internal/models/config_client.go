@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConfigClient is a consumer of a config with its own proxy URL, so that it
+// can be revoked on its own. Only the hash of its secret is stored.
+type ConfigClient struct {
+	ID         uuid.UUID
+	ConfigID   uuid.UUID
+	Name       string
+	SecretHash string
+	// ExpiresAt is nil when the client does not expire.
+	ExpiresAt *time.Time
+	// LimitNumberOfRequests is counted over the period of the config, it
+	// is nil when the client shares the limit of the config.
+	LimitNumberOfRequests *int
+	// LastUsedAt is recorded by the proxy.
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (c ConfigClient) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProviderLocal identifies the users that sign in with a username and a
+// password, their subject is the username.
+const ProviderLocal = "local"
+
+type Credentials struct {
+	UserID       uuid.UUID
+	Username     string
+	PasswordHash string
+	// TOTPSecret is encrypted with the SECRET_KEY. It is set while the
+	// second factor is being enrolled, before TOTPEnabled.
+	TOTPSecret  string
+	TOTPEnabled bool
+	// TOTPFailures counts the invalid codes since the last accepted one.
+	TOTPFailures int
+	TOTPFailedAt *time.Time
+	// FirstAccount is set on the account created without an invite while
+	// there was none, only one such account can exist.
+	FirstAccount bool
+	CreatedAt    time.Time
+}
+
+const (
+	AuthTokenInvite        = "invite"
+	AuthTokenPasswordReset = "password_reset"
+)
+
+// AuthToken is a single use invite or password reset token, only the hash
+// of the token sent to the user is stored.
+type AuthToken struct {
+	ID        uuid.UUID
+	Kind      string
+	TokenHash string
+	// UserID is the user whose password is reset, nil for invites.
+	UserID    *uuid.UUID
+	Email     string
+	CreatedBy *uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
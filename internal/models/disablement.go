@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DisabledReasonExpired is the reason recorded by the expiry job, saving the
+// availability of a config enables it again.
+const DisabledReasonExpired = "expired"
+
+// Disablement records why a config or project was switched off, the proxy
+// serves neither a disabled config nor the configs of a disabled project.
+type Disablement struct {
+	At     time.Time
+	Reason string
+	// By is the id of the user who disabled it, nil when the application
+	// did, such as on expiry. The name is looked up when shown, so that it
+	// follows renames.
+	By *uuid.UUID
+}
+
+// NewDisablement returns nil when at is nil, as read from the nullable
+// disabled_at column. by is the disabled_by column, empty when the
+// application disabled it.
+func NewDisablement(at *time.Time, reason string, by string) *Disablement {
+	if at == nil {
+		return nil
+	}
+	disablement := &Disablement{At: *at, Reason: reason}
+	if userID, err := uuid.Parse(by); err == nil {
+		disablement.By = &userID
+	}
+	return disablement
+}
+
+// ByColumn is the value of the disabled_by column.
+func (d Disablement) ByColumn() string {
+	if d.By == nil {
+		return ""
+	}
+	return d.By.String()
+}
@@ -4,5 +4,6 @@ type GithubUser struct {
 	Id        int    `json:"id"`
 	Login     string `json:"login"`
 	Name      string `json:"name"`
+	Email     string `json:"email"`
 	AvatarUrl string `json:"avatar_url"`
 }
@@ -9,4 +9,7 @@ type Project struct {
 	UserID      uuid.UUID
 	AccessKey   string
 	Configs     []Config
+	Webhooks    []Webhook
+	// Disabled is set while none of the configs of the project is served.
+	Disabled *Disablement
 }
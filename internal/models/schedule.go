@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScheduleWindow is a weekly window in UTC, from StartMinute included to
+// EndMinute excluded, counted from midnight.
+type ScheduleWindow struct {
+	Weekday     time.Weekday
+	StartMinute int
+	EndMinute   int
+}
+
+func (w ScheduleWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	minute := t.Hour()*60 + t.Minute()
+	return t.Weekday() == w.Weekday && w.StartMinute <= minute && minute < w.EndMinute
+}
+
+// Validate checks the window fits in its day.
+func (w ScheduleWindow) Validate() error {
+	if w.Weekday < time.Sunday || w.Weekday > time.Saturday {
+		return fmt.Errorf("invalid weekday %d", w.Weekday)
+	}
+	if w.StartMinute < 0 || w.EndMinute > 24*60 || w.StartMinute >= w.EndMinute {
+		return fmt.Errorf("the window must start before it ends within the day, got %s-%s",
+			FormatMinute(w.StartMinute), FormatMinute(w.EndMinute))
+	}
+	return nil
+}
+
+// FormatMinute writes a minute of the day as 15:04, the end of the day as
+// 24:00.
+func FormatMinute(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// ParseMinute reads a time of the day written as 15:04, or 24:00 for the end
+// of the day.
+func ParseMinute(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time of the day must be written as 15:04, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// mondayFirst orders the weekdays the way schedules are written.
+var mondayFirst = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// FormatSchedule writes the windows grouped by hours, such as
+// "Mon-Fri 09:00-17:00 UTC".
+func FormatSchedule(windows []ScheduleWindow) string {
+	type hours struct{ start, end int }
+	var order []hours
+	days := make(map[hours][]time.Weekday)
+	for _, weekday := range mondayFirst {
+		for _, window := range windows {
+			if window.Weekday != weekday {
+				continue
+			}
+			h := hours{window.StartMinute, window.EndMinute}
+			if _, ok := days[h]; !ok {
+				order = append(order, h)
+			}
+			days[h] = append(days[h], weekday)
+		}
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, h := range order {
+		parts = append(parts, formatDays(days[h])+" "+FormatMinute(h.start)+"-"+FormatMinute(h.end))
+	}
+	return strings.Join(parts, ", ") + " UTC"
+}
+
+// formatDays writes consecutive days as a range, such as Mon-Fri.
+func formatDays(weekdays []time.Weekday) string {
+	index := func(weekday time.Weekday) int { return (int(weekday) + 6) % 7 }
+	var parts []string
+	for i := 0; i < len(weekdays); {
+		j := i
+		for j+1 < len(weekdays) && index(weekdays[j+1]) == index(weekdays[j])+1 {
+			j++
+		}
+		part := weekdays[i].String()[:3]
+		if j > i {
+			part += "-" + weekdays[j].String()[:3]
+		}
+		parts = append(parts, part)
+		i = j + 1
+	}
+	return strings.Join(parts, " ")
+}
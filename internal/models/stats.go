@@ -0,0 +1,9 @@
+package models
+
+// ResourceCounts are the totals across every user, exposed as metrics.
+type ResourceCounts struct {
+	Users              int
+	Projects           int
+	Configs            int
+	HeaderReplacements int
+}
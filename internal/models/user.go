@@ -2,9 +2,22 @@ package models
 
 import "github.com/google/uuid"
 
+// User is identified by the identity provider it signed in with and the
+// subject that provider knows it by.
 type User struct {
 	ID        uuid.UUID
-	OAuth2ID  int
+	Provider  string
+	Subject   string
 	Name      string
+	Email     string
 	AvatarUrl string
 }
+
+// DisplayName is the name of the user, or provider:subject when the
+// provider did not give one.
+func (u User) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.Provider + ":" + u.Subject
+}
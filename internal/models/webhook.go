@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventProjectCreated   = "project.created"
+	EventProjectUpdated   = "project.updated"
+	EventProjectDeleted   = "project.deleted"
+	EventProjectDisabled  = "project.disabled"
+	EventProjectEnabled   = "project.enabled"
+	EventConfigCreated    = "config.created"
+	EventConfigUpdated    = "config.updated"
+	EventConfigDeleted    = "config.deleted"
+	EventConfigExpired    = "config.expired"
+	EventConfigDisabled   = "config.disabled"
+	EventConfigEnabled    = "config.enabled"
+	EventHeaderCreated    = "header.created"
+	EventHeaderUpdated    = "header.updated"
+	EventHeaderDeleted    = "header.deleted"
+	EventAccessKeyRotated = "access_key.rotated"
+	EventWebhookTest      = "webhook.test"
+)
+
+var WebhookEvents = []string{
+	EventProjectCreated, EventProjectUpdated, EventProjectDeleted, EventProjectDisabled, EventProjectEnabled,
+	EventConfigCreated, EventConfigUpdated, EventConfigDeleted, EventConfigExpired, EventConfigDisabled, EventConfigEnabled,
+	EventHeaderCreated, EventHeaderUpdated, EventHeaderDeleted,
+	EventAccessKeyRotated,
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	ProjectID uuid.UUID
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+func (w Webhook) Subscribes(event string) bool {
+	if event == EventWebhookTest {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID
+	WebhookID  uuid.UUID
+	EventID    uuid.UUID
+	Event      string
+	Attempt    int
+	StatusCode int
+	Error      string
+	CreatedAt  time.Time
+}
+
+func (d WebhookDelivery) Successful() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode < 300
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LocalAccount loads the credentials of the logged user, the account pages
+// only exist for users signed in with a username and a password.
+func (s *Server) LocalAccount(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := c.Get("user").(*models.User)
+		if !ok {
+			return handlers.InternalError("missing user")
+		}
+
+		credentials, err := s.db.GetUserCredentials(c.Request().Context(), user.ID)
+		if errors.Is(err, database.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		if err != nil {
+			return handlers.InternalError("failed to get credentials: %w", err)
+		}
+
+		c.Set("credentials", credentials)
+		return next(c)
+	}
+}
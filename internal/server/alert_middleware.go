@@ -0,0 +1,40 @@
+package server
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) AlertBelongsToConfig(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		config, ok := c.Get("config").(*models.Config)
+		if !ok {
+			return handlers.InternalError("missing config")
+		}
+		alertID, idErr := uuid.Parse(c.Param("alertId"))
+		if idErr != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid alert id")
+		}
+
+		alert, err := s.db.GetAlert(c.Request().Context(), alertID)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			return handlers.InternalError("failed to get alert: %w", err)
+		}
+
+		if alert.ConfigID != config.ID {
+			return echo.NewHTTPError(http.StatusBadRequest).SetInternal(errors.New("alert does not belong to the config"))
+		}
+
+		c.Set("alert", alert)
+		return next(c)
+	}
+}
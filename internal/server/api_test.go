@@ -0,0 +1,156 @@
+package server
+
+import (
+	"configuration-management/internal/api"
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/models"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	cfg.ProxyHost = "proxy.example.com"
+	server := newTestServer(t, cfg, io.Discard)
+	httpServer := httptest.NewServer(server.RegisterRoutes())
+	defer httpServer.Close()
+
+	user, _ := server.db.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	other, _ := server.db.CreateUser(ctx, models.User{Provider: "github", Subject: "2", Name: "other"})
+	secret, tokenHash, _ := auth.NewAuthToken()
+	token, _ := server.db.CreateAPIToken(ctx, user.ID, "laptop", tokenHash)
+	otherProject, _ := server.db.CreateProject(ctx, "other", "", "key", other.ID)
+
+	var statusErr *api.StatusError
+	if _, err := api.NewClient(httpServer.URL, "invalid").ListProjects(ctx); !errors.As(err, &statusErr) ||
+		statusErr.StatusCode != http.StatusUnauthorized || statusErr.Message != "Invalid API token" {
+		t.Fatalf("expected an invalid token to be rejected, got %v", err)
+	}
+
+	client := api.NewClient(httpServer.URL, secret)
+	project, err := client.CreateProject(ctx, api.CreateProjectRequest{Name: "payments", Description: "Payment providers"})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+	config, err := client.CreateConfig(ctx, project.ID, api.ConfigRequest{Name: "stripe", Limit: "100/hour"})
+	if err != nil || config.Limit != "100/hour" {
+		t.Fatalf("CreateConfig returned %+v, %v", config, err)
+	}
+	if _, err := client.CreateConfig(ctx, project.ID, api.ConfigRequest{Name: "adyen", Limit: "100/fortnight"}); !errors.As(err, &statusErr) ||
+		statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an invalid limit to be rejected, got %v", err)
+	}
+
+	if config, err = client.UpdateConfig(ctx, project.ID, config.ID, api.ConfigRequest{Limit: "200/hour"}); err != nil || config.Limit != "200/hour" {
+		t.Fatalf("UpdateConfig returned %+v, %v", config, err)
+	}
+	if config, err = client.SetHeader(ctx, project.ID, config.ID, "Authorization", "Bearer one"); err != nil || !slices.Equal(config.Headers, []string{"Authorization"}) {
+		t.Fatalf("SetHeader returned %+v, %v", config, err)
+	}
+	if config, err = client.SetHeader(ctx, project.ID, config.ID, "authorization", "Bearer two"); err != nil || len(config.Headers) != 1 {
+		t.Fatalf("expected setting the header again to replace its value, got %+v, %v", config, err)
+	}
+	headers, _ := server.db.ListHeaderReplacements(ctx, config.ID)
+	encrypter, _ := cfg.Encrypter()
+	if value, _ := encrypter.Decrypt(ctx, headers[0].HeaderValue); value != "Bearer two" {
+		t.Fatalf("expected the header value to be replaced, got %q", value)
+	}
+
+	connection, err := client.GetConnection(ctx, project.ID, config.ID)
+	stored, _ := server.db.GetProject(ctx, project.ID)
+	want := "https://" + config.ID.String() + ":" + project.ID.String() + ":" + stored.AccessKey + "@proxy.example.com"
+	if err != nil || connection.ConnectionString != want {
+		t.Fatalf("expected the connection string %q, got %+v, %v", want, connection, err)
+	}
+	if env := connection.Snippets["env"]; env != "export HTTPS_PROXY='"+want+"'" {
+		t.Fatalf("expected the HTTPS_PROXY snippet, got %q", env)
+	}
+
+	found, err := client.FindProject(ctx, "payments")
+	if err != nil || len(found.Configs) != 1 || found.Configs[0].Name != "stripe" {
+		t.Fatalf("FindProject returned %+v, %v", found, err)
+	}
+	if _, err := client.GetProject(ctx, otherProject.ID); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the project of another user to be rejected, got %v", err)
+	}
+
+	if config, err = client.UnsetHeader(ctx, project.ID, config.ID, "Authorization"); err != nil || len(config.Headers) != 0 {
+		t.Fatalf("UnsetHeader returned %+v, %v", config, err)
+	}
+	if err := client.DeleteConfig(ctx, project.ID, config.ID); err != nil {
+		t.Fatalf("DeleteConfig returned error: %v", err)
+	}
+	if err := client.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject returned error: %v", err)
+	}
+	if projects, err := client.ListProjects(ctx); err != nil || len(projects) != 0 {
+		t.Fatalf("expected the project to be deleted, got %+v, %v", projects, err)
+	}
+
+	tokens, _ := server.db.ListAPITokens(ctx, user.ID)
+	if len(tokens) != 1 || tokens[0].ID != token.ID || tokens[0].LastUsedAt == nil {
+		t.Fatalf("expected the last use of the token to be recorded, got %+v", tokens)
+	}
+}
+
+func TestAPIDoesNotAcceptSessions(t *testing.T) {
+	handler := newTestServer(t, testConfig(), io.Discard).RegisterRoutes()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, api.Prefix+"/projects", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "value"})
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON 401 without a token, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIExportImport(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, testConfig(), io.Discard)
+	handler := server.RegisterRoutes()
+
+	user, _ := server.db.CreateUser(ctx, models.User{Provider: "github", Subject: "1", Name: "user"})
+	secret, tokenHash, _ := auth.NewAuthToken()
+	server.db.CreateAPIToken(ctx, user.ID, "ci", tokenHash)
+	project, _ := server.db.CreateProject(ctx, "payments", "", "key", user.ID)
+	server.db.CreateConfig(ctx, project.ID, "stripe", 100, "hour")
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, api.Prefix+"/projects/"+project.ID.String()+"/export", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "name: stripe") {
+		t.Fatalf("expected the project to be exported, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	document := strings.Replace(rec.Body.String(), "name: payments", "name: billing", 1)
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, api.Prefix+"/projects/import", strings.NewReader(document))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	req.Header.Set("Content-Type", "application/yaml")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected the import to answer with JSON, got %d %s", rec.Code, rec.Body.String())
+	}
+	projects, _ := server.db.ListProjects(ctx, user.ID, database.ProjectListOptions{})
+	if len(projects) != 2 {
+		t.Fatalf("expected the import to create a project, got %+v", projects)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, api.Prefix+"/projects/"+project.ID.String()+"/export", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "value"})
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the export to require a token, got %d", rec.Code)
+	}
+}
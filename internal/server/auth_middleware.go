@@ -1,39 +1,39 @@
 package server
 
 import (
-	"log"
+	"configuration-management/internal/auth"
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/logging"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 )
 
+// apiTokenTouchInterval bounds how often the last use of an API token is
+// written, as for sessions.
+const apiTokenTouchInterval = time.Minute
+
 func (s *Server) UserAuth(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		sess, err := session.Get("session", c)
 		if err != nil {
-			log.Printf("failed to read user session: %v\n", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("failed to read user session: %w", err)
 		}
 
-		sessionIDString, ok := sess.Values["session_id"]
+		sessionID, ok := handlers.SessionIDFromCookie(sess)
 		if !ok {
-			log.Println("no session id, redirecting")
 			return c.Redirect(http.StatusTemporaryRedirect, "/login")
 		}
 
-		sessionID, parseErr := uuid.Parse(sessionIDString.(string))
-		if parseErr != nil {
-			log.Println("unable to parse session id")
-			return echo.NewHTTPError(http.StatusInternalServerError)
-
-		}
-
-		userSession, userSessionErr := s.db.GetUserSession(sessionID)
+		userSession, userSessionErr := s.db.GetUserSession(c.Request().Context(), sessionID)
 		if userSessionErr != nil {
-			log.Printf("failed to get user session: %v\n", userSessionErr)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("failed to get user session: %w", userSessionErr)
 		}
 
 		if userSession == nil {
@@ -41,13 +41,81 @@ func (s *Server) UserAuth(next echo.HandlerFunc) echo.HandlerFunc {
 
 		}
 
-		user, userErr := s.db.GetUser(userSession.UserID)
+		now := time.Now().UTC()
+		if !s.sessionPolicy.Active(*userSession, now) {
+			if err := s.db.DeleteUserSession(c.Request().Context(), userSession.ID); err != nil {
+				logging.FromContext(c.Request().Context()).Error("failed to delete expired user session", "error", err)
+			}
+			return c.Redirect(http.StatusTemporaryRedirect, "/login")
+		}
+
+		if s.sessionPolicy.NeedsTouch(*userSession, now) {
+			if err := s.db.TouchUserSession(c.Request().Context(), userSession.ID, now); err != nil {
+				logging.FromContext(c.Request().Context()).Error("failed to update user session", "error", err)
+			}
+			userSession.LastSeenAt = now
+		}
+
+		user, userErr := s.db.GetUser(c.Request().Context(), userSession.UserID)
 		if userErr != nil {
-			log.Printf("failed to get user: %v\n", userErr)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("failed to get user: %w", userErr)
+		}
+
+		c.Set("currentSession", userSession)
+		c.Set("user", user)
+		c.SetRequest(c.Request().WithContext(logging.With(c.Request().Context(), "user_id", user.ID)))
+		return next(c)
+	}
+}
+
+// MetricsTokenAuth lets through the requests whose Authorization header
+// carries the metrics token of the configuration.
+func (s *Server) MetricsTokenAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.hasMetricsToken(c) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid metrics token")
+		}
+		return next(c)
+	}
+}
+
+// hasMetricsToken is false when no metrics token is configured.
+func (s *Server) hasMetricsToken(c echo.Context) bool {
+	secret, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return ok && s.config.MetricsToken != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.config.MetricsToken)) == 1
+}
+
+// APITokenAuth authenticates the API requests with the token of the
+// Authorization header instead of the session cookie.
+func (s *Server) APITokenAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		secret, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || secret == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing API token")
+		}
+
+		token, err := s.db.GetAPIToken(c.Request().Context(), auth.HashAuthToken(secret))
+		if errors.Is(err, database.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API token")
+		}
+		if err != nil {
+			return handlers.InternalError("failed to get api token: %w", err)
+		}
+
+		now := time.Now().UTC()
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+			if err := s.db.TouchAPIToken(c.Request().Context(), token.ID, now); err != nil {
+				logging.FromContext(c.Request().Context()).Error("failed to update api token", "error", err)
+			}
+		}
+
+		user, err := s.db.GetUser(c.Request().Context(), token.UserID)
+		if err != nil {
+			return handlers.InternalError("failed to get user: %w", err)
 		}
 
 		c.Set("user", user)
+		c.SetRequest(c.Request().WithContext(logging.With(c.Request().Context(), "user_id", user.ID, "api_token_id", token.ID)))
 		return next(c)
 	}
 }
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		remoteAddr     string
+		forwardedFor   string
+		ip             string
+	}{
+		{"direct", nil, "203.0.113.7:5000", "", "203.0.113.7"},
+		{"forwarded without trusted proxies", nil, "10.1.2.3:5000", "203.0.113.7", "10.1.2.3"},
+		{"forwarded by a trusted proxy", []*net.IPNet{proxies}, "10.1.2.3:5000", "203.0.113.7", "203.0.113.7"},
+		{"forwarded by an untrusted client", []*net.IPNet{proxies}, "198.51.100.1:5000", "203.0.113.7", "198.51.100.1"},
+		{"forwarded garbage", []*net.IPNet{proxies}, "10.1.2.3:5000", "not an address", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if ip := ClientIP(tt.trustedProxies)(req); ip != tt.ip {
+				t.Fatalf("expected %s, got %s", tt.ip, ip)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
 	"configuration-management/internal/models"
-	"log"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -13,25 +15,24 @@ func (s *Server) ConfigBelongToProject(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		project, ok := c.Get("project").(*models.Project)
 		if !ok {
-			log.Println("Missing project")
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("missing project")
 		}
 
 		configID, idErr := uuid.Parse(c.Param("configId"))
 		if idErr != nil {
-			log.Fatalf("Invalid config id: %e", idErr)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid config id")
 		}
 
-		config, err := s.db.GetConfig(configID)
+		config, err := s.db.GetConfig(c.Request().Context(), configID)
 		if err != nil {
-			log.Printf("failed to get config: %v\n", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			if errors.Is(err, database.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			return handlers.InternalError("failed to get config: %w", err)
 		}
 
 		if config.ProjectID != project.ID {
-			log.Println("config does not belong to the project")
-			return echo.NewHTTPError(http.StatusBadRequest)
+			return echo.NewHTTPError(http.StatusBadRequest).SetInternal(errors.New("config does not belong to the project"))
 		}
 
 		c.Set("config", config)
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	cfg := testConfig()
+	cfg.BaseURL = "https://limiter.example.com"
+	cfg.CORSAllowedOrigins = []string{cfg.BaseURL}
+	handler := newTestServer(t, cfg, io.Discard).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var csrfCookie *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "_csrf" {
+			csrfCookie = cookie
+		}
+	}
+	if csrfCookie == nil {
+		t.Fatalf("expected the login page to set the CSRF cookie")
+	}
+	if !strings.Contains(rec.Body.String(), csrfCookie.Value) {
+		t.Fatalf("expected the page to send the CSRF token with htmx requests")
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		origin string
+		status int
+	}{
+		{"missing token", "", "", http.StatusBadRequest},
+		{"wrong token", "wrong", "", http.StatusForbidden},
+		{"foreign origin", csrfCookie.Value, "https://evil.example.com", http.StatusForbidden},
+		// The token is accepted, the request then needs a logged user.
+		{"valid token", csrfCookie.Value, "https://limiter.example.com", http.StatusTemporaryRedirect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader("name=project"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(csrfCookie)
+			if tt.token != "" {
+				req.Header.Set("X-CSRF-Token", tt.token)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLogoutRequiresCSRF(t *testing.T) {
+	handler := newTestServer(t, testConfig(), io.Discard).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected logging out with a link to be refused, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected logging out without a CSRF token to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"configuration-management/internal/logging"
+	"configuration-management/internal/models"
+	"configuration-management/web"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPErrorHandler logs the errors returned by handlers and middlewares and
+// renders them for the client: a toast for htmx requests, JSON for API
+// clients and an error page otherwise. The internal cause of an error is
+// logged but never sent.
+func (s *Server) HTTPErrorHandler(err error, c echo.Context) {
+	code := http.StatusInternalServerError
+	message := http.StatusText(code)
+	cause := err
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		code = httpErr.Code
+		message = http.StatusText(code)
+		if httpMessage, ok := httpErr.Message.(string); ok && httpMessage != "" {
+			message = httpMessage
+		}
+		cause = httpErr.Internal
+	}
+
+	logger := logging.FromContext(c.Request().Context())
+	switch {
+	case code >= http.StatusInternalServerError:
+		if cause == nil {
+			cause = err
+		}
+		logger.Error("request failed", "status", code, "error", cause)
+		trace.SpanFromContext(c.Request().Context()).RecordError(cause)
+	case cause != nil:
+		logger.Warn("request rejected", "status", code, "error", cause)
+	}
+
+	if c.Response().Committed {
+		return
+	}
+
+	var renderErr error
+	switch {
+	case c.Request().Method == http.MethodHead:
+		renderErr = c.NoContent(code)
+	case c.Request().Header.Get("HX-Request") == "true":
+		c.Response().Header().Set("HX-Retarget", "#errors")
+		c.Response().Header().Set("HX-Reswap", "beforeend")
+		renderErr = renderError(c, code, web.ErrorToast(message))
+	case isAPIRequest(c) || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON):
+		renderErr = c.JSON(code, map[string]string{"error": message})
+	default:
+		user, _ := c.Get("user").(*models.User)
+		renderErr = renderError(c, code, web.ErrorPage(user, code, message))
+	}
+	if renderErr != nil {
+		logger.Error("failed to render error", "error", renderErr)
+	}
+}
+
+func renderError(c echo.Context, code int, component templ.Component) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(code)
+	return component.Render(c.Request().Context(), c.Response().Writer)
+}
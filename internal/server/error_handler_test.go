@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"configuration-management/internal/config"
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/webhooks"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.BaseURL = "http://localhost:8080"
+	cfg.CORSAllowedOrigins = []string{cfg.BaseURL}
+	cfg.SecretKey = strings.Repeat("ab", 32)
+	return cfg
+}
+
+// newTestServer logs as JSON to w.
+func newTestServer(t *testing.T, cfg *config.Config, w io.Writer) *Server {
+	logger, err := logging.New(w, logging.Config{Format: logging.FormatJSON, Level: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypter, err := cfg.Encrypter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := memstore.New()
+	server, err := newServer(cfg, db, logger, nil, webhooks.NewDispatcher(db, encrypter))
+	if err != nil {
+		t.Fatalf("newServer returned error: %v", err)
+	}
+	return server
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	var logs bytes.Buffer
+	server := newTestServer(t, testConfig(), &logs)
+	failure := handlers.InternalError("failed to query: %w", errors.New("connection refused"))
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		contentType string
+		contains    string
+	}{
+		{"htmx", map[string]string{"HX-Request": "true"}, echo.MIMETextHTMLCharsetUTF8, "alert-error"},
+		{"json", map[string]string{"Accept": "application/json"}, echo.MIMEApplicationJSON, `"error":"Internal Server Error"`},
+		{"page", nil, echo.MIMETextHTMLCharsetUTF8, "500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/projects", nil)
+			req = req.WithContext(logging.WithLogger(req.Context(), server.logger))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			server.HTTPErrorHandler(failure, echo.New().NewContext(req, rec))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("expected status 500, got %d", rec.Code)
+			}
+			if !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), tt.contentType) {
+				t.Errorf("expected content type %s, got %s", tt.contentType, rec.Header().Get(echo.HeaderContentType))
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("expected the body to contain %q, got %s", tt.contains, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), "connection refused") {
+				t.Errorf("expected the internal error not to be sent to the client")
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Fatalf("expected the internal error to be logged, got %s", logs.String())
+	}
+}
+
+func TestHTTPErrorHandlerRetargetsHtmxRequests(t *testing.T) {
+	server := newTestServer(t, testConfig(), io.Discard)
+	req := httptest.NewRequest(http.MethodDelete, "/projects/1", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+
+	server.HTTPErrorHandler(echo.NewHTTPError(http.StatusNotFound, "Project not found"), echo.New().NewContext(req, rec))
+
+	if rec.Header().Get("HX-Retarget") != "#errors" || rec.Header().Get("HX-Reswap") != "beforeend" {
+		t.Fatalf("expected the error to be added to the toasts, got %v", rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "Project not found") {
+		t.Fatalf("expected the error message in the toast, got %s", rec.Body.String())
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	handler := newTestServer(t, testConfig(), &logs).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		t.Fatalf("expected a request id header")
+	}
+
+	var entry map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry["msg"] == "request" {
+			break
+		}
+	}
+	if entry["request_id"] != requestID || entry["route"] != "/login" || entry["status"] != float64(http.StatusOK) {
+		t.Fatalf("unexpected request log %v", entry)
+	}
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
 	"configuration-management/internal/models"
-	"log"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -13,25 +15,24 @@ func (s *Server) HeaderBelongsToConfig(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		config, ok := c.Get("config").(*models.Config)
 		if !ok {
-			log.Println("Missing config")
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("missing config")
 		}
 		headerID, idErr := uuid.Parse(c.Param("headerId"))
 		if idErr != nil {
-			log.Fatalf("Invalid header id: %e", idErr)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid header id")
 		}
 
-		header, err := s.db.GetHeaderReplacement(headerID)
+		header, err := s.db.GetHeaderReplacement(c.Request().Context(), headerID)
 		if err != nil {
-			log.Printf("failed to get header replacement: %v\n", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			if errors.Is(err, database.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			return handlers.InternalError("failed to get header replacement: %w", err)
 
 		}
 
 		if header.ConfigID != config.ID {
-			log.Println("header does not belong to the config")
-			return echo.NewHTTPError(http.StatusBadRequest)
+			return echo.NewHTTPError(http.StatusBadRequest).SetInternal(errors.New("header does not belong to the config"))
 		}
 
 		c.Set("header", header)
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"configuration-management/internal/health"
+	"configuration-management/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func getReport(t *testing.T, handler http.Handler, path string, token ...string) (int, health.Report) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, token := range token {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var report health.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("expected a JSON report, got %q", rec.Body.String())
+	}
+	return rec.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	cfg := testConfig()
+	cfg.MetricsToken = "scrape-token"
+	var logs strings.Builder
+	server := newTestServer(t, cfg, &logs)
+	handler := server.RegisterRoutes()
+
+	code, report := getReport(t, handler, "/readyz")
+	if code != http.StatusOK || !report.OK() {
+		t.Fatalf("expected the server to be ready, got %d %+v", code, report)
+	}
+	for _, name := range []string{"database", "encryption"} {
+		if report.Checks[name].Status != health.StatusOK {
+			t.Errorf("expected the %s check to pass, got %+v", name, report.Checks[name])
+		}
+	}
+
+	server.readiness = append(server.readiness, health.Check{Name: "migrations", Run: func(ctx context.Context) error {
+		return errors.New("the database is at migration 11, this binary expects 12")
+	}})
+	code, report = getReport(t, handler, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusUnavailable {
+		t.Fatalf("expected the server not to be ready, got %d %+v", code, report)
+	}
+	if report.Checks["migrations"].Status != health.StatusUnavailable || report.Checks["migrations"].Error != "" {
+		t.Errorf("expected the failed check to be reported without its error, got %+v", report.Checks["migrations"])
+	}
+	if !strings.Contains(logs.String(), "expects 12") {
+		t.Errorf("expected the error of the failed check to be logged, got %q", logs.String())
+	}
+
+	for _, token := range []string{"other-token", "scrape-token"} {
+		_, report = getReport(t, handler, "/readyz", token)
+		if shown := strings.Contains(report.Checks["migrations"].Error, "expects 12"); shown != (token == "scrape-token") {
+			t.Errorf("expected the error to be shown only with the metrics token, got %+v with %q", report.Checks["migrations"], token)
+		}
+	}
+
+	if code, report := getReport(t, handler, "/livez"); code != http.StatusOK || !report.OK() {
+		t.Fatalf("expected the server to be live while not ready, got %d %+v", code, report)
+	}
+}
+
+func TestReadinessDetectsAnotherSecretKey(t *testing.T) {
+	server := newTestServer(t, testConfig(), io.Discard)
+	otherKey, _ := utils.NewEncrypter([]byte(strings.Repeat("k", 32)))
+	value, _ := otherKey.Encrypt(context.Background(), "Bearer secret")
+	if _, err := server.db.CreateHeaderReplacement(context.Background(), uuid.New(), "Authorization", value); err != nil {
+		t.Fatal(err)
+	}
+
+	code, report := getReport(t, server.RegisterRoutes(), "/readyz")
+	if code != http.StatusServiceUnavailable || report.Checks["encryption"].Status != health.StatusUnavailable {
+		t.Fatalf("expected the encryption check to fail, got %d %+v", code, report)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"configuration-management/internal/logging"
+	"log/slog"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLogger hands every request a logger carrying its request id and
+// route, and logs the request once it is handled. It must run after the
+// RequestID middleware, and after the tracing middleware for the logs to
+// carry the trace id.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			requestLogger := logger.With(
+				"request_id", c.Response().Header().Get(echo.HeaderXRequestID),
+				"method", req.Method,
+				"route", c.Path(),
+			)
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				requestLogger = requestLogger.With("trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+			}
+			c.SetRequest(req.WithContext(logging.WithLogger(req.Context(), requestLogger)))
+
+			if err := next(c); err != nil {
+				// Handle the error here so that the logged status is the
+				// one sent to the client.
+				c.Error(err)
+			}
+
+			// The logger of the request context also carries the
+			// attributes added by later middlewares, such as the user id.
+			logging.FromContext(c.Request().Context()).Info("request",
+				"path", req.URL.Path,
+				"status", c.Response().Status,
+				"latency", time.Since(start),
+				"remote_ip", c.RealIP(),
+			)
+			return nil
+		}
+	}
+}
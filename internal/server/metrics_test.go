@@ -0,0 +1,70 @@
+package server
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	cfg := testConfig()
+	cfg.MetricsToken = "scrape-token"
+	server := newTestServer(t, cfg, io.Discard)
+	server.metrics = metrics.New(memstore.New(), nil)
+	handler := server.RegisterRoutes()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil))
+	server.metrics.Login("github", metrics.LoginFailed)
+
+	for name, authorization := range map[string]string{
+		"missing": "",
+		"wrong":   "Bearer other-token",
+		"scheme":  "scrape-token",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: expected status 401, got %d", name, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer scrape-token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`limiter_http_requests_total{method="GET",route="/login",status="200"} 1`,
+		`limiter_logins_total{provider="github",result="failure"} 1`,
+		"limiter_projects 0",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the metrics to contain %q", want)
+		}
+	}
+}
+
+func TestMetricsEndpointWithoutToken(t *testing.T) {
+	server := newTestServer(t, testConfig(), io.Discard)
+	server.metrics = metrics.New(memstore.New(), nil)
+
+	rec := httptest.NewRecorder()
+	server.RegisterRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 without a metrics token, got %d", rec.Code)
+	}
+}
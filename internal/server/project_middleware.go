@@ -1,8 +1,10 @@
 package server
 
 import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
 	"configuration-management/internal/models"
-	"log"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -13,24 +15,23 @@ func (s *Server) ProjectBelongsToLoggedUser(next echo.HandlerFunc) echo.HandlerF
 	return func(c echo.Context) error {
 		user, ok := c.Get("user").(*models.User)
 		if !ok {
-			log.Println("Missing user")
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			return handlers.InternalError("missing user")
 		}
 		projectID, idErr := uuid.Parse(c.Param("id"))
 		if idErr != nil {
-			log.Fatalf("Invalid project id: %e", idErr)
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
 		}
-		project, err := s.db.GetProject(projectID)
+		project, err := s.db.GetProject(c.Request().Context(), projectID)
 		if err != nil {
-			log.Printf("failed to get project: %v\n", err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
+			if errors.Is(err, database.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			return handlers.InternalError("failed to get project: %w", err)
 
 		}
 
 		if project.UserID != user.ID {
-			log.Println("project does not belong to the logged user")
-			return echo.NewHTTPError(http.StatusUnauthorized)
+			return echo.NewHTTPError(http.StatusUnauthorized).SetInternal(errors.New("project does not belong to the logged user"))
 		}
 
 		c.Set("project", project)
@@ -2,8 +2,12 @@ package server
 
 import (
 	"net/http"
-	"os"
+	"time"
 
+	"configuration-management/internal/api"
+	"configuration-management/internal/health"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/tracing"
 	"configuration-management/web"
 
 	"github.com/gorilla/sessions"
@@ -12,40 +16,126 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 )
 
+// readinessTimeout bounds each readiness check, so that probes fail rather
+// than hang while the database is unreachable.
+const readinessTimeout = 2 * time.Second
+
+// sessionStore keeps the sessions in signed cookies, which are only sent
+// over https when the application is served over https.
+func (s *Server) sessionStore() sessions.Store {
+	store := sessions.NewCookieStore([]byte(s.config.SessionSecret))
+	store.Options = &sessions.Options{
+		Path:     "/",
+		MaxAge:   int(s.config.Sessions.MaxAge.Seconds()),
+		Secure:   s.config.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	return store
+}
+
 func (s *Server) RegisterRoutes() http.Handler {
 	e := echo.New()
-	e.Use(session.Middleware(sessions.NewCookieStore([]byte(os.Getenv("SESSION_SECRET")))))
-	e.Use(middleware.Logger())
+	e.HTTPErrorHandler = s.HTTPErrorHandler
+	// the trusted proxies are checked by config.Validate
+	trustedProxies, _ := s.config.TrustedProxyNetworks()
+	e.IPExtractor = ClientIP(trustedProxies)
+	e.Use(middleware.RequestID())
+	if s.metrics != nil {
+		e.Use(s.metrics.Middleware)
+	}
+	e.Use(tracing.Middleware)
+	e.Use(RequestLogger(s.logger))
 	e.Use(middleware.Recover())
+	e.Use(session.Middleware(s.sessionStore()))
 
+	allowedOrigins := s.config.CORSAllowedOrigins
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"https://*", "http://*"},
+		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	e.Use(CheckOrigin(allowedOrigins))
+	e.Use(CSRF(s.config.SecureCookies()))
+	e.Use(CSRFToken)
+	e.Use(s.UserNames)
 
 	fileServer := http.FileServer(http.FS(web.Files))
 	e.GET("/assets/*", echo.WrapHandler(fileServer))
 	e.GET("/health", s.healthHandler)
+	e.GET("/livez", s.livenessHandler)
+	e.GET("/readyz", s.readinessHandler)
+	if s.metrics != nil && s.config.MetricsToken != "" {
+		e.GET("/metrics", echo.WrapHandler(s.metrics.Handler()), s.MetricsTokenAuth)
+	}
 
 	e.GET("/login", s.loginHandler.Login)
-	e.GET("/logout", s.loginHandler.Logout)
-	e.GET("/auth/github", s.loginHandler.LoginWithGitlab)
-	e.GET("/auth/github/callback", s.loginHandler.GithubCallback)
+	e.POST("/logout", s.loginHandler.Logout)
+	e.GET("/auth/:provider", s.loginHandler.LoginWithProvider)
+	e.GET("/auth/:provider/callback", s.loginHandler.Callback)
+
+	if s.localAuth.Enabled {
+		e.POST("/login/local", s.localHandler.Login)
+		e.GET("/login/totp", s.localHandler.TOTPLogin)
+		e.POST("/login/totp", s.localHandler.VerifyTOTPLogin)
+		e.GET("/signup", s.localHandler.Signup)
+		e.POST("/signup", s.localHandler.CreateAccount)
+		e.GET("/password/forgot", s.localHandler.ForgotPassword)
+		e.POST("/password/forgot", s.localHandler.RequestPasswordReset)
+		e.GET("/password/reset", s.localHandler.ResetPassword)
+		e.POST("/password/reset", s.localHandler.ConfirmPasswordReset)
+
+		accountGroup := e.Group("/account", s.UserAuth, s.LocalAccount)
+		accountGroup.GET("", s.accountHandler.Account)
+		accountGroup.POST("/password", s.accountHandler.ChangePassword)
+		accountGroup.POST("/totp", s.accountHandler.StartTOTP)
+		accountGroup.POST("/totp/confirm", s.accountHandler.ConfirmTOTP)
+		accountGroup.POST("/totp/disable", s.accountHandler.DisableTOTP)
+		accountGroup.POST("/invites", s.accountHandler.CreateInvite)
+	}
+
+	sessionsGroup := e.Group("/sessions", s.UserAuth)
+	sessionsGroup.GET("", s.sessionsHandler.ListSessions)
+	sessionsGroup.POST("/revoke-all", s.sessionsHandler.RevokeAllSessions)
+	sessionsGroup.DELETE("/:sessionId", s.sessionsHandler.RevokeSession, s.SessionBelongsToLoggedUser)
+
+	tokensGroup := e.Group("/tokens", s.UserAuth)
+	tokensGroup.GET("", s.tokensHandler.ListAPITokens)
+	tokensGroup.POST("", s.tokensHandler.CreateAPIToken)
+	tokensGroup.DELETE("/:tokenId", s.tokensHandler.DeleteAPIToken)
 
 	projectsGroup := e.Group("/projects", s.UserAuth)
 	projectsGroup.GET("", s.projectsHandler.ListProjects, s.UserAuth)
 	projectsGroup.POST("", s.projectsHandler.CreateProject)
+	projectsGroup.POST("/import", s.manifestHandler.ImportProject)
 
 	projectActionsGroup := projectsGroup.Group("/:id", s.ProjectBelongsToLoggedUser)
 	projectActionsGroup.DELETE("", s.projectsHandler.DeleteProject)
+	projectActionsGroup.POST("/access-key/rotate", s.projectsHandler.RotateAccessKey)
+	projectActionsGroup.POST("/disable", s.killSwitch.DisableProject)
+	projectActionsGroup.POST("/enable", s.killSwitch.EnableProject)
+	projectActionsGroup.GET("/export", s.manifestHandler.ExportProject)
+	projectActionsGroup.POST("/import", s.manifestHandler.ImportIntoProject)
+	projectActionsGroup.GET("/configs", s.configHandler.ListConfigs)
 	projectActionsGroup.POST("/configs", s.configHandler.CreateConfig)
+	projectActionsGroup.POST("/configs/disable", s.killSwitch.DisableProjectConfigs)
+	projectActionsGroup.POST("/webhooks", s.webhooksHandler.CreateWebhook)
+
+	webhooksGroup := projectActionsGroup.Group("/webhooks/:webhookId", s.WebhookBelongsToProject)
+	webhooksGroup.DELETE("", s.webhooksHandler.DeleteWebhook)
+	webhooksGroup.GET("/secret", s.webhooksHandler.GetWebhookSecret)
+	webhooksGroup.GET("/deliveries", s.webhooksHandler.ListDeliveries)
+	webhooksGroup.POST("/test", s.webhooksHandler.SendTestEvent)
 
 	configsGroup := projectActionsGroup.Group("/configs/:configId", s.ConfigBelongToProject)
 	configsGroup.DELETE("", s.configHandler.DeleteConfig)
 	configsGroup.GET("/connection", s.configHandler.GetConfigConnection)
+	configsGroup.GET("/connection/dotenv", s.configHandler.DownloadConfigConnection)
+	configsGroup.POST("/availability", s.availability.UpdateConfigAvailability)
+	configsGroup.POST("/disable", s.killSwitch.DisableConfig)
+	configsGroup.POST("/enable", s.killSwitch.EnableConfig)
 
 	configsGroup.POST("/headers", s.headersHandler.CreateHeaderReplacement)
 
@@ -53,9 +143,68 @@ func (s *Server) RegisterRoutes() http.Handler {
 	headersGroup.DELETE("", s.headersHandler.DeleteHeaderReplacement)
 	headersGroup.GET("/value", s.headersHandler.GetHeaderReplacementValue)
 
+	configsGroup.POST("/clients", s.clientsHandler.CreateConfigClient)
+	configsGroup.DELETE("/clients/:clientId", s.clientsHandler.DeleteConfigClient)
+
+	configsGroup.POST("/alerts", s.alertsHandler.CreateAlert)
+
+	alertsGroup := configsGroup.Group("/alerts/:alertId", s.AlertBelongsToConfig)
+	alertsGroup.DELETE("", s.alertsHandler.DeleteAlert)
+
+	apiGroup := e.Group(api.Prefix, s.APITokenAuth)
+	apiGroup.GET("/projects", s.apiHandler.ListProjects)
+	apiGroup.POST("/projects", s.apiHandler.CreateProject)
+	apiGroup.POST("/projects/import", s.manifestHandler.ImportProject)
+
+	apiProjectGroup := apiGroup.Group("/projects/:id", s.ProjectBelongsToLoggedUser)
+	apiProjectGroup.GET("", s.apiHandler.GetProject)
+	apiProjectGroup.DELETE("", s.apiHandler.DeleteProject)
+	apiProjectGroup.POST("/configs", s.apiHandler.CreateConfig)
+	apiProjectGroup.GET("/export", s.manifestHandler.ExportProject)
+	apiProjectGroup.POST("/import", s.manifestHandler.ImportIntoProject)
+
+	apiConfigGroup := apiProjectGroup.Group("/configs/:configId", s.ConfigBelongToProject)
+	apiConfigGroup.PATCH("", s.apiHandler.UpdateConfig)
+	apiConfigGroup.DELETE("", s.apiHandler.DeleteConfig)
+	apiConfigGroup.GET("/connection", s.apiHandler.GetConnection)
+	apiConfigGroup.PUT("/headers/:headerName", s.apiHandler.SetHeader)
+	apiConfigGroup.DELETE("/headers/:headerName", s.apiHandler.UnsetHeader)
+
 	return e
 }
 
 func (s *Server) healthHandler(c echo.Context) error {
-	return c.JSON(http.StatusOK, s.db.Health())
+	stats := s.db.Health()
+	if stats["status"] != "up" {
+		return c.JSON(http.StatusServiceUnavailable, stats)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// livenessHandler only reports that the process serves requests, an
+// unavailable dependency must not get it restarted.
+func (s *Server) livenessHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// readinessHandler logs why the checks failed, the errors are only answered
+// to requests carrying the metrics token.
+func (s *Server) readinessHandler(c echo.Context) error {
+	report := health.Run(c.Request().Context(), readinessTimeout, s.readiness)
+	showErrors := s.hasMetricsToken(c)
+	for name, result := range report.Checks {
+		if result.Error == "" {
+			continue
+		}
+		logging.FromContext(c.Request().Context()).Warn("readiness check failed", "check", name, "error", result.Error)
+		if !showErrors {
+			result.Error = ""
+			report.Checks[name] = result
+		}
+	}
+
+	if !report.OK() {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
 }
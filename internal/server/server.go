@@ -2,47 +2,108 @@ package server
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"configuration-management/internal/auth"
+	"configuration-management/internal/config"
 	"configuration-management/internal/database"
 	"configuration-management/internal/handlers"
+	"configuration-management/internal/health"
+	"configuration-management/internal/mail"
+	"configuration-management/internal/metrics"
+	"configuration-management/internal/webhooks"
 )
 
 type Server struct {
-	port int
+	port    int
+	config  *config.Config
+	logger  *slog.Logger
+	metrics *metrics.Metrics
+	// readiness lists the checks of /readyz.
+	readiness []health.Check
 
-	db              *database.DatabaseHandler
+	db              database.Store
+	sessionPolicy   auth.SessionPolicy
 	projectsHandler *handlers.ProjectHandler
 	configHandler   *handlers.ConfigHandler
 	headersHandler  *handlers.HeaderReplacementsHandler
 	loginHandler    *handlers.LoginHandler
+	alertsHandler   *handlers.AlertsHandler
+	clientsHandler  *handlers.ConfigClientsHandler
+	availability    *handlers.ConfigAvailabilityHandler
+	killSwitch      *handlers.KillSwitchHandler
+	webhooksHandler *handlers.WebhooksHandler
+	sessionsHandler *handlers.SessionsHandler
+	localAuth       auth.LocalAuth
+	localHandler    *handlers.LocalAuthHandler
+	accountHandler  *handlers.AccountHandler
+	manifestHandler *handlers.ManifestHandler
+	tokensHandler   *handlers.APITokensHandler
+	apiHandler      *handlers.APIHandler
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	db := database.New()
-	NewServer := &Server{
-		port:            port,
-		projectsHandler: handlers.NewProjectHandler(db),
-		headersHandler:  handlers.NewHeaderReplacementsHandler(db),
-		loginHandler:    handlers.NewLoginHandler(db),
-		configHandler:   handlers.NewConfigHandler(db),
-		db:              db,
+// NewServer checks the database and the secret key on /readyz, followed by
+// the given checks. The webhook events are published through dispatcher,
+// which the caller waits for on shutdown.
+func NewServer(cfg *config.Config, db database.Store, logger *slog.Logger, metrics *metrics.Metrics,
+	dispatcher *webhooks.Dispatcher, checks ...health.Check) (*http.Server, error) {
+	NewServer, err := newServer(cfg, db, logger, metrics, dispatcher)
+	if err != nil {
+		return nil, err
 	}
+	NewServer.readiness = append(NewServer.readiness, checks...)
 
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf("localhost:%d", NewServer.port),
 		Handler:      NewServer.RegisterRoutes(),
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return server
+	return server, nil
+}
+
+// newServer records nothing when metrics is nil.
+func newServer(cfg *config.Config, db database.Store, logger *slog.Logger, metrics *metrics.Metrics,
+	dispatcher *webhooks.Dispatcher) (*Server, error) {
+	providers, err := cfg.Providers()
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity provider configuration: %v", err)
+	}
+	encrypter, err := cfg.Encrypter()
+	if err != nil {
+		return nil, err
+	}
+
+	mailer := mail.NewMailer(cfg.SMTP)
+	return &Server{
+		port:            cfg.Port,
+		config:          cfg,
+		logger:          logger,
+		metrics:         metrics,
+		readiness:       []health.Check{health.DatabaseCheck(db), health.EncryptionCheck(encrypter, db)},
+		projectsHandler: handlers.NewProjectHandler(db, dispatcher),
+		headersHandler:  handlers.NewHeaderReplacementsHandler(db, encrypter, dispatcher, metrics),
+		loginHandler:    handlers.NewLoginHandler(providers, cfg.Local, db, db, cfg.Sessions, metrics, cfg.SecureCookies()),
+		configHandler:   handlers.NewConfigHandler(db, db, encrypter, dispatcher, cfg.ProxyHost, metrics),
+		alertsHandler:   handlers.NewAlertsHandler(db),
+		clientsHandler:  handlers.NewConfigClientsHandler(db, cfg.ProxyHost),
+		availability:    handlers.NewConfigAvailabilityHandler(db, dispatcher),
+		killSwitch:      handlers.NewKillSwitchHandler(db, db, db, dispatcher),
+		webhooksHandler: handlers.NewWebhooksHandler(db, encrypter, dispatcher, metrics),
+		sessionsHandler: handlers.NewSessionsHandler(db, cfg.Sessions),
+		localAuth:       cfg.Local,
+		localHandler:    handlers.NewLocalAuthHandler(cfg.Local, cfg.BaseURL, db, encrypter, cfg.Sessions, mailer, metrics),
+		accountHandler:  handlers.NewAccountHandler(cfg.Local, cfg.BaseURL, db, encrypter, mailer),
+		manifestHandler: handlers.NewManifestHandler(db, encrypter, dispatcher),
+		tokensHandler:   handlers.NewAPITokensHandler(db, cfg.BaseURL),
+		apiHandler:      handlers.NewAPIHandler(db, encrypter, dispatcher, cfg.ProxyHost, metrics),
+		db:              db,
+		sessionPolicy:   cfg.Sessions,
+	}, nil
 }
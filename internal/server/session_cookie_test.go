@@ -0,0 +1,37 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"configuration-management/internal/auth"
+
+	"github.com/gorilla/sessions"
+)
+
+func TestCookieFlags(t *testing.T) {
+	cfg := testConfig()
+	cfg.BaseURL = "https://limiter.example.com"
+	cfg.CORSAllowedOrigins = []string{cfg.BaseURL}
+	cfg.GitHub = auth.GitHubConfig{ClientID: "id", ClientSecret: "secret"}
+	server := newTestServer(t, cfg, io.Discard)
+
+	options := server.sessionStore().(*sessions.CookieStore).Options
+	if !options.Secure || !options.HttpOnly || options.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected session cookie options %+v", options)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github", nil)
+	rec := httptest.NewRecorder()
+	server.RegisterRoutes().ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if !cookie.Secure || !cookie.HttpOnly || cookie.SameSite == http.SameSiteNoneMode || cookie.SameSite == http.SameSiteDefaultMode {
+			t.Errorf("expected the %s cookie to be secure, got %+v", cookie.Name, cookie)
+		}
+	}
+	if len(rec.Result().Cookies()) < 2 {
+		t.Fatalf("expected the login flow and CSRF cookies, got %+v", rec.Result().Cookies())
+	}
+}
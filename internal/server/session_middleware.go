@@ -0,0 +1,38 @@
+package server
+
+import (
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/models"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) SessionBelongsToLoggedUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := c.Get("user").(*models.User)
+		if !ok {
+			return handlers.InternalError("missing user")
+		}
+
+		sessionID, idErr := uuid.Parse(c.Param("sessionId"))
+		if idErr != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid session id")
+		}
+
+		userSession, err := s.db.GetUserSession(c.Request().Context(), sessionID)
+		if err != nil {
+			return handlers.InternalError("failed to get user session: %w", err)
+		}
+
+		// Other users' sessions are reported as missing so that their
+		// identifiers cannot be probed.
+		if userSession == nil || userSession.UserID != user.ID {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+
+		c.Set("userSession", userSession)
+		return next(c)
+	}
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"configuration-management/web"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// UserNames lets the templates show the name of the users recorded by id,
+// such as who disabled a config.
+func (s *Server) UserNames(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		lookup := func(ctx context.Context, userID uuid.UUID) string {
+			user, err := s.db.GetUser(ctx, userID)
+			if err != nil {
+				return ""
+			}
+			return user.DisplayName()
+		}
+		c.SetRequest(c.Request().WithContext(web.WithUserNames(c.Request().Context(), lookup)))
+		return next(c)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"configuration-management/internal/database"
+	"configuration-management/internal/handlers"
+	"configuration-management/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) WebhookBelongsToProject(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		project, ok := c.Get("project").(*models.Project)
+		if !ok {
+			return handlers.InternalError("missing project")
+		}
+
+		webhookID, idErr := uuid.Parse(c.Param("webhookId"))
+		if idErr != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+		}
+
+		webhook, err := s.db.GetWebhook(c.Request().Context(), webhookID)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			return handlers.InternalError("failed to get webhook: %w", err)
+		}
+
+		if webhook.ProjectID != project.ID {
+			return echo.NewHTTPError(http.StatusBadRequest).SetInternal(errors.New("webhook does not belong to the project"))
+		}
+
+		c.Set("webhook", webhook)
+		return next(c)
+	}
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	e := echo.New()
+	e.Use(Middleware)
+	e.GET("/projects/:id", func(c echo.Context) error {
+		_, span := Start(c.Request().Context(), "store.GetProject")
+		span.End()
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/projects/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a store and a request span, got %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "GET /projects/:id" {
+		t.Errorf("expected the span to be named after the route, got %q", server.Name())
+	}
+	if server.SpanContext().TraceID().String() != traceID {
+		t.Errorf("expected the trace of the caller to be continued, got %s", server.SpanContext().TraceID())
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("expected the store span to be a child of the request span")
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("expected the failed request to be marked as an error, got %v", server.Status())
+	}
+	var status int64
+	for _, attribute := range server.Attributes() {
+		if attribute.Key == semconv.HTTPResponseStatusCodeKey {
+			status = attribute.Value.AsInt64()
+		}
+	}
+	if status != http.StatusInternalServerError {
+		t.Errorf("expected the status code attribute to be 500, got %d", status)
+	}
+}
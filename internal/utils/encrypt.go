@@ -1,11 +1,13 @@
 package utils
 
 import (
+	"configuration-management/internal/tracing"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/hex"
-	"os"
+	"fmt"
 )
 
 func GenerateToken(length int) string {
@@ -16,22 +18,32 @@ func GenerateToken(length int) string {
 	return hex.EncodeToString(b)
 }
 
-func DecryptData(data string) (string, error) {
-	secretKeyHex := os.Getenv("SECRET_KEY")
-	secretKey, decodeErr := hex.DecodeString(secretKeyHex)
-	if decodeErr != nil {
-		return "", decodeErr
-	}
-	return Decrypt(secretKey, data)
+// Encrypter encrypts the secrets stored in the database with the
+// SECRET_KEY shared with the proxy.
+type Encrypter struct {
+	key []byte
 }
 
-func EncryptData(data string) (string, error) {
-	secretKeyHex := os.Getenv("SECRET_KEY")
-	secretKey, decodeErr := hex.DecodeString(secretKeyHex)
-	if decodeErr != nil {
-		return "", decodeErr
+// NewEncrypter returns an encrypter for an AES-256 key.
+func NewEncrypter(key []byte) (*Encrypter, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the secret key must be 32 bytes long, got %d", len(key))
 	}
-	return Encrypt(secretKey, data)
+	return &Encrypter{key}, nil
+}
+
+func (e *Encrypter) Encrypt(ctx context.Context, data string) (string, error) {
+	_, span := tracing.Start(ctx, "encrypter.Encrypt")
+	encrypted, err := Encrypt(e.key, data)
+	tracing.End(span, err)
+	return encrypted, err
+}
+
+func (e *Encrypter) Decrypt(ctx context.Context, data string) (string, error) {
+	_, span := tracing.Start(ctx, "encrypter.Decrypt")
+	decrypted, err := Decrypt(e.key, data)
+	tracing.End(span, err)
+	return decrypted, err
 }
 
 func Encrypt(key []byte, data string) (string, error) {
@@ -69,6 +81,9 @@ func Decrypt(key []byte, hexData string) (string, error) {
 		return "", err
 	}
 
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted data is too short")
+	}
 	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
 	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRefuseInternalAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		refused bool
+	}{
+		{"127.0.0.1:443", true},
+		{"[::1]:443", true},
+		{"10.0.0.8:443", true},
+		{"172.16.0.1:443", true},
+		{"192.168.1.1:443", true},
+		{"169.254.169.254:80", true},
+		{"0.0.0.0:443", true},
+		{"0.1.2.3:443", true},
+		{"100.64.0.1:443", true},
+		{"100.127.255.254:443", true},
+		{"[::ffff:127.0.0.1]:443", true},
+		{"[::ffff:10.0.0.8]:443", true},
+		{"[::ffff:169.254.169.254]:80", true},
+		{"[::ffff:100.64.0.1]:443", true},
+		{"100.128.0.1:443", false},
+		{"[fd00::1]:443", true},
+		{"93.184.215.14:443", false},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", false},
+	}
+	for _, tt := range tests {
+		err := refuseInternalAddress("tcp", tt.address, nil)
+		if refused := errors.Is(err, ErrForbiddenAddress); refused != tt.refused {
+			t.Errorf("refuseInternalAddress(%q) = %v, expected refused %t", tt.address, err, tt.refused)
+		}
+	}
+}
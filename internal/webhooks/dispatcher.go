@@ -0,0 +1,216 @@
+package webhooks
+
+import (
+	"bytes"
+	"configuration-management/internal/database"
+	"configuration-management/internal/logging"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature-256"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	ProjectID  uuid.UUID `json:"project_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Dispatcher delivers project events to the webhooks subscribed to them.
+// Deliveries run in the background and are retried with exponential backoff,
+// every attempt is recorded in the delivery log.
+type Dispatcher struct {
+	webhooks    database.WebhookStore
+	encrypter   *utils.Encrypter
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	inFlight    sync.WaitGroup
+}
+
+func NewDispatcher(webhooks database.WebhookStore, encrypter *utils.Encrypter) *Dispatcher {
+	return &Dispatcher{
+		webhooks:    webhooks,
+		encrypter:   encrypter,
+		client:      NewClient(10 * time.Second),
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+	}
+}
+
+// Publish looks up the project webhooks subscribed to the event before
+// returning. Deliveries outlive the request, they are not cancelled together
+// with ctx.
+func (d *Dispatcher) Publish(ctx context.Context, projectID uuid.UUID, eventType string, data any) {
+	webhooks, err := d.webhooks.ListWebhooks(ctx, projectID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list webhooks", "project_id", projectID, "error", err)
+		return
+	}
+
+	d.start(ctx, webhooks, newEvent(projectID, eventType, data), true)
+}
+
+// PublishProjectDeleted looks up the project webhooks subscribed to the
+// project.deleted event and returns a function delivering it, to be called once
+// the project is deleted. The webhooks and their delivery log are deleted
+// together with the project, so these attempts are only logged.
+func (d *Dispatcher) PublishProjectDeleted(ctx context.Context, project models.Project) func() {
+	webhooks, err := d.webhooks.ListWebhooks(ctx, project.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list webhooks", "project_id", project.ID, "error", err)
+		return func() {}
+	}
+
+	event := newEvent(project.ID, models.EventProjectDeleted, NewProjectPayload(project))
+	return func() {
+		d.start(ctx, webhooks, event, false)
+	}
+}
+
+func (d *Dispatcher) start(ctx context.Context, webhooks []models.Webhook, event Event, record bool) {
+	deliveryCtx := context.WithoutCancel(ctx)
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event.Type) {
+			d.inFlight.Add(1)
+			go func() {
+				defer d.inFlight.Done()
+				d.deliver(deliveryCtx, webhook, event, record)
+			}()
+		}
+	}
+}
+
+// Wait blocks until the deliveries in progress are done, including their
+// retries, so that a command can publish events right before exiting.
+func (d *Dispatcher) Wait() {
+	d.inFlight.Wait()
+}
+
+// WaitContext is Wait giving up when ctx is done, the deliveries still in
+// progress are then dropped.
+func (d *Dispatcher) WaitContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendTest makes a single synchronous delivery attempt of a test event.
+func (d *Dispatcher) SendTest(ctx context.Context, webhook models.Webhook) (*models.WebhookDelivery, error) {
+	event := newEvent(webhook.ProjectID, models.EventWebhookTest, map[string]string{
+		"message": "This is a test event",
+	})
+	statusCode, sendErr := d.send(ctx, webhook, event)
+	return d.record(ctx, webhook, event, 1, statusCode, sendErr)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, webhook models.Webhook, event Event, record bool) {
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, sendErr := d.send(ctx, webhook, event)
+		if record {
+			if _, err := d.record(ctx, webhook, event, attempt, statusCode, sendErr); err != nil {
+				logging.FromContext(ctx).Error("failed to record webhook delivery", "webhook_id", webhook.ID, "error", err)
+				return
+			}
+		} else if sendErr != nil {
+			logging.FromContext(ctx).Warn("failed to deliver webhook", "webhook_id", webhook.ID, "attempt", attempt, "error", sendErr)
+		}
+		if sendErr == nil || attempt == d.maxAttempts {
+			return
+		}
+
+		time.Sleep(d.baseDelay << (attempt - 1))
+	}
+}
+
+func (d *Dispatcher) record(ctx context.Context, webhook models.Webhook, event Event, attempt int, statusCode int, sendErr error) (*models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		WebhookID:  webhook.ID,
+		EventID:    event.ID,
+		Event:      event.Type,
+		Attempt:    attempt,
+		StatusCode: statusCode,
+	}
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+
+	return d.webhooks.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook models.Webhook, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	secret, err := d.encrypter.Decrypt(ctx, webhook.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decrypt webhook secret: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID.String())
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the value of the signature header for the payload, receivers
+// verify it by computing the HMAC-SHA256 of the raw request body.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newEvent(projectID uuid.UUID, eventType string, data any) Event {
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		ProjectID:  projectID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
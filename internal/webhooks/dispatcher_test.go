@@ -0,0 +1,177 @@
+package webhooks
+
+import (
+	"configuration-management/internal/database/memstore"
+	"configuration-management/internal/models"
+	"configuration-management/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testEncrypter, _ = utils.NewEncrypter([]byte("0123456789abcdef0123456789abcdef"))
+
+// receiver records the requests made to a webhook, failing the first ones.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+	times    []time.Time
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	r.times = append(r.times, time.Now())
+	if len(r.requests) <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func newTestDispatcher(t *testing.T, handler http.Handler) (*Dispatcher, *memstore.Store, models.Webhook) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	store := memstore.New()
+	project, _ := store.CreateProject(ctx, "payments", "", "key", uuid.New())
+	secret, _ := testEncrypter.Encrypt(ctx, "secret")
+	webhook, err := store.CreateWebhook(ctx, project.ID, server.URL, secret, []string{models.EventConfigCreated, models.EventProjectDeleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewDispatcher(store, testEncrypter)
+	// the test server listens on a loopback address
+	dispatcher.client = server.Client()
+	dispatcher.baseDelay = 10 * time.Millisecond
+	return dispatcher, store, *webhook
+}
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"type":"config.created"}`)
+	if signature := Sign("secret", payload); signature != "sha256=91bad4ac62114aa60284dab0d521f731d8968ba140b3346ff00732608d353569" {
+		t.Fatalf("unexpected signature %s", signature)
+	}
+	if Sign("secret", payload) == Sign("other", payload) {
+		t.Fatal("expected the signature to depend on the secret")
+	}
+}
+
+func TestPublishSignsAndRetries(t *testing.T) {
+	ctx := context.Background()
+	receiver := &receiver{failures: 2}
+	dispatcher, store, webhook := newTestDispatcher(t, receiver)
+
+	dispatcher.Publish(ctx, webhook.ProjectID, models.EventConfigCreated, map[string]string{"name": "stripe"})
+	dispatcher.Publish(ctx, webhook.ProjectID, models.EventHeaderCreated, nil)
+	dispatcher.Wait()
+
+	if len(receiver.requests) != 3 {
+		t.Fatalf("expected 2 failed attempts and a successful one, got %d requests", len(receiver.requests))
+	}
+	for i, req := range receiver.requests {
+		if req.Header.Get(SignatureHeader) != Sign("secret", receiver.bodies[i]) {
+			t.Errorf("attempt %d: signature %q does not match the body", i+1, req.Header.Get(SignatureHeader))
+		}
+		if req.Header.Get(EventHeader) != models.EventConfigCreated {
+			t.Errorf("attempt %d: unexpected event header %q", i+1, req.Header.Get(EventHeader))
+		}
+	}
+
+	var event Event
+	if err := json.Unmarshal(receiver.bodies[2], &event); err != nil || event.Type != models.EventConfigCreated || event.ProjectID != webhook.ProjectID {
+		t.Fatalf("unexpected event %+v: %v", event, err)
+	}
+
+	// the delay doubles after every failed attempt
+	if first, second := receiver.times[1].Sub(receiver.times[0]), receiver.times[2].Sub(receiver.times[1]); first < 10*time.Millisecond || second < 20*time.Millisecond {
+		t.Errorf("expected backoff delays of at least 10ms and 20ms, got %s and %s", first, second)
+	}
+
+	deliveries, _ := store.ListWebhookDeliveries(ctx, webhook.ID, 10)
+	if len(deliveries) != 3 || deliveries[0].Attempt != 3 || !deliveries[0].Successful() ||
+		deliveries[2].Attempt != 1 || deliveries[2].Successful() || deliveries[2].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestPublishGivesUp(t *testing.T) {
+	ctx := context.Background()
+	receiver := &receiver{failures: 10}
+	dispatcher, store, webhook := newTestDispatcher(t, receiver)
+	dispatcher.maxAttempts = 3
+
+	dispatcher.Publish(ctx, webhook.ProjectID, models.EventConfigCreated, nil)
+	dispatcher.Wait()
+
+	if deliveries, _ := store.ListWebhookDeliveries(ctx, webhook.ID, 10); len(receiver.requests) != 3 || len(deliveries) != 3 {
+		t.Fatalf("expected 3 attempts, got %d requests and %d deliveries", len(receiver.requests), len(deliveries))
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	dispatcher, _, webhook := newTestDispatcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	dispatcher.Publish(ctx, webhook.ProjectID, models.EventConfigCreated, nil)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := dispatcher.WaitContext(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+
+	close(release)
+	if err := dispatcher.WaitContext(ctx); err != nil {
+		t.Fatalf("expected the delivery to finish, got %v", err)
+	}
+}
+
+func TestPublishProjectDeleted(t *testing.T) {
+	ctx := context.Background()
+	receiver := &receiver{}
+	dispatcher, store, webhook := newTestDispatcher(t, receiver)
+	project, _ := store.GetProject(ctx, webhook.ProjectID)
+
+	notify := dispatcher.PublishProjectDeleted(ctx, *project)
+	if err := store.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatal(err)
+	}
+	notify()
+	dispatcher.Wait()
+
+	if len(receiver.requests) != 1 || receiver.requests[0].Header.Get(EventHeader) != models.EventProjectDeleted {
+		t.Fatalf("expected the project.deleted event to be delivered once, got %d requests", len(receiver.requests))
+	}
+}
+
+func TestDispatcherRefusesLoopback(t *testing.T) {
+	receiver := &receiver{}
+	dispatcher, _, webhook := newTestDispatcher(t, receiver)
+	dispatcher.client = NewClient(time.Second)
+
+	delivery, err := dispatcher.SendTest(context.Background(), webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Successful() || len(receiver.requests) != 0 {
+		t.Fatalf("expected the delivery to a loopback address to be refused, got %+v", delivery)
+	}
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"configuration-management/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ProjectPayload struct {
+	ID          uuid.UUID           `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Disabled    *DisablementPayload `json:"disabled,omitempty"`
+}
+
+type ConfigPayload struct {
+	ID                    uuid.UUID           `json:"id"`
+	ProjectID             uuid.UUID           `json:"project_id"`
+	Name                  string              `json:"name"`
+	LimitNumberOfRequests int                 `json:"limit_requests_count"`
+	LimitPer              string              `json:"limit_duration"`
+	Disabled              *DisablementPayload `json:"disabled,omitempty"`
+}
+
+// DisablementPayload names who disabled it by user id, omitted when the
+// application did.
+type DisablementPayload struct {
+	At     time.Time  `json:"at"`
+	Reason string     `json:"reason"`
+	By     *uuid.UUID `json:"by,omitempty"`
+}
+
+// HeaderPayload intentionally omits the header value.
+type HeaderPayload struct {
+	ID         uuid.UUID `json:"id"`
+	ConfigID   uuid.UUID `json:"config_id"`
+	HeaderName string    `json:"header_name"`
+}
+
+func NewProjectPayload(project models.Project) ProjectPayload {
+	return ProjectPayload{project.ID, project.Name, project.Description, newDisablementPayload(project.Disabled)}
+}
+
+func NewConfigPayload(config models.Config) ConfigPayload {
+	return ConfigPayload{config.ID, config.ProjectID, config.Name, config.LimitNumberOfRequests, config.LimitPer,
+		newDisablementPayload(config.Disabled)}
+}
+
+func newDisablementPayload(disabled *models.Disablement) *DisablementPayload {
+	if disabled == nil {
+		return nil
+	}
+	return &DisablementPayload{disabled.At, disabled.Reason, disabled.By}
+}
+
+func NewHeaderPayload(header models.HeaderReplacement) HeaderPayload {
+	return HeaderPayload{header.ID, header.ConfigID, header.HeaderName}
+}
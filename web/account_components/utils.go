@@ -0,0 +1,21 @@
+package account_components
+
+import "time"
+
+// TOTPEnrollment is the secret to add to an authenticator app, either by
+// scanning the QR code or by typing the secret.
+type TOTPEnrollment struct {
+	Secret    string
+	URL       string
+	QRCodeURI string
+}
+
+type Invite struct {
+	Link      string
+	ExpiresAt time.Time
+	Emailed   bool
+}
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
@@ -0,0 +1,26 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type csrfTokenKey struct{}
+
+// WithCSRFToken makes the CSRF token of the request available to the
+// templates.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey{}, token)
+}
+
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// CSRFHeaders returns the hx-headers value that sends the CSRF token with
+// every htmx request of the page.
+func CSRFHeaders(ctx context.Context) string {
+	headers, _ := json.Marshal(map[string]string{"X-CSRF-Token": CSRFToken(ctx)})
+	return string(headers)
+}
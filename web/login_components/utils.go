@@ -0,0 +1,10 @@
+package login_components
+
+// SignupPage describes the signup form. Allowed is false when an invite is
+// required and none was given.
+type SignupPage struct {
+	Allowed bool
+	// Token is the invite the account is created with.
+	Token string
+	Email string
+}
@@ -2,8 +2,16 @@ package projects_components
 
 import (
 	"configuration-management/internal/forms"
-	"github.com/google/uuid"
+	"configuration-management/internal/manifest"
+	"configuration-management/internal/models"
+	"configuration-management/web"
+	"context"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func GetModalId(projectID uuid.UUID) string {
@@ -42,3 +50,217 @@ func GetInputClass(fieldName string, errors forms.FormErrors, additionalClasses
 func GetListHeaderReplacementID(configID uuid.UUID) string {
 	return "list_headers" + strings.Replace(configID.String(), "-", "", -1)
 }
+
+func GetCreateAlertFormID(configID uuid.UUID) string {
+	return "create_alert_form" + strings.Replace(configID.String(), "-", "", -1)
+}
+
+func GetListAlertsID(configID uuid.UUID) string {
+	return "list_alerts" + strings.Replace(configID.String(), "-", "", -1)
+}
+
+func GetListClientsID(configID uuid.UUID) string {
+	return "list_clients" + strings.Replace(configID.String(), "-", "", -1)
+}
+
+func GetCreateClientFormID(configID uuid.UUID) string {
+	return "create_client_form" + strings.Replace(configID.String(), "-", "", -1)
+}
+
+func formatLastUsed(lastUsedAt *time.Time) string {
+	if lastUsedAt == nil {
+		return "Never used"
+	}
+	return "Last used " + lastUsedAt.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func GetConfigAvailabilityID(configID uuid.UUID) string {
+	return "config_availability" + strings.Replace(configID.String(), "-", "", -1)
+}
+
+func GetProjectKillSwitchID(projectID uuid.UUID) string {
+	return "project_kill_switch" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+func describeDisablement(ctx context.Context, disabled models.Disablement) string {
+	text := "Disabled " + disabled.At.UTC().Format("2006-01-02 15:04 UTC")
+	if disabled.By != nil {
+		text += " by " + web.UserName(ctx, *disabled.By)
+	}
+	return text + ": " + disabled.Reason
+}
+
+// clientLimitError names the limit of the config the client limit has to
+// be lower than.
+func clientLimitError(err string, config models.Config) string {
+	if err == "lt" {
+		return "lower than " + strconv.Itoa(config.LimitNumberOfRequests)
+	}
+	return err
+}
+
+// hasAvailabilityErrors opens the availability form, the kill switch shows
+// its own error.
+func hasAvailabilityErrors(errors forms.FormErrors) bool {
+	for field := range errors {
+		if field != "Reason" {
+			return true
+		}
+	}
+	return false
+}
+
+func disabledConfigsNotice(count int) string {
+	switch count {
+	case 0:
+		return "Every config was disabled already"
+	case 1:
+		return "Disabled 1 config"
+	default:
+		return fmt.Sprintf("Disabled %d configs", count)
+	}
+}
+
+// DateTimeInputLayout is the layout of datetime-local inputs, read as UTC.
+const DateTimeInputLayout = "2006-01-02T15:04"
+
+func formatDateTimeInput(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(DateTimeInputLayout)
+}
+
+func formatAvailabilityTime(t *time.Time, unset string) string {
+	if t == nil {
+		return unset
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func configStatusClass(status string) string {
+	switch status {
+	case models.ConfigStatusActive:
+		return "badge badge-success"
+	case models.ConfigStatusExpired, models.ConfigStatusDisabled:
+		return "badge badge-error"
+	default:
+		return "badge badge-warning"
+	}
+}
+
+// scheduleWeekdays orders the day checkboxes of the schedule form.
+var scheduleWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+func scheduleHasDay(schedule []models.ScheduleWindow, weekday time.Weekday) bool {
+	for _, window := range schedule {
+		if window.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleStart and scheduleEnd fill the hours of the form, which sets the
+// same hours on every day.
+func scheduleStart(schedule []models.ScheduleWindow) string {
+	if len(schedule) == 0 {
+		return ""
+	}
+	return models.FormatMinute(schedule[0].StartMinute)
+}
+
+func scheduleEnd(schedule []models.ScheduleWindow) string {
+	if len(schedule) == 0 {
+		return ""
+	}
+	return models.FormatMinute(schedule[0].EndMinute % (24 * 60))
+}
+
+func GetAlertStateClass(state string) string {
+	if state == models.AlertStateFiring {
+		return "badge badge-error"
+	}
+
+	return "badge badge-success"
+}
+
+func CountAlerts(configs []models.Config) int {
+	count := 0
+	for _, config := range configs {
+		count += len(config.Alerts)
+	}
+
+	return count
+}
+
+func CountFiringAlerts(configs []models.Config) int {
+	count := 0
+	for _, config := range configs {
+		for _, alert := range config.Alerts {
+			if alert.State == models.AlertStateFiring {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func GetCreateWebhookFormID(projectID uuid.UUID) string {
+	return "create_webhook_form" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+func GetListWebhooksID(projectID uuid.UUID) string {
+	return "list_webhooks" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+func GetWebhookDeliveriesID(webhookID uuid.UUID) string {
+	return "webhook_deliveries" + strings.Replace(webhookID.String(), "-", "", -1)
+}
+
+type ProjectsListing struct {
+	Page       int
+	TotalPages int
+	// LazyConfigs renders a placeholder that loads the config tabs when the
+	// project is opened.
+	LazyConfigs bool
+}
+
+func GetAlertsSummaryID(projectID uuid.UUID) string {
+	return "alerts_summary" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+// GetImportModalID identifies the import dialog of a project, or the one of
+// the projects page when projectID is nil.
+func GetImportModalID(projectID *uuid.UUID) string {
+	if projectID == nil {
+		return "import_modal"
+	}
+	return "import_modal_" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+func GetImportResultID(projectID *uuid.UUID) string {
+	if projectID == nil {
+		return "import_result"
+	}
+	return "import_result_" + strings.Replace(projectID.String(), "-", "", -1)
+}
+
+func importURL(projectID *uuid.UUID) string {
+	if projectID == nil {
+		return "/projects/import"
+	}
+	return "/projects/" + projectID.String() + "/import"
+}
+
+func changeBadgeClass(action string) string {
+	switch action {
+	case manifest.ActionCreate:
+		return "badge-success"
+	case manifest.ActionDelete:
+		return "badge-error"
+	default:
+		return "badge-warning"
+	}
+}
@@ -0,0 +1,7 @@
+package sessions_components
+
+import "time"
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
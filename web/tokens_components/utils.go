@@ -0,0 +1,10 @@
+package tokens_components
+
+import "time"
+
+func FormatTime(t *time.Time) string {
+	if t == nil {
+		return "Never"
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
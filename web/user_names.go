@@ -0,0 +1,28 @@
+package web
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type userNamesKey struct{}
+
+// WithUserNames makes the lookup of user names available to the templates,
+// records keep the user id and show the current name.
+func WithUserNames(ctx context.Context, lookup func(ctx context.Context, userID uuid.UUID) string) context.Context {
+	return context.WithValue(ctx, userNamesKey{}, lookup)
+}
+
+// UserName returns the display name of the user, or the id when there is no
+// lookup or the user is gone.
+func UserName(ctx context.Context, userID uuid.UUID) string {
+	lookup, ok := ctx.Value(userNamesKey{}).(func(context.Context, uuid.UUID) string)
+	if !ok {
+		return userID.String()
+	}
+	if name := lookup(ctx, userID); name != "" {
+		return name
+	}
+	return userID.String()
+}